@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"jarvis-memory/internal/db"
+)
+
+// runBench fills a namespace with synthetic seeds, checks that vector
+// search is served by the HNSW index and measures latency and recall
+// against exact search for several ef_search values.
+func runBench(ctx context.Context, dbConn *db.DB, args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	seeds := fs.Int("seeds", 100000, "number of synthetic seeds")
+	queries := fs.Int("queries", 50, "number of random queries per ef_search value")
+	limit := fs.Int("limit", 10, "results per query")
+	efList := fs.String("ef", "40,100,200", "comma-separated ef_search values")
+	namespace := fs.String("namespace", db.BenchNamespacePrefix, "namespace for the synthetic seeds, starting with \""+db.BenchNamespacePrefix+"\"")
+	keep := fs.Bool("keep", false, "keep the synthetic seeds for the next run")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var efs []int
+	for _, part := range splitList(*efList) {
+		ef, err := strconv.Atoi(part)
+		if err != nil || ef < 1 || ef > 1000 {
+			return fmt.Errorf("invalid ef_search %q", part)
+		}
+		efs = append(efs, ef)
+	}
+
+	dims, err := dbConn.EmbeddingColumnDimensions(ctx, "seeds")
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	inserted, err := dbConn.InsertSyntheticSeeds(ctx, *namespace, *seeds, dims, func(done int) {
+		fmt.Printf("\rInserting synthetic seeds... %d/%d", done, *seeds)
+	})
+	if inserted > 0 {
+		fmt.Printf("\nInserted %d seeds in %s\n", inserted, time.Since(start).Round(time.Millisecond))
+	}
+	if err != nil {
+		return err
+	}
+	if !*keep {
+		defer func() {
+			if n, err := dbConn.DeleteSyntheticSeeds(ctx, *namespace); err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+			} else {
+				fmt.Printf("Deleted %d synthetic seeds\n", n)
+			}
+		}()
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	vectors := make([][]float32, *queries)
+	for i := range vectors {
+		vectors[i] = make([]float32, dims)
+		for j := range vectors[i] {
+			vectors[i][j] = rng.Float32() - 0.5
+		}
+	}
+	// A threshold of -1 keeps every neighbour, so the benchmark measures
+	// retrieval alone.
+	opts := db.SeedSearchOptions{Namespace: *namespace, Limit: *limit, Threshold: -1}
+
+	plan, err := dbConn.ExplainSeedSearch(ctx, vectors[0], opts)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nPlan of the retrieval stage:\n%s\n\n", plan)
+	usesIndex := strings.Contains(plan, "seeds_embedding_idx")
+
+	exact := make([][]string, len(vectors))
+	for i, v := range vectors {
+		if exact[i], err = dbConn.SeedSearchHits(ctx, v, opts, true); err != nil {
+			return err
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "EF_SEARCH\tP50\tP95\tRECALL")
+	for _, ef := range efs {
+		opts.EfSearch = ef
+		latencies := make([]time.Duration, len(vectors))
+		found, total := 0, 0
+		for i, v := range vectors {
+			t := time.Now()
+			ids, err := dbConn.SeedSearchHits(ctx, v, opts, false)
+			if err != nil {
+				return err
+			}
+			latencies[i] = time.Since(t)
+			found += overlapCount(ids, exact[i])
+			total += len(exact[i])
+		}
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		recall := 1.0
+		if total > 0 {
+			recall = float64(found) / float64(total)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%.3f\n", ef, percentile(latencies, 0.5), percentile(latencies, 0.95), recall)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if !usesIndex {
+		return fmt.Errorf("the planner did not use seeds_embedding_idx")
+	}
+	fmt.Println("\nThe planner uses seeds_embedding_idx.")
+	return nil
+}
+
+func overlapCount(got, want []string) int {
+	set := make(map[string]bool, len(want))
+	for _, id := range want {
+		set[id] = true
+	}
+	n := 0
+	for _, id := range got {
+		if set[id] {
+			n++
+		}
+	}
+	return n
+}
+
+// percentile expects sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[int(p*float64(len(sorted)-1))].Round(10 * time.Microsecond)
+}
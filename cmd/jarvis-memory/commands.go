@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"jarvis-memory/internal/admin"
+	"jarvis-memory/internal/db"
+)
+
+const usage = `Usage: jarvis-memory [command]
+
+Without a command the HTTP server is started.
+
+Commands:
+  migrate status                 show applied and pending migrations
+  migrate up [version]           apply pending migrations (up to version)
+  migrate down [steps]           revert the last applied migration(s)
+  keys create -name <name> -scopes read,write,admin [-namespaces ns1,ns2|*]
+  keys list
+  keys revoke <id>
+  bench [-seeds 100000] [-queries 50] [-limit 10] [-ef 40,100,200] [-namespace bench] [-keep]
+                                 benchmark vector search on synthetic seeds`
+
+func runCommand(ctx context.Context, dbConn *db.DB, args []string) error {
+	switch args[0] {
+	case "migrate":
+		return runMigrate(ctx, dbConn, args[1:])
+	case "keys":
+		if err := dbConn.AutoMigrate(ctx); err != nil {
+			return fmt.Errorf("failed to migrate database: %w", err)
+		}
+		return runKeys(ctx, dbConn, args[1:])
+	case "bench":
+		if err := dbConn.AutoMigrate(ctx); err != nil {
+			return fmt.Errorf("failed to migrate database: %w", err)
+		}
+		return runBench(ctx, dbConn, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+	}
+}
+
+func runMigrate(ctx context.Context, dbConn *db.DB, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing migrate subcommand\n%s", usage)
+	}
+
+	// Optional numeric argument: target version for up, step count for down
+	n := 0
+	if len(args) > 1 {
+		v, err := strconv.Atoi(args[1])
+		if err != nil || v < 0 {
+			return fmt.Errorf("invalid number %q", args[1])
+		}
+		n = v
+	}
+
+	switch args[0] {
+	case "status":
+		states, err := dbConn.MigrationStatus(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+		for _, st := range states {
+			applied := "pending"
+			if st.AppliedAt != nil {
+				applied = st.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", st.Version, st.Name, applied)
+		}
+		return w.Flush()
+	case "up":
+		return dbConn.MigrateUp(ctx, n)
+	case "down":
+		return dbConn.MigrateDown(ctx, n)
+	default:
+		return fmt.Errorf("unknown migrate subcommand %q\n%s", args[0], usage)
+	}
+}
+
+func runKeys(ctx context.Context, dbConn *db.DB, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing keys subcommand\n%s", usage)
+	}
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("keys create", flag.ContinueOnError)
+		name := fs.String("name", "", "descriptive name for the key")
+		scopes := fs.String("scopes", "read", "comma-separated scopes (read, write, admin)")
+		namespaces := fs.String("namespaces", db.DefaultNamespace, "comma-separated namespaces, or * for all")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		resp, err := admin.CreateKey(ctx, dbConn, nil, admin.CreateKeyRequest{
+			Name:       *name,
+			Scopes:     splitList(*scopes),
+			Namespaces: splitList(*namespaces),
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Created key %s (%s)\n", resp.ID, resp.Name)
+		fmt.Printf("Key: %s\n", resp.Key)
+		fmt.Println("Store it now, it cannot be shown again.")
+		return nil
+
+	case "list":
+		keys, err := dbConn.ListAPIKeys(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tPREFIX\tSCOPES\tNAMESPACES\tREVOKED")
+		for _, k := range keys {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%t\n", k.ID, k.Name, k.Prefix, strings.Join(k.Scopes, ","), strings.Join(k.Namespaces, ","), k.RevokedAt != nil)
+		}
+		return w.Flush()
+
+	case "revoke":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: jarvis-memory keys revoke <id>")
+		}
+		if err := dbConn.RevokeAPIKey(ctx, args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("Revoked key %s\n", args[1])
+		return nil
+
+	default:
+		return fmt.Errorf("unknown keys subcommand %q\n%s", args[0], usage)
+	}
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
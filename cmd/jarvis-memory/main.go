@@ -2,9 +2,12 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/labstack/echo/v5"
 	"github.com/labstack/echo/v5/middleware"
@@ -12,7 +15,11 @@ import (
 	"jarvis-memory/internal/admin"
 	"jarvis-memory/internal/api"
 	"jarvis-memory/internal/db"
+	"jarvis-memory/internal/decay"
 	"jarvis-memory/internal/embeddings"
+	"jarvis-memory/internal/reembed"
+	"jarvis-memory/internal/retention"
+	"jarvis-memory/internal/trash"
 )
 
 func main() {
@@ -29,40 +36,73 @@ func main() {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
+	// Subcommands (e.g. `jarvis-memory migrate status`) run against the DB and exit
+	if len(os.Args) > 1 {
+		if err := runCommand(context.Background(), dbConn, os.Args[1:]); err != nil {
+			log.Fatalf("%v", err)
+		}
+		return
+	}
+
 	if err := dbConn.AutoMigrate(context.Background()); err != nil {
 		log.Fatalf("Failed to migrate database: %v", err)
 	}
 
-	// 1b. Apply memory decay on startup
-	if err := dbConn.ApplyDecay(context.Background()); err != nil {
-		log.Printf("Warning: failed to apply decay: %v", err)
+	// 1b. Schedule memory decay
+	decayPolicies, decayInterval := decayConfig()
+	decayScheduler := decay.NewScheduler(dbConn, decayPolicies, decayInterval)
+	if decayInterval > 0 {
+		go decayScheduler.Run(context.Background())
 	}
 
-	// 2. Initialize Embeddings
-	modelPath := os.Getenv("GTE_MODEL_PATH")
-	if modelPath == "" {
-		modelPath = "models/gte-small.gtemodel"
+	// 1c. Empty the trash in the background
+	if retention, interval := trashConfig(); retention > 0 {
+		go trash.NewPurger(dbConn, retention, interval).Run(context.Background())
 	}
 
-	embService, err := embeddings.NewService(modelPath)
+	// 2. Initialize Embeddings
+	embedder, err := embeddings.New(embedderConfig())
 	if err != nil {
 		log.Fatalf("Failed to initialize embeddings service (is the model downloaded?): %v", err)
 	}
-	defer embService.Close()
+	log.Printf("Embedder: %s (%d dimensions)", embedder.ModelID(), embedder.Dimensions())
+	checkEmbedder(context.Background(), dbConn, embedder)
+	emb := embeddings.NewSwappable(embedder)
+	// A finished re-embed job replaces and closes the startup embedder
+	defer func() { embeddings.Close(emb.Current()) }()
+
+	// 2b. Partition, roll up and expire agent contexts in the background
+	retentionPolicies, maintenanceInterval := retentionConfig()
+	maintainer := retention.NewMaintainer(dbConn, emb, retentionPolicies, maintenanceInterval)
+	if maintenanceInterval > 0 {
+		go maintainer.Run(context.Background())
+	}
 
 	// 3. Setup Echo
 	e := echo.New()
 
+	// Auth is on unless explicitly switched off with AUTH_ENABLED=false
+	authEnabled := os.Getenv("AUTH_ENABLED") != "false"
+	if authEnabled {
+		checkAPIKeys(context.Background(), dbConn)
+	} else {
+		log.Println("Warning: AUTH_ENABLED=false, API is open to anyone who can reach it")
+	}
+
 	// Middleware
 	e.Use(middleware.Recover())
-	e.Use(middleware.CORS("*"))
+	if origins := corsOrigins(authEnabled); len(origins) > 0 {
+		e.Use(middleware.CORS(origins...))
+	}
 
 	// 4. Register API Routes
-	apiHandler := api.NewHandler(dbConn, embService)
+	authn := api.NewAuthenticator(dbConn, authEnabled)
+
+	apiHandler := api.NewHandler(dbConn, emb, authn, apiConfig())
 	apiHandler.RegisterRoutes(e)
 
 	// 5. Register Admin Routes
-	adminHandler := admin.NewHandler(dbConn)
+	adminHandler := admin.NewHandler(dbConn, authn, reembed.NewRunner(dbConn, emb, reembedTargets()), decayScheduler, maintainer)
 	adminHandler.RegisterRoutes(e)
 
 	// 6. Start server
@@ -76,3 +116,222 @@ func main() {
 		log.Fatalf("Shutting down the server: %v", err)
 	}
 }
+
+// corsOrigins reads the origins allowed to call the API from a browser. With
+// auth enabled, cross-origin requests are refused unless CORS_ORIGINS lists
+// them; the admin panel is served from the same origin and needs none.
+func corsOrigins(authEnabled bool) []string {
+	if v := os.Getenv("CORS_ORIGINS"); v != "" {
+		return splitList(v)
+	}
+	if authEnabled {
+		return nil
+	}
+	return []string{"*"}
+}
+
+// checkAPIKeys warns when auth is enabled but no key can pass it yet, so every
+// request will be rejected until one is minted.
+func checkAPIKeys(ctx context.Context, dbConn *db.DB) {
+	keys, err := dbConn.ListAPIKeys(ctx)
+	if err != nil {
+		log.Fatalf("Failed to load api keys: %v", err)
+	}
+	for _, k := range keys {
+		if k.RevokedAt == nil {
+			return
+		}
+	}
+	log.Println("Warning: auth is enabled but no api key exists; mint one with `jarvis-memory keys create -name ops -scopes admin -namespaces '*'` or set AUTH_ENABLED=false")
+}
+
+// apiConfig reads the API handler settings from the environment.
+func apiConfig() api.Config {
+	dedup := db.DedupOptions{
+		Policy:    os.Getenv("DEDUP_POLICY"),
+		Scope:     os.Getenv("DEDUP_SCOPE"),
+		Threshold: 0.95,
+	}
+	if dedup.Policy == "" {
+		dedup.Policy = db.DedupOff
+	}
+	if dedup.Scope == "" {
+		dedup.Scope = db.DedupScopeType
+	}
+	if v := os.Getenv("DEDUP_THRESHOLD"); v != "" {
+		t, err := strconv.ParseFloat(v, 32)
+		if err != nil {
+			log.Fatalf("Invalid DEDUP_THRESHOLD %q: %v", v, err)
+		}
+		dedup.Threshold = float32(t)
+	}
+	if err := dedup.Validate(); err != nil {
+		log.Fatalf("Invalid dedup settings: %v", err)
+	}
+
+	reinforce := db.ReinforcementOptions{
+		Curve:   os.Getenv("REINFORCE_CURVE"),
+		Boost:   0.05,
+		Spacing: 24 * time.Hour,
+		Ceiling: 1.0,
+	}
+	if reinforce.Curve == "" {
+		reinforce.Curve = db.ReinforceSpaced
+	}
+	for env, dst := range map[string]*float32{"REINFORCE_BOOST": &reinforce.Boost, "REINFORCE_CEILING": &reinforce.Ceiling} {
+		if v := os.Getenv(env); v != "" {
+			f, err := strconv.ParseFloat(v, 32)
+			if err != nil {
+				log.Fatalf("Invalid %s %q: %v", env, v, err)
+			}
+			*dst = float32(f)
+		}
+	}
+	if v := os.Getenv("REINFORCE_SPACING"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("Invalid REINFORCE_SPACING %q: %v", v, err)
+		}
+		reinforce.Spacing = d
+	}
+	if err := reinforce.Validate(); err != nil {
+		log.Fatalf("Invalid reinforcement settings: %v", err)
+	}
+
+	feedback := db.DefaultFeedbackRules
+	if v := os.Getenv("FEEDBACK_RULES"); v != "" {
+		feedback = db.FeedbackRules{}
+		for signal, adj := range db.DefaultFeedbackRules {
+			feedback[signal] = adj
+		}
+		if err := json.Unmarshal([]byte(v), &feedback); err != nil {
+			log.Fatalf("Invalid FEEDBACK_RULES: %v", err)
+		}
+		if err := feedback.Validate(); err != nil {
+			log.Fatalf("Invalid FEEDBACK_RULES: %v", err)
+		}
+	}
+
+	return api.Config{Dedup: dedup, Reinforcement: reinforce, Feedback: feedback}
+}
+
+// decayConfig reads the per-type decay policies and how often decay runs. An
+// interval of 0 disables scheduled decay.
+func decayConfig() (map[string]db.DecayPolicy, time.Duration) {
+	policies := decay.DefaultPolicies
+	if v := os.Getenv("DECAY_POLICIES"); v != "" {
+		p, err := decay.ParsePolicies(v)
+		if err != nil {
+			log.Fatalf("Invalid DECAY_POLICIES: %v", err)
+		}
+		policies = p
+	}
+	interval := 24 * time.Hour
+	if v := os.Getenv("DECAY_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			log.Fatalf("Invalid DECAY_INTERVAL %q", v)
+		}
+		interval = d
+	}
+	return policies, interval
+}
+
+// trashConfig reads how long deleted seeds are kept and how often the trash
+// is purged. A retention of 0 keeps deleted seeds forever.
+func trashConfig() (retention, interval time.Duration) {
+	retention, interval = 30*24*time.Hour, time.Hour
+	if v := os.Getenv("TRASH_RETENTION"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			log.Fatalf("Invalid TRASH_RETENTION %q", v)
+		}
+		retention = d
+	}
+	if v := os.Getenv("TRASH_PURGE_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Fatalf("Invalid TRASH_PURGE_INTERVAL %q", v)
+		}
+		interval = d
+	}
+	return retention, interval
+}
+
+// retentionConfig reads the agent context retention policies and how often
+// maintenance runs. An interval of 0 disables scheduled maintenance.
+func retentionConfig() ([]db.ContextRetentionPolicy, time.Duration) {
+	policies := retention.DefaultPolicies
+	if v := os.Getenv("CONTEXT_RETENTION"); v != "" {
+		p, err := retention.ParsePolicies(v)
+		if err != nil {
+			log.Fatalf("Invalid CONTEXT_RETENTION: %v", err)
+		}
+		policies = p
+	}
+	interval := 6 * time.Hour
+	if v := os.Getenv("CONTEXT_MAINTENANCE_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			log.Fatalf("Invalid CONTEXT_MAINTENANCE_INTERVAL %q", v)
+		}
+		interval = d
+	}
+	return policies, interval
+}
+
+// embedderConfig reads the embedding backend settings from the environment.
+func embedderConfig() embeddings.Config {
+	cfg := embeddings.Config{
+		Backend:   os.Getenv("EMBEDDER"),
+		ModelPath: os.Getenv("GTE_MODEL_PATH"),
+		URL:       os.Getenv("EMBEDDER_URL"),
+		Model:     os.Getenv("EMBEDDER_MODEL"),
+		APIKey:    os.Getenv("EMBEDDER_API_KEY"),
+		Format:    os.Getenv("EMBEDDER_FORMAT"),
+	}
+	if cfg.ModelPath == "" {
+		cfg.ModelPath = "models/gte-small.gtemodel"
+	}
+	if dims := os.Getenv("EMBEDDER_DIMENSIONS"); dims != "" {
+		if n, err := strconv.Atoi(dims); err == nil {
+			cfg.Dimensions = n
+		}
+	}
+	return cfg
+}
+
+// reembedTargets reads the embedders a re-embed job may switch to, as a JSON
+// object mapping a name to an embedder config, e.g.
+// {"nomic": {"backend": "http", "url": "http://localhost:11434", "model": "nomic-embed-text", "format": "ollama"}}.
+// Jobs name a target instead of sending a config, so API callers cannot point
+// the server at arbitrary model paths or URLs.
+func reembedTargets() map[string]embeddings.Config {
+	targets := map[string]embeddings.Config{}
+	if v := os.Getenv("REEMBED_TARGETS"); v != "" {
+		if err := json.Unmarshal([]byte(v), &targets); err != nil {
+			log.Fatalf("Invalid REEMBED_TARGETS: %v", err)
+		}
+	}
+	return targets
+}
+
+// checkEmbedder refuses to start when the configured embedder cannot write to
+// the embedding columns, and warns when it differs from the stored model.
+func checkEmbedder(ctx context.Context, dbConn *db.DB, embedder embeddings.Embedder) {
+	dims, err := dbConn.EmbeddingColumnDimensions(ctx, "seeds")
+	if err != nil {
+		log.Fatalf("Failed to inspect embedding column: %v", err)
+	}
+	if dims != embedder.Dimensions() {
+		log.Fatalf("Embedder %s produces %d dimensions but the database stores %d. Start with the previous embedder and migrate via POST /admin/api/reembed.",
+			embedder.ModelID(), embedder.Dimensions(), dims)
+	}
+
+	stored, err := dbConn.StoredEmbeddingModel(ctx)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+	} else if stored != "" && stored != embedder.ModelID() {
+		log.Printf("Warning: stored embeddings come from %s but the embedder is %s; search quality will suffer until you re-embed.", stored, embedder.ModelID())
+	}
+}
@@ -1,49 +1,51 @@
 #!/usr/bin/env bash
-# Auto-Capture: Save conversation after AI turn (Dual Storage)
-# This hook runs after each AI turn to persist the conversation.
-#
-# Dual Storage:
-#   1. Seed — Full thread snapshot for semantic search
-#   2. Agent Context — Truncated summary for structured metadata
+# Auto-Capture: Save conversation after AI turn
+# This hook runs after each AI turn and posts it to /conversations/turns.
+# The server stores the turn and derives a thread snapshot seed (with dedup
+# and chunking) and an episodic agent context from it.
 
 # Check if auto-capture is enabled (default: true)
 JARVIS_AUTO_CAPTURE="${JARVIS_AUTO_CAPTURE:-true}"
 [[ "$JARVIS_AUTO_CAPTURE" != "true" ]] && exit 0
 
-API_BASE="http://localhost:8080"
+API_BASE="${JARVIS_API_URL:-http://localhost:8080}"
+
+# Send the API key (if configured) with every request
+curl() {
+    if [[ -n "${JARVIS_API_KEY:-}" ]]; then
+        command curl -H "Authorization: Bearer ${JARVIS_API_KEY}" "$@"
+    else
+        command curl "$@"
+    fi
+}
 AGENT_ID="${JARVIS_AGENT_ID:-JARVIS}"
 
 USER_MSG="${OPENCLAW_USER_MESSAGE:-}"
 AI_RESP="${OPENCLAW_AI_RESPONSE:-}"
+SESSION_ID="${OPENCLAW_SESSION_ID:-}"
+TOOL_CALLS="${OPENCLAW_TOOL_CALLS:-[]}"
 
 [[ -z "$USER_MSG" && -z "$AI_RESP" ]] && exit 0
 
-TS=$(date -u +"%Y-%m-%dT%H:%M:%SZ")
-
-# --- 1. Seed: Thread Snapshot Format ---
-TITLE="Thread snapshot - ${TS}"
-CONTENT="Thread snapshot - ${TS}
-
-Post: ${USER_MSG}
-
-Comments:
-User: ${USER_MSG}
-${AGENT_ID}: ${AI_RESP}"
-
-curl -s -X POST "${API_BASE}/seeds" \
-    -F "content=${CONTENT}" \
-    -F "title=${TITLE}" \
-    -F "type=auto_capture" > /dev/null 2>&1 &
-
-# --- 2. Agent Context: Structured Summary ---
-# Truncate summary to first 200 chars of AI response
-SUMMARY="${AI_RESP:0:200}"
+# Tool calls are passed through as a JSON array of {name, input, output}
+echo "$TOOL_CALLS" | jq -e 'type == "array"' > /dev/null 2>&1 || TOOL_CALLS="[]"
+
+body=$(jq -n \
+    --arg agent "$AGENT_ID" \
+    --arg session "$SESSION_ID" \
+    --arg user "$USER_MSG" \
+    --arg resp "$AI_RESP" \
+    --argjson tools "$TOOL_CALLS" \
+    '{agentId: $agent, sessionId: $session, userMessage: $user, assistantResponse: $resp, toolCalls: $tools}')
+
+# Capture in the background: embedding the turn can take a while and the
+# agent should not wait for it
+(
+    if ! curl -sf --max-time 30 -X POST "${API_BASE}/conversations/turns" \
+        -H "Content-Type: application/json" \
+        -d "$body" > /dev/null 2>&1; then
+        echo "jarvis-memory: failed to capture conversation turn" >&2
+    fi
+) < /dev/null > /dev/null &
+disown
 
-curl -s -X POST "${API_BASE}/agent-contexts" \
-    -H "Content-Type: application/json" \
-    -d "{
-        \"agentId\": \"${AGENT_ID}\",
-        \"type\": \"episodic\",
-        \"metadata\": {\"timestamp\": \"${TS}\", \"source\": \"auto_capture\"},
-        \"summary\": $(echo "$SUMMARY" | jq -Rs .)
-    }" > /dev/null 2>&1 &
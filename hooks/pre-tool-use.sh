@@ -8,7 +8,16 @@ JARVIS_AUTO_RECALL="${JARVIS_AUTO_RECALL:-true}"
 
 set -euo pipefail
 
-API_BASE="http://localhost:8080"
+API_BASE="${JARVIS_API_URL:-http://localhost:8080}"
+
+# Send the API key (if configured) with every request
+curl() {
+    if [[ -n "${JARVIS_API_KEY:-}" ]]; then
+        command curl -H "Authorization: Bearer ${JARVIS_API_KEY}" "$@"
+    else
+        command curl "$@"
+    fi
+}
 
 # Get the user's latest message from stdin (OpenClaw passes context)
 USER_MESSAGE="${OPENCLAW_USER_MESSAGE:-}"
@@ -17,15 +26,19 @@ if [[ -z "$USER_MESSAGE" ]]; then
     exit 0
 fi
 
-# Query for relevant memories from local Jarvis Memory API
-# Threshold is set to 0.5 to only return somewhat relevant memories
-response=$(curl -s -X POST "${API_BASE}/seeds/query" \
+AGENT_ID="${JARVIS_AGENT_ID:-JARVIS}"
+RECALL_BUDGET="${JARVIS_RECALL_BUDGET:-800}"
+RECALL_TIMEOUT="${JARVIS_RECALL_TIMEOUT:-5}"
+SESSION_ID="${OPENCLAW_SESSION_ID:-}"
+
+# One call: the API searches seeds and this agent's contexts, drops
+# duplicates, prefers memories of the current session and trims the block
+# to the token budget
+body=$(jq -n --arg m "$USER_MESSAGE" --arg a "$AGENT_ID" --arg s "$SESSION_ID" --argjson b "$RECALL_BUDGET" \
+    '{message: $m, agentId: $a, sessionId: $s, budget: $b, format: "markdown"}')
+memories=$(curl -s --max-time "$RECALL_TIMEOUT" -X POST "${API_BASE}/recall" \
     -H "Content-Type: application/json" \
-    -d "{\"query\":\"${USER_MESSAGE}\",\"limit\":5,\"threshold\":0.5}" 2>/dev/null || echo "[]")
-
-# Extract memory content if any
-# The API returns a JSON array of hit objects. We want the `content` of each.
-memories=$(echo "$response" | jq -r '.[].content? // empty' 2>/dev/null | head -n 5)
+    -d "$body" 2>/dev/null | jq -r '.block // empty' 2>/dev/null || true)
 
 if [[ -n "$memories" ]]; then
     echo "---"
@@ -1,32 +1,70 @@
 package admin
 
 import (
-	"context"
-	"database/sql"
 	"embed"
+	"errors"
 	"io/fs"
 	"net/http"
-	"time"
 
 	"github.com/labstack/echo/v5"
 
+	"jarvis-memory/internal/api"
+	"jarvis-memory/internal/auth"
 	"jarvis-memory/internal/db"
+	"jarvis-memory/internal/decay"
+	"jarvis-memory/internal/reembed"
+	"jarvis-memory/internal/retention"
 )
 
 //go:embed dist/*
 var distFS embed.FS
 
 type AdminHandler struct {
-	db *db.DB
+	db      *db.DB
+	auth    *api.Authenticator
+	reembed *reembed.Runner
+	decay   *decay.Scheduler
+	// retention maintains agent contexts
+	retention *retention.Maintainer
 }
 
-func NewHandler(dbConn *db.DB) *AdminHandler {
-	return &AdminHandler{db: dbConn}
+func NewHandler(dbConn *db.DB, authn *api.Authenticator, runner *reembed.Runner, scheduler *decay.Scheduler, maintainer *retention.Maintainer) *AdminHandler {
+	return &AdminHandler{db: dbConn, auth: authn, reembed: runner, decay: scheduler, retention: maintainer}
 }
 
 func (h *AdminHandler) RegisterRoutes(e *echo.Echo) {
+	// adminOnly checks the namespace resolved by NamespaceMiddleware. Routes
+	// mounted without it act on every namespace, so the empty namespace makes
+	// Require demand an admin key for all namespaces ("*").
+	adminOnly := h.auth.Require(auth.ScopeAdmin)
+
 	// JSON API for the React frontend
-	e.GET("/admin/api/data", h.HandleAdminData)
+	e.GET("/admin/api/data", h.HandleAdminData, api.NamespaceMiddleware, adminOnly)
+	e.GET("/ns/:namespace/admin/api/data", h.HandleAdminData, api.NamespaceMiddleware, adminOnly)
+
+	// Permanent seed deletion, bypassing the trash and protection
+	e.DELETE("/admin/api/seeds/:id", h.HandleHardDeleteSeed, api.NamespaceMiddleware, adminOnly)
+	e.DELETE("/ns/:namespace/admin/api/seeds/:id", h.HandleHardDeleteSeed, api.NamespaceMiddleware, adminOnly)
+
+	// Seed revision diffs
+	e.GET("/admin/api/seeds/:id/diff", h.HandleSeedDiff, api.NamespaceMiddleware, adminOnly)
+	e.GET("/ns/:namespace/admin/api/seeds/:id/diff", h.HandleSeedDiff, api.NamespaceMiddleware, adminOnly)
+
+	// API key management
+	e.GET("/admin/api/keys", h.HandleListKeys, adminOnly)
+	e.POST("/admin/api/keys", h.HandleCreateKey, adminOnly)
+	e.DELETE("/admin/api/keys/:id", h.HandleRevokeKey, adminOnly)
+
+	// Embedding model migration
+	e.POST("/admin/api/reembed", h.HandleStartReembed, adminOnly)
+	e.GET("/admin/api/reembed", h.HandleReembedStatus, adminOnly)
+
+	// Memory decay
+	e.GET("/admin/api/decay", h.HandleListDecayRuns, adminOnly)
+	e.POST("/admin/api/decay", h.HandleRunDecay, adminOnly)
+
+	// Agent context retention and partitions
+	e.POST("/admin/api/contexts/maintenance", h.HandleRunContextMaintenance, adminOnly)
 
 	// Serve the React SPA from embedded dist/
 	distContent, _ := fs.Sub(distFS, "dist")
@@ -46,20 +84,55 @@ func (h *AdminHandler) RegisterRoutes(e *echo.Echo) {
 	})
 }
 
+// HandleHardDeleteSeed removes a seed for good, including its chunks and
+// revision history.
+func (h *AdminHandler) HandleHardDeleteSeed(c *echo.Context) error {
+	if err := h.db.HardDeleteSeed(c.Request().Context(), api.Namespace(c), c.Param("id"), api.Actor(c)); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, map[string]bool{"deleted": true})
+}
+
 type AdminData struct {
 	Seeds         []db.Seed         `json:"seeds"`
 	AgentContexts []db.AgentContext `json:"agentContexts"`
+	// SeedsNext and AgentContextsNext are the cursors of the following
+	// pages; pass them back as ?seedsCursor= and ?contextsCursor=.
+	SeedsNext         string `json:"seedsNext,omitempty"`
+	AgentContextsNext string `json:"agentContextsNext,omitempty"`
 }
 
+// HandleAdminData returns a page of seeds and one of agent contexts. Seeds
+// take the same filters and sorts as GET /seeds; ?limit= (default 100)
+// applies to both lists.
 func (h *AdminHandler) HandleAdminData(c *echo.Context) error {
 	ctx := c.Request().Context()
 
-	seeds, err := h.getLatestSeeds(ctx)
+	seedOpts, err := api.SeedListOptions(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if seedOpts.Limit == 0 {
+		seedOpts.Limit = 100
+	}
+	seedOpts.Cursor = c.QueryParam("seedsCursor")
+	contextOpts := db.AgentContextListOptions{
+		Namespace:   seedOpts.Namespace,
+		PageOptions: db.PageOptions{Limit: seedOpts.Limit, Cursor: c.QueryParam("contextsCursor")},
+	}
+
+	seeds, seedsNext, err := h.db.ListSeeds(ctx, seedOpts)
+	if errors.Is(err, db.ErrInvalidPage) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to load seeds: " + err.Error()})
 	}
 
-	contexts, err := h.getLatestAgentContexts(ctx)
+	contexts, contextsNext, err := h.db.GetAgentContexts(ctx, contextOpts)
+	if errors.Is(err, db.ErrInvalidPage) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to load agent contexts: " + err.Error()})
 	}
@@ -72,36 +145,9 @@ func (h *AdminHandler) HandleAdminData(c *echo.Context) error {
 	}
 
 	return c.JSON(http.StatusOK, AdminData{
-		Seeds:         seeds,
-		AgentContexts: contexts,
+		Seeds:             seeds,
+		AgentContexts:     contexts,
+		SeedsNext:         seedsNext,
+		AgentContextsNext: contextsNext,
 	})
 }
-
-func (h *AdminHandler) getLatestSeeds(ctx context.Context) ([]db.Seed, error) {
-	query := `SELECT id, content, title, type, confidence, last_accessed, created_at FROM seeds ORDER BY created_at DESC LIMIT 100`
-	rows, err := h.db.QueryContext(ctx, query)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var seeds []db.Seed
-	for rows.Next() {
-		var s db.Seed
-		var lastAccessed sql.NullTime
-		if err := rows.Scan(&s.ID, &s.Content, &s.Title, &s.Type, &s.Confidence, &lastAccessed, &s.CreatedAt); err != nil {
-			return nil, err
-		}
-		if lastAccessed.Valid {
-			s.LastAccessed = lastAccessed.Time
-		} else {
-			s.LastAccessed = time.Time{}
-		}
-		seeds = append(seeds, s)
-	}
-	return seeds, nil
-}
-
-func (h *AdminHandler) getLatestAgentContexts(ctx context.Context) ([]db.AgentContext, error) {
-	return h.db.GetAgentContexts(ctx, "")
-}
@@ -0,0 +1,36 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v5"
+
+	"jarvis-memory/internal/db"
+)
+
+// HandleListDecayRuns returns the latest decay runs (?limit=, default 20).
+func (h *AdminHandler) HandleListDecayRuns(c *echo.Context) error {
+	limit := 20
+	if l, err := strconv.Atoi(c.QueryParam("limit")); err == nil && l > 0 {
+		limit = l
+	}
+	runs, err := h.db.ListDecayRuns(c.Request().Context(), limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if runs == nil {
+		runs = []db.DecayRun{}
+	}
+	return c.JSON(http.StatusOK, runs)
+}
+
+// HandleRunDecay applies decay immediately instead of waiting for the next
+// scheduled run.
+func (h *AdminHandler) HandleRunDecay(c *echo.Context) error {
+	run, err := h.decay.RunOnce(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, run)
+}
@@ -0,0 +1,187 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v5"
+
+	"jarvis-memory/internal/api"
+	"jarvis-memory/internal/db"
+)
+
+// DiffLine is one line of a line-based content diff. Op is "equal",
+// "delete" (only in From) or "insert" (only in To).
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// FieldChange is a scalar field that differs between two revisions.
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+type SeedDiff struct {
+	SeedID  string        `json:"seed_id"`
+	From    string        `json:"from"`
+	To      string        `json:"to"`
+	Fields  []FieldChange `json:"fields"`
+	Content []DiffLine    `json:"content"`
+}
+
+// HandleSeedDiff compares two revisions of a seed. "from" and "to" are
+// revision numbers; "to" defaults to the seed's current state.
+func (h *AdminHandler) HandleSeedDiff(c *echo.Context) error {
+	ctx := c.Request().Context()
+	ns := api.Namespace(c)
+	id := c.Param("id")
+
+	seed, err := h.db.GetSeed(ctx, ns, id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if seed == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "seed not found"})
+	}
+	current := &db.SeedRevision{
+		SeedID:     seed.ID,
+		Content:    seed.Content,
+		Title:      seed.Title,
+		Type:       seed.Type,
+		Confidence: seed.Confidence,
+		Protected:  seed.Protected,
+		Tags:       seed.Tags,
+		Metadata:   seed.Metadata,
+	}
+
+	load := func(param string) (*db.SeedRevision, string, error) {
+		v := c.QueryParam(param)
+		if v == "" || v == "current" {
+			return current, "current", nil
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, "", fmt.Errorf("%s must be a revision number or \"current\"", param)
+		}
+		rev, err := h.db.GetSeedRevision(ctx, ns, id, n)
+		if err != nil {
+			return nil, "", err
+		}
+		if rev == nil {
+			return nil, "", fmt.Errorf("revision %d not found", n)
+		}
+		return rev, v, nil
+	}
+
+	if c.QueryParam("from") == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "from is required"})
+	}
+	from, fromName, err := load("from")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	to, toName, err := load("to")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	diff := SeedDiff{SeedID: id, From: fromName, To: toName, Fields: []FieldChange{}}
+	for _, f := range []FieldChange{
+		{"title", from.Title, to.Title},
+		{"type", from.Type, to.Type},
+		{"confidence", fmt.Sprint(from.Confidence), fmt.Sprint(to.Confidence)},
+		{"protected", strconv.FormatBool(from.Protected), strconv.FormatBool(to.Protected)},
+		{"tags", strings.Join(from.Tags, ", "), strings.Join(to.Tags, ", ")},
+		{"metadata", string(from.Metadata), string(to.Metadata)},
+	} {
+		if f.From != f.To {
+			diff.Fields = append(diff.Fields, f)
+		}
+	}
+	diff.Content = diffLines(strings.Split(from.Content, "\n"), strings.Split(to.Content, "\n"))
+	return c.JSON(http.StatusOK, diff)
+}
+
+// maxDiffCells caps the LCS table of diffLines. Larger diffs fall back to a
+// linear one that is correct but not minimal.
+const maxDiffCells = 1 << 20
+
+// diffLines diffs a and b line by line. Lines shared at both ends are matched
+// directly; the rest gets a minimal diff from the longest common subsequence
+// when its table fits in maxDiffCells, and is otherwise reported as deleted
+// and then inserted.
+func diffLines(a, b []string) []DiffLine {
+	var out []DiffLine
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		out = append(out, DiffLine{"equal", a[0]})
+		a, b = a[1:], b[1:]
+	}
+	var suffix []DiffLine
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		suffix = append(suffix, DiffLine{"equal", a[len(a)-1]})
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+
+	if (len(a)+1)*(len(b)+1) <= maxDiffCells {
+		out = append(out, lcsDiff(a, b)...)
+	} else {
+		for _, line := range a {
+			out = append(out, DiffLine{"delete", line})
+		}
+		for _, line := range b {
+			out = append(out, DiffLine{"insert", line})
+		}
+	}
+
+	for i := len(suffix) - 1; i >= 0; i-- {
+		out = append(out, suffix[i])
+	}
+	return out
+}
+
+// lcsDiff computes a minimal line diff from the longest common subsequence
+// of a and b, using a table of (len(a)+1)·(len(b)+1) cells.
+func lcsDiff(a, b []string) []DiffLine {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out []DiffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, DiffLine{"equal", a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, DiffLine{"delete", a[i]})
+			i++
+		default:
+			out = append(out, DiffLine{"insert", b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		out = append(out, DiffLine{"delete", a[i]})
+	}
+	for ; j < len(b); j++ {
+		out = append(out, DiffLine{"insert", b[j]})
+	}
+	return out
+}
@@ -0,0 +1,63 @@
+package admin
+
+import (
+	"strings"
+	"testing"
+)
+
+// render writes a diff as " line", "-line" and "+line".
+func render(diff []DiffLine) string {
+	prefix := map[string]string{"equal": " ", "delete": "-", "insert": "+"}
+	lines := make([]string, len(diff))
+	for i, d := range diff {
+		lines[i] = prefix[d.Op] + d.Text
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"identical", "a\nb", "a\nb", " a\n b"},
+		{"insert in middle", "a\nc", "a\nb\nc", " a\n+b\n c"},
+		{"delete at end", "a\nb\nc", "a\nb", " a\n b\n-c"},
+		{"replace line", "a\nb\nc", "a\nx\nc", " a\n-b\n+x\n c"},
+		{"all new", "a", "b", "-a\n+b"},
+		{"from empty", "", "a", "-\n+a"},
+		{"moved line", "a\nb\nc\nd", "b\nc\na\nd", "-a\n b\n c\n+a\n d"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := render(diffLines(strings.Split(tt.a, "\n"), strings.Split(tt.b, "\n")))
+			if got != tt.want {
+				t.Errorf("diff:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffLinesLarge(t *testing.T) {
+	// Both sides differ everywhere except their first and last line, and
+	// the middle is too big for the LCS table.
+	n := 2000
+	a, b := make([]string, n), make([]string, n)
+	for i := range a {
+		a[i], b[i] = "a"+strings.Repeat("x", i%7), "b"+strings.Repeat("y", i%5)
+	}
+	a[0], b[0] = "head", "head"
+	a[n-1], b[n-1] = "tail", "tail"
+
+	diff := diffLines(a, b)
+	if len(diff) != 2*n-2 {
+		t.Fatalf("got %d lines, want %d", len(diff), 2*n-2)
+	}
+	if diff[0] != (DiffLine{"equal", "head"}) || diff[len(diff)-1] != (DiffLine{"equal", "tail"}) {
+		t.Errorf("shared ends not kept: %+v … %+v", diff[0], diff[len(diff)-1])
+	}
+	if diff[1].Op != "delete" || diff[n-1].Op != "insert" {
+		t.Errorf("middle not reported as deleted then inserted: %+v, %+v", diff[1], diff[n-1])
+	}
+}
@@ -0,0 +1,117 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v5"
+
+	"jarvis-memory/internal/api"
+	"jarvis-memory/internal/auth"
+	"jarvis-memory/internal/db"
+)
+
+type CreateKeyRequest struct {
+	Name       string   `json:"name"`
+	Scopes     []string `json:"scopes"`
+	Namespaces []string `json:"namespaces"`
+}
+
+// CreateKeyResponse carries the plaintext key. It is returned exactly once;
+// only its hash is stored.
+type CreateKeyResponse struct {
+	db.APIKey
+	Key string `json:"key"`
+}
+
+func (h *AdminHandler) HandleListKeys(c *echo.Context) error {
+	keys, err := h.db.ListAPIKeys(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if keys == nil {
+		keys = []db.APIKey{}
+	}
+	return c.JSON(http.StatusOK, keys)
+}
+
+func (h *AdminHandler) HandleCreateKey(c *echo.Context) error {
+	var req CreateKeyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid json"})
+	}
+
+	resp, err := CreateKey(c.Request().Context(), h.db, api.APIKey(c), req)
+	if errors.Is(err, ErrExceedsIssuer) {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	}
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusCreated, resp)
+}
+
+func (h *AdminHandler) HandleRevokeKey(c *echo.Context) error {
+	id := c.Param("id")
+
+	if err := h.db.RevokeAPIKey(c.Request().Context(), id); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, map[string]bool{"revoked": true})
+}
+
+// ErrExceedsIssuer is returned when a key would grant more than the key
+// minting it holds.
+var ErrExceedsIssuer = errors.New("cannot grant more than the issuing key holds")
+
+// CreateKey validates req, mints a new key and stores its hash. It is shared
+// by the HTTP endpoint and the `jarvis-memory keys create` command. A non-nil
+// issuer is the key making the request; the new key may only carry scopes and
+// namespaces the issuer already holds. The CLI passes nil, as does the HTTP
+// endpoint when auth is disabled.
+func CreateKey(ctx context.Context, d *db.DB, issuer *db.APIKey, req CreateKeyRequest) (*CreateKeyResponse, error) {
+	if req.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if len(req.Scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+	for _, s := range req.Scopes {
+		if !auth.ValidScope(s) {
+			return nil, fmt.Errorf("unknown scope %q", s)
+		}
+	}
+	if len(req.Namespaces) == 0 {
+		req.Namespaces = []string{db.DefaultNamespace}
+	}
+	if issuer != nil {
+		for _, s := range req.Scopes {
+			if !auth.HasScope(issuer, s) {
+				return nil, fmt.Errorf("%w: scope %q", ErrExceedsIssuer, s)
+			}
+		}
+		for _, ns := range req.Namespaces {
+			if !auth.HasNamespace(issuer, ns) {
+				return nil, fmt.Errorf("%w: namespace %q", ErrExceedsIssuer, ns)
+			}
+		}
+	}
+
+	key, prefix, hash, err := auth.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	k := db.APIKey{
+		Name:       req.Name,
+		Prefix:     prefix,
+		Scopes:     req.Scopes,
+		Namespaces: req.Namespaces,
+	}
+	if err := d.InsertAPIKey(ctx, &k, hash); err != nil {
+		return nil, err
+	}
+	return &CreateKeyResponse{APIKey: k, Key: key}, nil
+}
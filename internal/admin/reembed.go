@@ -0,0 +1,46 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v5"
+
+	"jarvis-memory/internal/reembed"
+)
+
+type StartReembedRequest struct {
+	// Target names one of the embedders configured in REEMBED_TARGETS.
+	Target string `json:"target"`
+}
+
+// HandleStartReembed starts a background job that re-embeds every seed and
+// agent context with the server-configured target named in the request body.
+func (h *AdminHandler) HandleStartReembed(c *echo.Context) error {
+	var req StartReembedRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid json"})
+	}
+
+	job, err := h.reembed.Start(c.Request().Context(), req.Target)
+	switch {
+	case errors.Is(err, reembed.ErrRunning):
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	case errors.Is(err, reembed.ErrUnknownTarget):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case err != nil:
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusAccepted, job)
+}
+
+func (h *AdminHandler) HandleReembedStatus(c *echo.Context) error {
+	job, err := h.db.LatestReembedJob(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if job == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "no re-embed job has run"})
+	}
+	return c.JSON(http.StatusOK, job)
+}
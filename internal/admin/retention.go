@@ -0,0 +1,17 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v5"
+)
+
+// HandleRunContextMaintenance creates upcoming agent context partitions,
+// rolls up and deletes expired contexts, and drops emptied partitions now.
+func (h *AdminHandler) HandleRunContextMaintenance(c *echo.Context) error {
+	report, err := h.retention.RunOnce(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, report)
+}
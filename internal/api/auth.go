@@ -0,0 +1,90 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v5"
+
+	"jarvis-memory/internal/auth"
+	"jarvis-memory/internal/db"
+)
+
+// APIKeyHeader is an alternative to "Authorization: Bearer <key>".
+const APIKeyHeader = "X-API-Key"
+
+const apiKeyKey = "apiKey"
+
+// Authenticator checks API keys against the api_keys table. When disabled,
+// every request is allowed, which keeps single-user local setups working
+// without any configuration.
+type Authenticator struct {
+	db      *db.DB
+	enabled bool
+}
+
+func NewAuthenticator(d *db.DB, enabled bool) *Authenticator {
+	return &Authenticator{db: d, enabled: enabled}
+}
+
+// Require returns middleware that rejects requests whose key lacks scope or
+// access to the namespace resolved by NamespaceMiddleware.
+func (a *Authenticator) Require(scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c *echo.Context) error {
+			if !a.enabled {
+				return next(c)
+			}
+
+			token := bearerToken(c.Request())
+			if token == "" {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "api key required"})
+			}
+
+			key, err := a.db.AuthenticateAPIKey(c.Request().Context(), auth.HashKey(token))
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			}
+			if key == nil {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid api key"})
+			}
+
+			ns, _ := c.Get(namespaceKey).(string)
+			if !auth.Allows(key, scope, ns) {
+				target := "this namespace"
+				if ns == "" {
+					target = "all namespaces"
+				}
+				return c.JSON(http.StatusForbidden, map[string]string{"error": "api key lacks " + scope + " access to " + target})
+			}
+
+			c.Set(apiKeyKey, key)
+			return next(c)
+		}
+	}
+}
+
+// APIKey returns the key that authenticated the request, or nil when auth
+// is disabled.
+func APIKey(c *echo.Context) *db.APIKey {
+	k, _ := c.Get(apiKeyKey).(*db.APIKey)
+	return k
+}
+
+// Actor names whoever made the request, for audit trails such as seed
+// revisions: the API key's name and prefix, or "anonymous" without auth.
+func Actor(c *echo.Context) string {
+	if k := APIKey(c); k != nil {
+		return k.Name + " (" + k.Prefix + ")"
+	}
+	return "anonymous"
+}
+
+func bearerToken(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); h != "" {
+		if token, ok := strings.CutPrefix(h, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	return strings.TrimSpace(r.Header.Get(APIKeyHeader))
+}
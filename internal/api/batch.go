@@ -0,0 +1,285 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/labstack/echo/v5"
+
+	"jarvis-memory/internal/db"
+	"jarvis-memory/internal/embeddings"
+)
+
+// maxBatchSeeds caps how many seeds one /seeds/batch request may carry.
+const maxBatchSeeds = 1000
+
+// maxBatchBytes caps the size of a /seeds/batch body.
+const maxBatchBytes = 64 << 20
+
+var errBatchTooLarge = fmt.Errorf("batch exceeds %d seeds", maxBatchSeeds)
+
+type BatchSeedItem struct {
+	Content    string          `json:"content"`
+	Title      string          `json:"title"`
+	Type       string          `json:"type"`
+	Confidence float32         `json:"confidence"`
+	Tags       []string        `json:"tags"`
+	Metadata   json.RawMessage `json:"metadata"`
+}
+
+// batchEntry is one decoded item; err is set when its NDJSON line was malformed.
+type batchEntry struct {
+	item BatchSeedItem
+	err  string
+}
+
+type BatchSeedResult struct {
+	Index  int    `json:"index"`
+	Status string `json:"status"`
+	ID     string `json:"id,omitempty"`
+	Error  string `json:"error,omitempty"`
+
+	// Action is the dedup outcome; ID is the existing seed for merged and
+	// rejected items.
+	Action     string   `json:"action,omitempty"`
+	Similarity *float32 `json:"similarity,omitempty"`
+}
+
+type BatchSeedsResponse struct {
+	Created  int               `json:"created"`
+	Merged   int               `json:"merged"`
+	Rejected int               `json:"rejected"`
+	Failed   int               `json:"failed"`
+	Results  []BatchSeedResult `json:"results"`
+}
+
+// HandleCreateSeedsBatch ingests many seeds at once. The body is either a
+// JSON array or NDJSON (one seed object per line). Invalid items, and items
+// the database rejects, are reported individually instead of failing the
+// whole batch.
+func (h *Handler) HandleCreateSeedsBatch(c *echo.Context) error {
+	entries, err := decodeBatch(c.Response(), c.Request())
+	var tooBig *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooBig):
+		return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{"error": fmt.Sprintf("batch body exceeds %d bytes", maxBatchBytes)})
+	case err != nil:
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if len(entries) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "batch is empty"})
+	}
+
+	results := make([]BatchSeedResult, len(entries))
+	var valid []int
+	for i, entry := range entries {
+		item := entry.item
+		results[i] = BatchSeedResult{Index: i}
+		fieldErr := checkSeedFields(item.Content, item.Title, item.Type)
+		metaErr := checkMetadata(item.Metadata)
+		switch {
+		case entry.err != "":
+			results[i].Error = entry.err
+		case fieldErr != nil:
+			results[i].Error = fieldErr.Error()
+		case item.Confidence < 0 || item.Confidence > 1:
+			results[i].Error = "confidence must be between 0.0 and 1.0"
+		case metaErr != nil:
+			results[i].Error = metaErr.Error()
+		default:
+			valid = append(valid, i)
+		}
+	}
+
+	texts := make([]string, len(valid))
+	for j, i := range valid {
+		texts[j] = entries[i].item.Content
+	}
+	embs := h.embedSeeds(texts)
+
+	var seeds []*db.Seed
+	var seedEmbs [][]float32
+	var seedChunks [][]db.SeedChunk
+	var seedIdx []int
+	for j, i := range valid {
+		if embs[j] == nil {
+			results[i].Error = "failed to embed content"
+			continue
+		}
+		item := entries[i].item
+		seeds = append(seeds, &db.Seed{
+			Namespace:      Namespace(c),
+			Content:        item.Content,
+			Title:          item.Title,
+			Type:           item.Type,
+			Confidence:     item.Confidence,
+			Tags:           normalizeTags(item.Tags),
+			Metadata:       item.Metadata,
+			EmbeddingModel: embs[j].model,
+		})
+		seedEmbs = append(seedEmbs, embs[j].embedding)
+		seedChunks = append(seedChunks, embs[j].chunks)
+		seedIdx = append(seedIdx, i)
+	}
+
+	if len(seeds) > 0 {
+		inserted, insertErr := h.db.InsertSeeds(c.Request().Context(), seeds, seedEmbs, seedChunks, h.cfg.Dedup)
+		for k, i := range seedIdx {
+			if insertErr != nil {
+				results[i].Error = insertErr.Error()
+				continue
+			}
+			if inserted[k].Err != nil {
+				results[i].Error = inserted[k].Err.Error()
+				continue
+			}
+			results[i].ID = inserted[k].SeedID
+			results[i].Action = inserted[k].Action
+			results[i].Similarity = inserted[k].Similarity
+		}
+	}
+
+	resp := BatchSeedsResponse{Results: results}
+	for i := range results {
+		switch {
+		case results[i].Error != "":
+			results[i].Status = "error"
+			resp.Failed++
+		case results[i].Action == db.ActionMerged:
+			results[i].Status = "merged"
+			resp.Merged++
+		case results[i].Action == db.ActionRejected:
+			results[i].Status = "rejected"
+			resp.Rejected++
+		default:
+			results[i].Status = "created"
+			resp.Created++
+		}
+	}
+
+	status := http.StatusCreated
+	switch {
+	case resp.Created > 0:
+	case resp.Merged > 0 || resp.Rejected > 0:
+		status = http.StatusOK
+	default:
+		status = http.StatusUnprocessableEntity
+	}
+	return c.JSON(status, resp)
+}
+
+// embedBatch embeds texts in one call and returns the model that produced
+// the vectors. If the batch call fails it retries item by item so a single
+// bad input only fails itself; failed entries are left nil.
+func (h *Handler) embedBatch(texts []string) ([][]float32, string) {
+	if len(texts) == 0 {
+		return nil, ""
+	}
+	var embs [][]float32
+	var model string
+	embeddings.Pin(h.emb, func(e embeddings.Embedder) error {
+		model = e.ModelID()
+		var err error
+		if embs, err = e.EmbedBatch(texts); err == nil {
+			return nil
+		}
+		embs = make([][]float32, len(texts))
+		for i, text := range texts {
+			if emb, err := e.Embed(text); err == nil {
+				embs[i] = emb
+			}
+		}
+		return nil
+	})
+	return embs, model
+}
+
+// decodeBatch reads the items of a /seeds/batch body as it streams in,
+// failing with errBatchTooLarge as soon as it holds more than maxBatchSeeds
+// and with an *http.MaxBytesError past maxBatchBytes.
+func decodeBatch(w http.ResponseWriter, r *http.Request) ([]batchEntry, error) {
+	br := bufio.NewReader(http.MaxBytesReader(w, r.Body, maxBatchBytes))
+	first, err := peekNonSpace(br)
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, batchReadError(err, "failed to read body")
+	}
+
+	var entries []batchEntry
+	if !strings.Contains(r.Header.Get("Content-Type"), "ndjson") && first == '[' {
+		dec := json.NewDecoder(br)
+		if _, err := dec.Token(); err != nil {
+			return nil, batchReadError(err, "invalid json")
+		}
+		for dec.More() {
+			if len(entries) == maxBatchSeeds {
+				return nil, errBatchTooLarge
+			}
+			var entry batchEntry
+			if err := dec.Decode(&entry.item); err != nil {
+				return nil, batchReadError(err, "invalid json")
+			}
+			entries = append(entries, entry)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, batchReadError(err, "invalid json")
+		}
+		return entries, nil
+	}
+
+	scanner := bufio.NewScanner(br)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		if len(entries) == maxBatchSeeds {
+			return nil, errBatchTooLarge
+		}
+		var entry batchEntry
+		if err := json.Unmarshal(text, &entry.item); err != nil {
+			entry.err = fmt.Sprintf("invalid json on line %d", line)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, batchReadError(err, "failed to read ndjson: "+err.Error())
+	}
+	return entries, nil
+}
+
+// peekNonSpace skips leading whitespace and returns the next byte without
+// consuming it.
+func peekNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if !unicode.IsSpace(rune(b)) {
+			return b, br.UnreadByte()
+		}
+	}
+}
+
+// batchReadError keeps an oversized body recognisable and replaces any other
+// read or decode error with msg.
+func batchReadError(err error, msg string) error {
+	var tooBig *http.MaxBytesError
+	if errors.As(err, &tooBig) {
+		return err
+	}
+	return errors.New(msg)
+}
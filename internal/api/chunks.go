@@ -0,0 +1,58 @@
+package api
+
+import (
+	"jarvis-memory/internal/chunking"
+	"jarvis-memory/internal/db"
+)
+
+// embeddedSeed is the embedding of a seed's full content plus, for content
+// too long for one embedding window, its embedded chunks. model produced
+// all of them.
+type embeddedSeed struct {
+	embedding []float32
+	chunks    []db.SeedChunk
+	model     string
+}
+
+// embedSeeds embeds each content together with its chunks in a single
+// embedding call. An entry is nil if any of its texts failed to embed.
+func (h *Handler) embedSeeds(contents []string) []*embeddedSeed {
+	var texts []string
+	starts := make([]int, len(contents))
+	splits := make([][]chunking.Chunk, len(contents))
+	for i, content := range contents {
+		starts[i] = len(texts)
+		texts = append(texts, content)
+		if chunks := chunking.Split(content, chunking.DefaultOptions); len(chunks) > 1 {
+			splits[i] = chunks
+			for _, ch := range chunks {
+				texts = append(texts, ch.Text)
+			}
+		}
+	}
+
+	embs, model := h.embedBatch(texts)
+	out := make([]*embeddedSeed, len(contents))
+	for i := range contents {
+		es := &embeddedSeed{embedding: embs[starts[i]], model: model}
+		ok := es.embedding != nil
+		for k, ch := range splits[i] {
+			emb := embs[starts[i]+1+k]
+			if emb == nil {
+				ok = false
+				break
+			}
+			es.chunks = append(es.chunks, db.SeedChunk{Index: ch.Index, Content: ch.Text, Embedding: emb})
+		}
+		if ok {
+			out[i] = es
+		}
+	}
+	return out
+}
+
+// embedSeed is embedSeeds for a single content.
+func (h *Handler) embedSeed(content string) (*embeddedSeed, bool) {
+	es := h.embedSeeds([]string{content})[0]
+	return es, es != nil
+}
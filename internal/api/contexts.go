@@ -0,0 +1,168 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/labstack/echo/v5"
+
+	"jarvis-memory/internal/db"
+	"jarvis-memory/internal/embeddings"
+)
+
+// agentContextText is what an agent context's embedding is computed from:
+// the summary, else the metadata, else the type. It mirrors the TextExpr of
+// the agent_contexts re-embed target, so metadata must already be in its
+// jsonb rendering (see normalizeContextMetadata).
+func agentContextText(ac *db.AgentContext) string {
+	if ac.Summary != "" {
+		return ac.Summary
+	}
+	if len(ac.Metadata) > 0 {
+		return string(ac.Metadata)
+	}
+	return ac.Type
+}
+
+// normalizeContextMetadata rewrites the metadata of a summary-less context,
+// which is embedded, in Postgres' jsonb rendering. The request bytes may
+// differ in key order and spacing, and the re-embed job would otherwise give
+// the context a different vector than it was created with.
+func (h *Handler) normalizeContextMetadata(ctx context.Context, ac *db.AgentContext) error {
+	if ac.Summary != "" || len(ac.Metadata) == 0 {
+		return nil
+	}
+	meta, err := h.db.JSONBText(ctx, ac.Metadata)
+	if err != nil {
+		return err
+	}
+	ac.Metadata = meta
+	return nil
+}
+
+// UpdateAgentContextRequest is the body of PUT and PATCH
+// /agent-contexts/:id. PUT replaces the context, so omitted metadata and
+// summary are cleared; PATCH only changes the fields present, and clears
+// metadata given as null or {}.
+type UpdateAgentContextRequest struct {
+	AgentID  *string         `json:"agentId"`
+	Type     *string         `json:"type"`
+	Metadata json.RawMessage `json:"metadata"`
+	Summary  *string         `json:"summary"`
+}
+
+// HandleReplaceAgentContext handles PUT /agent-contexts/:id.
+func (h *Handler) HandleReplaceAgentContext(c *echo.Context) error {
+	return h.updateAgentContext(c, false)
+}
+
+// HandlePatchAgentContext handles PATCH /agent-contexts/:id.
+func (h *Handler) HandlePatchAgentContext(c *echo.Context) error {
+	return h.updateAgentContext(c, true)
+}
+
+func (h *Handler) updateAgentContext(c *echo.Context, patch bool) error {
+	var req UpdateAgentContextRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid json"})
+	}
+	if !patch && (req.AgentID == nil || *req.AgentID == "" || req.Type == nil || *req.Type == "") {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "agentId and type are required"})
+	}
+	if (req.AgentID != nil && *req.AgentID == "") || (req.Type != nil && *req.Type == "") {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "agentId and type must not be empty"})
+	}
+
+	ctx := c.Request().Context()
+	ac, err := h.db.GetAgentContextByID(ctx, Namespace(c), c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if ac == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "agent context not found"})
+	}
+
+	before := agentContextText(ac)
+	if !patch {
+		ac.Metadata, ac.Summary = nil, ""
+	}
+	if req.AgentID != nil {
+		ac.AgentID = *req.AgentID
+	}
+	if req.Type != nil {
+		ac.Type = *req.Type
+	}
+	switch {
+	case isJSONNull(req.Metadata):
+		ac.Metadata = nil
+	case len(req.Metadata) > 0:
+		ac.Metadata = req.Metadata
+	}
+	if req.Summary != nil {
+		ac.Summary = *req.Summary
+	}
+	if err := h.normalizeContextMetadata(ctx, ac); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	// Only re-embed when the embedded text changed
+	var emb []float32
+	if text := agentContextText(ac); text != before {
+		emb, ac.EmbeddingModel, err = embeddings.EmbedWithModel(h.emb, text)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to embed agent context"})
+		}
+	}
+
+	if err := h.db.UpdateAgentContext(ctx, ac, emb); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, ac)
+}
+
+func (h *Handler) HandleDeleteAgentContext(c *echo.Context) error {
+	if err := h.db.DeleteAgentContext(c.Request().Context(), Namespace(c), c.Param("id")); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, map[string]bool{"deleted": true})
+}
+
+// HandleDeleteAgentContexts deletes the contexts of ?agentId=, optionally
+// limited to ?since= and ?until= (same keywords as search). Protected
+// contexts are kept and counted.
+func (h *Handler) HandleDeleteAgentContexts(c *echo.Context) error {
+	opts := db.AgentContextDeleteOptions{
+		Namespace: Namespace(c),
+		AgentID:   c.QueryParam("agentId"),
+		Since:     parseTimeKeyword(c.QueryParam("since")),
+		Until:     parseTimeKeyword(c.QueryParam("until")),
+	}
+	if opts.AgentID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "agentId is required"})
+	}
+	// A typo in the range must not widen the delete to the whole agent
+	if (c.QueryParam("since") != "" && opts.Since == nil) || (c.QueryParam("until") != "" && opts.Until == nil) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "since and until must be a date or time keyword"})
+	}
+
+	res, err := h.db.DeleteAgentContexts(c.Request().Context(), opts)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+func (h *Handler) HandleSetAgentContextProtected(c *echo.Context) error {
+	id := c.Param("id")
+
+	var req SetProtectedRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid json"})
+	}
+
+	if err := h.db.SetAgentContextProtected(c.Request().Context(), Namespace(c), id, req.Protected); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"id": id, "protected": req.Protected})
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v5"
+
+	"jarvis-memory/internal/db"
+	"jarvis-memory/internal/embeddings"
+)
+
+// Types of what a conversation turn is stored as.
+const (
+	turnSeedType    = "auto_capture"
+	turnContextType = "episodic"
+)
+
+// turnSummaryRunes caps the agent context summary of a turn.
+const turnSummaryRunes = 200
+
+// sessionTitleRunes caps the title a session gets from its first message.
+const sessionTitleRunes = 80
+
+// toolOutputRunes caps each tool output quoted in a turn's seed.
+const toolOutputRunes = 500
+
+// ToolCall is one tool invocation made during a turn.
+type ToolCall struct {
+	Name   string          `json:"name"`
+	Input  json.RawMessage `json:"input,omitempty"`
+	Output string          `json:"output,omitempty"`
+}
+
+// ConversationTurnRequest is the body of POST /conversations/turns.
+type ConversationTurnRequest struct {
+	AgentID string `json:"agentId"`
+	// SessionID is the client's session ID or the ID of a session; the turn
+	// joins that session, starting it if needed.
+	SessionID         string     `json:"sessionId"`
+	UserMessage       string     `json:"userMessage"`
+	AssistantResponse string     `json:"assistantResponse"`
+	ToolCalls         []ToolCall `json:"toolCalls"`
+}
+
+// ConversationTurnResponse is the stored turn with the derived seed (and its
+// dedup outcome) and agent context.
+type ConversationTurnResponse struct {
+	Turn         *db.ConversationTurn `json:"turn"`
+	Seed         *db.Seed             `json:"seed"`
+	SeedResult   db.InsertResult      `json:"seed_result"`
+	AgentContext *db.AgentContext     `json:"agent_context"`
+}
+
+// HandleCreateConversationTurn stores one conversation turn and derives a
+// thread snapshot seed and an episodic agent context from it.
+func (h *Handler) HandleCreateConversationTurn(c *echo.Context) error {
+	var req ConversationTurnRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid json"})
+	}
+
+	if req.AgentID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "agentId is required"})
+	}
+	if strings.TrimSpace(req.UserMessage) == "" && strings.TrimSpace(req.AssistantResponse) == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "userMessage or assistantResponse is required"})
+	}
+	for i, tc := range req.ToolCalls {
+		if tc.Name == "" {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("toolCalls[%d]: name is required", i)})
+		}
+		if len(tc.Input) > 0 && !json.Valid(tc.Input) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("toolCalls[%d]: input must be JSON", i)})
+		}
+	}
+
+	now := time.Now().UTC()
+	content := turnContent(req)
+	emb, ok := h.embedSeed(content)
+	if !ok {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to embed content"})
+	}
+
+	meta, _ := json.Marshal(map[string]string{"source": "conversation_turn", "agentId": req.AgentID})
+	seed := &db.Seed{
+		Namespace:      Namespace(c),
+		Content:        content,
+		Title:          "Thread snapshot - " + now.Format(time.RFC3339),
+		Type:           turnSeedType,
+		Metadata:       meta,
+		EmbeddingModel: emb.model,
+	}
+
+	summary := turnSummary(req)
+	ctxMeta, _ := json.Marshal(map[string]string{"timestamp": now.Format(time.RFC3339), "source": "auto_capture"})
+	ac := &db.AgentContext{
+		Namespace: Namespace(c),
+		AgentID:   req.AgentID,
+		Type:      turnContextType,
+		Metadata:  ctxMeta,
+		Summary:   summary,
+	}
+	if err := h.normalizeContextMetadata(c.Request().Context(), ac); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	acEmb, model, err := embeddings.EmbedWithModel(h.emb, agentContextText(ac))
+	ac.EmbeddingModel = model
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to embed agent context"})
+	}
+
+	turn := &db.ConversationTurn{
+		Namespace:         Namespace(c),
+		AgentID:           req.AgentID,
+		UserMessage:       req.UserMessage,
+		AssistantResponse: req.AssistantResponse,
+	}
+	if len(req.ToolCalls) > 0 {
+		turn.ToolCalls, _ = json.Marshal(req.ToolCalls)
+	}
+
+	var session *db.SessionTurn
+	if req.SessionID != "" {
+		session = &db.SessionTurn{
+			Ref:         req.SessionID,
+			Title:       truncateRunes(strings.Join(strings.Fields(req.UserMessage), " "), sessionTitleRunes),
+			SummaryLine: summary,
+		}
+	}
+
+	res, err := h.db.InsertConversationTurn(c.Request().Context(), turn, db.ConversationTurnInsert{
+		Session:               session,
+		Seed:                  seed,
+		SeedEmbedding:         emb.embedding,
+		SeedChunks:            emb.chunks,
+		Dedup:                 h.cfg.Dedup,
+		AgentContext:          ac,
+		AgentContextEmbedding: acEmb,
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusCreated, ConversationTurnResponse{Turn: turn, Seed: seed, SeedResult: res, AgentContext: ac})
+}
+
+// turnContent renders a turn as seed content. It leaves out the timestamp,
+// which the seed's title and created_at already carry, so that repeated
+// turns are caught by dedup.
+func turnContent(req ConversationTurnRequest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Post: %s\n\nComments:\nUser: %s\n%s: %s", req.UserMessage, req.UserMessage, req.AgentID, req.AssistantResponse)
+	if len(req.ToolCalls) > 0 {
+		b.WriteString("\n\nTool calls:")
+		for _, tc := range req.ToolCalls {
+			fmt.Fprintf(&b, "\n- %s", tc.Name)
+			if len(tc.Input) > 0 {
+				fmt.Fprintf(&b, " %s", tc.Input)
+			}
+			if tc.Output != "" {
+				fmt.Fprintf(&b, " → %s", truncateRunes(tc.Output, toolOutputRunes))
+			}
+		}
+	}
+	return b.String()
+}
+
+// turnSummary is the start of the assistant's response, or of the user
+// message when there is no response.
+func turnSummary(req ConversationTurnRequest) string {
+	if strings.TrimSpace(req.AssistantResponse) != "" {
+		return truncateRunes(req.AssistantResponse, turnSummaryRunes)
+	}
+	return truncateRunes(req.UserMessage, turnSummaryRunes)
+}
+
+// truncateRunes cuts s to at most n runes without splitting a character.
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v5"
+
+	"jarvis-memory/internal/db"
+)
+
+type FeedbackRequest struct {
+	Signal  string `json:"signal"`
+	Comment string `json:"comment"`
+	// Query and Score describe the recall being judged; both are optional
+	// but make the events useful for tuning search thresholds.
+	Query string   `json:"query"`
+	Score *float32 `json:"score"`
+}
+
+func (h *Handler) HandleSeedFeedback(c *echo.Context) error {
+	var req FeedbackRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid json"})
+	}
+
+	adj, ok := h.cfg.Feedback[req.Signal]
+	if !ok {
+		signals := make([]string, 0, len(h.cfg.Feedback))
+		for s := range h.cfg.Feedback {
+			signals = append(signals, s)
+		}
+		sort.Strings(signals)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "signal must be one of " + strings.Join(signals, ", ")})
+	}
+
+	fb := &db.SeedFeedback{
+		SeedID:    c.Param("id"),
+		Namespace: Namespace(c),
+		Signal:    req.Signal,
+		Comment:   req.Comment,
+		Query:     req.Query,
+		Score:     req.Score,
+		Actor:     Actor(c),
+	}
+	if err := h.db.RecordFeedback(c.Request().Context(), fb, adj); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusCreated, fb)
+}
+
+// feedbackQuery reads the shared filters of the feedback listing routes.
+func feedbackQuery(c *echo.Context) db.FeedbackQuery {
+	q := db.FeedbackQuery{
+		Namespace: Namespace(c),
+		SeedID:    c.QueryParam("seedId"),
+		Signal:    c.QueryParam("signal"),
+		Since:     parseTimeKeyword(c.QueryParam("since")),
+		Until:     parseTimeKeyword(c.QueryParam("until")),
+	}
+	if id := c.Param("id"); id != "" {
+		q.SeedID = id
+	}
+	if l, err := strconv.Atoi(c.QueryParam("limit")); err == nil && l > 0 {
+		q.Limit = l
+	}
+	return q
+}
+
+// HandleListFeedback lists feedback events, filtered by ?seedId=, ?signal=,
+// ?since= and ?until=.
+func (h *Handler) HandleListFeedback(c *echo.Context) error {
+	events, err := h.db.ListFeedback(c.Request().Context(), feedbackQuery(c))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if events == nil {
+		events = []db.SeedFeedback{}
+	}
+	return c.JSON(http.StatusOK, events)
+}
+
+// HandleFeedbackStats aggregates feedback per signal with the score
+// distribution of the judged recalls.
+func (h *Handler) HandleFeedbackStats(c *echo.Context) error {
+	stats, err := h.db.FeedbackStatistics(c.Request().Context(), feedbackQuery(c))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if stats == nil {
+		stats = []db.FeedbackStats{}
+	}
+	return c.JSON(http.StatusOK, stats)
+}
@@ -3,53 +3,98 @@ package api
 import (
 	"encoding/json"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/labstack/echo/v5"
 
+	"jarvis-memory/internal/auth"
 	"jarvis-memory/internal/db"
 	"jarvis-memory/internal/embeddings"
 )
 
+// Config holds the tunable behaviour of the API handlers.
+type Config struct {
+	// Dedup decides what happens when a new seed nearly duplicates an
+	// existing one.
+	Dedup db.DedupOptions
+	// Reinforcement raises the confidence of seeds returned by search.
+	Reinforcement db.ReinforcementOptions
+	// Feedback maps each feedback signal to its confidence adjustment.
+	Feedback db.FeedbackRules
+}
+
 type Handler struct {
-	db  *db.DB
-	emb *embeddings.Service
+	db   *db.DB
+	emb  embeddings.Embedder
+	auth *Authenticator
+	cfg  Config
 }
 
-func NewHandler(d *db.DB, e *embeddings.Service) *Handler {
-	return &Handler{db: d, emb: e}
+func NewHandler(d *db.DB, e embeddings.Embedder, a *Authenticator, cfg Config) *Handler {
+	return &Handler{db: d, emb: e, auth: a, cfg: cfg}
 }
 
+// RegisterRoutes mounts every route twice: at the root, where the namespace
+// comes from the X-Jarvis-Namespace header, and under /ns/:namespace.
 func (h *Handler) RegisterRoutes(e *echo.Echo) {
-	e.GET("/seeds", h.HandleListSeeds)
-	e.POST("/seeds", h.HandleCreateSeed)
-	e.POST("/seeds/query", h.HandleQuerySeeds)
-	e.DELETE("/seeds/:id", h.HandleDeleteSeed)
-	e.PUT("/seeds/:id", h.HandleUpdateSeed)
-	e.POST("/seeds/:id/confidence", h.HandleSetConfidence)
-	e.POST("/seeds/:id/protect", h.HandleSetProtected)
-	e.POST("/agent-contexts", h.HandleCreateAgentContext)
-	e.GET("/agent-contexts", h.HandleGetAgentContexts)
-	e.GET("/agent-contexts/:id", h.HandleGetAgentContext)
+	read := h.auth.Require(auth.ScopeRead)
+	write := h.auth.Require(auth.ScopeWrite)
+
+	for _, g := range []*echo.Group{e.Group("", NamespaceMiddleware), e.Group("/ns/:namespace", NamespaceMiddleware)} {
+		g.GET("/seeds", h.HandleListSeeds, read)
+		g.POST("/seeds", h.HandleCreateSeed, write)
+		g.POST("/seeds/batch", h.HandleCreateSeedsBatch, write)
+		g.POST("/seeds/query", h.HandleQuerySeeds, read)
+		g.POST("/recall", h.HandleRecall, read)
+		g.DELETE("/seeds/:id", h.HandleDeleteSeed, write)
+		g.PUT("/seeds/:id", h.HandleUpdateSeed, write)
+		g.POST("/seeds/:id/confidence", h.HandleSetConfidence, write)
+		g.POST("/seeds/:id/protect", h.HandleSetProtected, write)
+		g.GET("/seeds/:id/history", h.HandleSeedHistory, read)
+		g.GET("/seeds/:id/recalls", h.HandleSeedRecalls, read)
+		g.POST("/seeds/:id/feedback", h.HandleSeedFeedback, write)
+		g.GET("/seeds/:id/feedback", h.HandleListFeedback, read)
+		g.GET("/feedback", h.HandleListFeedback, read)
+		g.GET("/feedback/stats", h.HandleFeedbackStats, read)
+		g.POST("/seeds/:id/revert/:rev", h.HandleRevertSeed, write)
+		g.POST("/seeds/:id/restore", h.HandleRestoreSeed, write)
+		g.GET("/seeds/:id/links", h.HandleListSeedLinks, read)
+		g.POST("/seeds/:id/links", h.HandleCreateSeedLink, write)
+		g.PATCH("/seeds/:id/links/:linkId", h.HandleUpdateSeedLink, write)
+		g.DELETE("/seeds/:id/links/:linkId", h.HandleDeleteSeedLink, write)
+		g.GET("/trash", h.HandleListTrash, read)
+		g.POST("/conversations/turns", h.HandleCreateConversationTurn, write)
+		g.GET("/sessions", h.HandleListSessions, read)
+		g.POST("/sessions", h.HandleCreateSession, write)
+		g.GET("/sessions/:id", h.HandleGetSession, read)
+		g.PATCH("/sessions/:id", h.HandleUpdateSession, write)
+		g.GET("/sessions/:id/timeline", h.HandleSessionTimeline, read)
+		g.POST("/agent-contexts", h.HandleCreateAgentContext, write)
+		g.GET("/agent-contexts", h.HandleGetAgentContexts, read)
+		g.POST("/agent-contexts/query", h.HandleQueryAgentContexts, read)
+		g.GET("/agent-contexts/:id", h.HandleGetAgentContext, read)
+		g.PUT("/agent-contexts/:id", h.HandleReplaceAgentContext, write)
+		g.PATCH("/agent-contexts/:id", h.HandlePatchAgentContext, write)
+		g.DELETE("/agent-contexts/:id", h.HandleDeleteAgentContext, write)
+		g.DELETE("/agent-contexts", h.HandleDeleteAgentContexts, write)
+		g.POST("/agent-contexts/:id/protect", h.HandleSetAgentContextProtected, write)
+	}
 }
 
 func (h *Handler) HandleListSeeds(c *echo.Context) error {
-	limitStr := c.QueryParam("limit")
-	limit := 50
-	if limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
-			limit = l
-		}
+	opts, err := SeedListOptions(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 	}
 
-	seeds, err := h.db.ListSeeds(c.Request().Context(), limit)
+	seeds, next, err := h.db.ListSeeds(c.Request().Context(), opts)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return listError(c, err)
 	}
 	if seeds == nil {
 		seeds = []db.Seed{}
 	}
+	setNextLink(c, next)
 	return c.JSON(http.StatusOK, seeds)
 }
 
@@ -58,34 +103,104 @@ func (h *Handler) HandleCreateSeed(c *echo.Context) error {
 	title := c.FormValue("title")
 	typ := c.FormValue("type")
 
-	if content == "" || title == "" || typ == "" {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "content, title, and type are required"})
+	if err := checkSeedFields(content, title, typ); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 	}
-
-	emb, err := h.emb.Embed(content)
+	metadata := json.RawMessage(c.FormValue("metadata"))
+	if err := checkMetadata(metadata); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	sessionID, _, status, err := h.sessionIDs(c, "", c.FormValue("sessionId"), "")
 	if err != nil {
+		return c.JSON(status, map[string]string{"error": err.Error()})
+	}
+
+	emb, ok := h.embedSeed(content)
+	if !ok {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to embed content"})
 	}
 
 	seed := &db.Seed{
-		Content: content,
-		Title:   title,
-		Type:    typ,
+		Namespace:      Namespace(c),
+		Content:        content,
+		Title:          title,
+		Type:           typ,
+		Tags:           splitTags(c.FormValue("tags")),
+		Metadata:       metadata,
+		SessionID:      sessionID,
+		EmbeddingModel: emb.model,
 	}
 
-	if err := h.db.InsertSeed(c.Request().Context(), seed, emb); err != nil {
+	res, err := h.db.InsertSeed(c.Request().Context(), seed, emb.embedding, emb.chunks, h.cfg.Dedup)
+	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
 
-	return c.JSON(http.StatusCreated, seed)
+	resp := CreateSeedResponse{Seed: seed, InsertResult: res}
+	switch res.Action {
+	case db.ActionMerged:
+		return c.JSON(http.StatusOK, resp)
+	case db.ActionRejected:
+		resp.Error = "a near-duplicate seed already exists"
+		return c.JSON(http.StatusConflict, resp)
+	}
+	return c.JSON(http.StatusCreated, resp)
+}
+
+// CreateSeedResponse is the stored seed together with the dedup outcome. For
+// merged and rejected inserts the seed is the existing near-duplicate.
+type CreateSeedResponse struct {
+	*db.Seed
+	db.InsertResult
+	Error string `json:"error,omitempty"`
 }
 
+// maxMMRCandidates bounds how many candidates a search may re-rank.
+const maxMMRCandidates = 500
+
 type QuerySeedsRequest struct {
 	Query     string  `json:"query"`
 	Limit     int     `json:"limit"`
 	Threshold float32 `json:"threshold"`
 	Since     string  `json:"since"`
 	Until     string  `json:"until"`
+
+	// Mode is "vector" (default) or "hybrid". Hybrid mode fuses full-text
+	// ranking with vector similarity; the weights tune each side (default 1.0).
+	Mode           string   `json:"mode"`
+	SemanticWeight *float32 `json:"semanticWeight"`
+	LexicalWeight  *float32 `json:"lexicalWeight"`
+
+	// Filters applied inside the search: seeds carrying any / all of the
+	// tags, of one of the types, or whose metadata contains the object.
+	TagsAny  []string        `json:"tagsAny"`
+	TagsAll  []string        `json:"tagsAll"`
+	Types    []string        `json:"types"`
+	Metadata json.RawMessage `json:"metadata"`
+
+	// SessionID limits the search to one session, ExcludeSessionID leaves
+	// one out. Both take a session ID or external ID.
+	SessionID        string `json:"sessionId"`
+	ExcludeSessionID string `json:"excludeSessionId"`
+
+	// Lambda, when set, re-ranks the hits by maximal marginal relevance:
+	// 1 keeps the similarity order, lower values trade relevance for
+	// variety. Candidates is how many nearest neighbours to pick from
+	// (default 4 × limit, max 500). Each hit then explains its pick in "mmr".
+	Lambda     *float32 `json:"lambda"`
+	Candidates int      `json:"candidates"`
+
+	// EfSearch sets hnsw.ef_search (1-1000) for this query: higher values
+	// find the true nearest neighbours more reliably but search longer.
+	EfSearch int `json:"efSearch"`
+
+	// Expand adds seeds linked to the hits, scored hit score × expandDecay
+	// (default 0.5) × link weight × their confidence. expandTypes limits the
+	// links followed, expandLimit the neighbours added (default limit).
+	Expand      bool     `json:"expand"`
+	ExpandDecay *float32 `json:"expandDecay"`
+	ExpandTypes []string `json:"expandTypes"`
+	ExpandLimit int      `json:"expandLimit"`
 }
 
 func parseTimeKeyword(keyword string) *time.Time {
@@ -141,6 +256,40 @@ func (h *Handler) HandleQuerySeeds(c *echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "query is required"})
 	}
 
+	if req.Mode == "" {
+		req.Mode = db.SearchModeVector
+	}
+	if req.Mode != db.SearchModeVector && req.Mode != db.SearchModeHybrid {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "mode must be vector or hybrid"})
+	}
+	if (req.SemanticWeight != nil && *req.SemanticWeight < 0) || (req.LexicalWeight != nil && *req.LexicalWeight < 0) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "weights must not be negative"})
+	}
+	if err := checkMetadata(req.Metadata); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if req.Lambda != nil && (*req.Lambda < 0 || *req.Lambda > 1) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "lambda must be between 0 and 1"})
+	}
+	if req.Candidates < 0 || req.Candidates > maxMMRCandidates {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "candidates must be between 0 and 500"})
+	}
+	if req.EfSearch < 0 || req.EfSearch > 1000 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "efSearch must be between 1 and 1000"})
+	}
+	if req.ExpandDecay != nil && (*req.ExpandDecay <= 0 || *req.ExpandDecay > 1) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "expandDecay must be greater than 0 and at most 1"})
+	}
+	for _, t := range req.ExpandTypes {
+		if !db.ValidLinkType(t) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "expandTypes: " + linkTypeError})
+		}
+	}
+	sessionID, excludeSessionID, status, err := h.sessionIDs(c, "", req.SessionID, req.ExcludeSessionID)
+	if err != nil {
+		return c.JSON(status, map[string]string{"error": err.Error()})
+	}
+
 	emb, err := h.emb.Embed(req.Query)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to embed query"})
@@ -153,10 +302,45 @@ func (h *Handler) HandleQuerySeeds(c *echo.Context) error {
 		req.Threshold = 0.5
 	}
 
-	since := parseTimeKeyword(req.Since)
-	until := parseTimeKeyword(req.Until)
+	opts := db.SeedSearchOptions{
+		Namespace:      Namespace(c),
+		Limit:          req.Limit,
+		Threshold:      req.Threshold,
+		Since:          parseTimeKeyword(req.Since),
+		Until:          parseTimeKeyword(req.Until),
+		Mode:           req.Mode,
+		QueryText:      req.Query,
+		SemanticWeight: 1.0,
+		LexicalWeight:  1.0,
+		EfSearch:       req.EfSearch,
+		Reinforcement:  h.cfg.Reinforcement,
+		SeedFilters: db.SeedFilters{
+			TagsAny:  normalizeTags(req.TagsAny),
+			TagsAll:  normalizeTags(req.TagsAll),
+			Types:    normalizeTags(req.Types),
+			Metadata: req.Metadata,
+
+			SessionID:        sessionID,
+			ExcludeSessionID: excludeSessionID,
+		},
+	}
+	if req.SemanticWeight != nil {
+		opts.SemanticWeight = *req.SemanticWeight
+	}
+	if req.LexicalWeight != nil {
+		opts.LexicalWeight = *req.LexicalWeight
+	}
+	if req.Lambda != nil {
+		opts.MMR = &db.MMROptions{Lambda: *req.Lambda, Candidates: req.Candidates}
+	}
+	if req.Expand {
+		opts.Expand = &db.GraphExpansion{Types: req.ExpandTypes, Limit: req.ExpandLimit}
+		if req.ExpandDecay != nil {
+			opts.Expand.Decay = *req.ExpandDecay
+		}
+	}
 
-	results, err := h.db.SearchSeeds(c.Request().Context(), emb, req.Limit, req.Threshold, since, until)
+	results, err := h.db.SearchSeeds(c.Request().Context(), emb, opts)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
@@ -171,7 +355,7 @@ func (h *Handler) HandleQuerySeeds(c *echo.Context) error {
 func (h *Handler) HandleDeleteSeed(c *echo.Context) error {
 	id := c.Param("id")
 
-	if err := h.db.DeleteSeed(c.Request().Context(), id); err != nil {
+	if err := h.db.DeleteSeed(c.Request().Context(), Namespace(c), id, Actor(c)); err != nil {
 		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
 	}
 
@@ -182,6 +366,11 @@ type UpdateSeedRequest struct {
 	Content string `json:"content"`
 	Title   string `json:"title"`
 	Type    string `json:"type"`
+
+	// Tags and Metadata replace the stored values; omit them to keep those.
+	// An explicit null or {} metadata clears it.
+	Tags     []string        `json:"tags"`
+	Metadata json.RawMessage `json:"metadata"`
 }
 
 func (h *Handler) HandleUpdateSeed(c *echo.Context) error {
@@ -195,23 +384,33 @@ func (h *Handler) HandleUpdateSeed(c *echo.Context) error {
 		req.Type = c.FormValue("type")
 	}
 
-	if req.Content == "" || req.Title == "" || req.Type == "" {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "content, title, and type are required"})
+	if err := checkSeedFields(req.Content, req.Title, req.Type); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if isJSONNull(req.Metadata) {
+		req.Metadata = json.RawMessage(`{}`)
+	}
+	if err := checkMetadata(req.Metadata); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 	}
 
-	emb, err := h.emb.Embed(req.Content)
-	if err != nil {
+	emb, ok := h.embedSeed(req.Content)
+	if !ok {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to embed content"})
 	}
 
 	seed := &db.Seed{
-		ID:      id,
-		Content: req.Content,
-		Title:   req.Title,
-		Type:    req.Type,
+		ID:             id,
+		Namespace:      Namespace(c),
+		Content:        req.Content,
+		Title:          req.Title,
+		Type:           req.Type,
+		Tags:           normalizeTags(req.Tags),
+		Metadata:       req.Metadata,
+		EmbeddingModel: emb.model,
 	}
 
-	if err := h.db.UpdateSeed(c.Request().Context(), seed, emb); err != nil {
+	if err := h.db.UpdateSeed(c.Request().Context(), seed, emb.embedding, emb.chunks, Actor(c)); err != nil {
 		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
 	}
 
@@ -234,7 +433,7 @@ func (h *Handler) HandleSetConfidence(c *echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "confidence must be between 0.0 and 1.0"})
 	}
 
-	if err := h.db.SetSeedConfidence(c.Request().Context(), id, req.Confidence); err != nil {
+	if err := h.db.SetSeedConfidence(c.Request().Context(), Namespace(c), id, req.Confidence, Actor(c)); err != nil {
 		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
 	}
 
@@ -253,7 +452,7 @@ func (h *Handler) HandleSetProtected(c *echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid json"})
 	}
 
-	if err := h.db.SetSeedProtected(c.Request().Context(), id, req.Protected); err != nil {
+	if err := h.db.SetSeedProtected(c.Request().Context(), Namespace(c), id, req.Protected, Actor(c)); err != nil {
 		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
 	}
 
@@ -265,6 +464,10 @@ type CreateAgentContextRequest struct {
 	Type     string          `json:"type"`
 	Metadata json.RawMessage `json:"metadata"`
 	Summary  string          `json:"summary"`
+	// Protected contexts cannot be deleted.
+	Protected bool `json:"protected"`
+	// SessionID attaches the context to a session (ID or external ID).
+	SessionID string `json:"sessionId"`
 }
 
 func (h *Handler) HandleCreateAgentContext(c *echo.Context) error {
@@ -276,28 +479,30 @@ func (h *Handler) HandleCreateAgentContext(c *echo.Context) error {
 	if req.AgentID == "" || req.Type == "" {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "agentId and type are required"})
 	}
+	sessionID, _, status, err := h.sessionIDs(c, req.AgentID, req.SessionID, "")
+	if err != nil {
+		return c.JSON(status, map[string]string{"error": err.Error()})
+	}
 
-	textToEmbed := req.Summary
-	if textToEmbed == "" {
-		if len(req.Metadata) > 0 {
-			textToEmbed = string(req.Metadata)
-		} else {
-			textToEmbed = req.Type
-		}
+	ac := &db.AgentContext{
+		Namespace: Namespace(c),
+		AgentID:   req.AgentID,
+		Type:      req.Type,
+		Metadata:  req.Metadata,
+		Summary:   req.Summary,
+		Protected: req.Protected,
+		SessionID: sessionID,
+	}
+	if err := h.normalizeContextMetadata(c.Request().Context(), ac); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 	}
 
-	emb, err := h.emb.Embed(textToEmbed)
+	emb, model, err := embeddings.EmbedWithModel(h.emb, agentContextText(ac))
+	ac.EmbeddingModel = model
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to embed agent context"})
 	}
 
-	ac := &db.AgentContext{
-		AgentID:  req.AgentID,
-		Type:     req.Type,
-		Metadata: req.Metadata,
-		Summary:  req.Summary,
-	}
-
 	if err := h.db.InsertAgentContext(c.Request().Context(), ac, emb); err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
@@ -306,24 +511,35 @@ func (h *Handler) HandleCreateAgentContext(c *echo.Context) error {
 }
 
 func (h *Handler) HandleGetAgentContexts(c *echo.Context) error {
-	agentID := c.QueryParam("agentId")
+	opts := db.AgentContextListOptions{
+		Namespace:   Namespace(c),
+		AgentID:     c.QueryParam("agentId"),
+		Type:        c.QueryParam("type"),
+		PageOptions: PageOptions(c),
+	}
+	sessionID, _, status, err := h.sessionIDs(c, opts.AgentID, c.QueryParam("sessionId"), "")
+	if err != nil {
+		return c.JSON(status, map[string]string{"error": err.Error()})
+	}
+	opts.SessionID = sessionID
 
-	results, err := h.db.GetAgentContexts(c.Request().Context(), agentID)
+	results, next, err := h.db.GetAgentContexts(c.Request().Context(), opts)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return listError(c, err)
 	}
 
 	if results == nil {
 		results = []db.AgentContext{}
 	}
 
+	setNextLink(c, next)
 	return c.JSON(http.StatusOK, results)
 }
 
 func (h *Handler) HandleGetAgentContext(c *echo.Context) error {
 	id := c.Param("id")
 
-	ac, err := h.db.GetAgentContextByID(c.Request().Context(), id)
+	ac, err := h.db.GetAgentContextByID(c.Request().Context(), Namespace(c), id)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
@@ -334,3 +550,67 @@ func (h *Handler) HandleGetAgentContext(c *echo.Context) error {
 
 	return c.JSON(http.StatusOK, ac)
 }
+
+type QueryAgentContextsRequest struct {
+	Query     string  `json:"query"`
+	AgentID   string  `json:"agentId"`
+	Type      string  `json:"type"`
+	Limit     int     `json:"limit"`
+	Threshold float32 `json:"threshold"`
+	Since     string  `json:"since"`
+	Until     string  `json:"until"`
+
+	// SessionID and ExcludeSessionID work as for /seeds/query.
+	SessionID        string `json:"sessionId"`
+	ExcludeSessionID string `json:"excludeSessionId"`
+}
+
+func (h *Handler) HandleQueryAgentContexts(c *echo.Context) error {
+	var req QueryAgentContextsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid json"})
+	}
+
+	if req.Query == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "query is required"})
+	}
+	sessionID, excludeSessionID, status, err := h.sessionIDs(c, req.AgentID, req.SessionID, req.ExcludeSessionID)
+	if err != nil {
+		return c.JSON(status, map[string]string{"error": err.Error()})
+	}
+
+	emb, err := h.emb.Embed(req.Query)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to embed query"})
+	}
+
+	if req.Limit <= 0 {
+		req.Limit = 10
+	}
+	if req.Threshold < 0 {
+		req.Threshold = 0.5
+	}
+
+	opts := db.AgentContextSearchOptions{
+		Namespace:        Namespace(c),
+		AgentID:          req.AgentID,
+		Type:             req.Type,
+		Limit:            req.Limit,
+		Threshold:        req.Threshold,
+		Since:            parseTimeKeyword(req.Since),
+		Until:            parseTimeKeyword(req.Until),
+		SessionID:        sessionID,
+		ExcludeSessionID: excludeSessionID,
+	}
+
+	results, err := h.db.SearchAgentContexts(c.Request().Context(), emb, opts)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	if results == nil {
+		results = []db.AgentContextSearchResult{}
+	}
+
+	return c.JSON(http.StatusOK, results)
+}
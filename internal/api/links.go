@@ -0,0 +1,132 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v5"
+
+	"jarvis-memory/internal/db"
+)
+
+// linkTypeError lists the valid link types.
+var linkTypeError = "type must be one of " + strings.Join(db.LinkTypes, ", ")
+
+type CreateSeedLinkRequest struct {
+	// TargetID is the seed the link points to. The seed in the path is the
+	// source: it supersedes, contradicts, elaborates, is caused_by or is
+	// related to the target.
+	TargetID string   `json:"targetId"`
+	Type     string   `json:"type"`
+	Weight   *float32 `json:"weight"`
+	Note     string   `json:"note"`
+}
+
+type UpdateSeedLinkRequest struct {
+	Weight *float32 `json:"weight"`
+	Note   *string  `json:"note"`
+}
+
+func validLinkWeight(w *float32) bool {
+	return w == nil || (*w >= 0 && *w <= 1)
+}
+
+// HandleCreateSeedLink links the seed in the path to another seed. A
+// supersedes link lowers the confidence of the target.
+func (h *Handler) HandleCreateSeedLink(c *echo.Context) error {
+	var req CreateSeedLinkRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid json"})
+	}
+
+	if req.TargetID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "targetId is required"})
+	}
+	if !db.ValidLinkType(req.Type) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": linkTypeError})
+	}
+	if !validLinkWeight(req.Weight) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "weight must be between 0 and 1"})
+	}
+	if req.TargetID == c.Param("id") {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "a seed cannot link to itself"})
+	}
+
+	link := &db.SeedLink{
+		Namespace: Namespace(c),
+		FromID:    c.Param("id"),
+		ToID:      req.TargetID,
+		Type:      req.Type,
+		Weight:    1,
+		Note:      req.Note,
+		CreatedBy: Actor(c),
+	}
+	if req.Weight != nil {
+		link.Weight = *req.Weight
+	}
+	if err := h.db.CreateSeedLink(c.Request().Context(), link); err != nil {
+		switch err.Error() {
+		case "seed not found":
+			return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		case "link already exists":
+			return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusCreated, link)
+}
+
+// HandleListSeedLinks lists the links of a seed with a summary of the seed
+// at the other end (?direction=out|in|both, ?type=).
+func (h *Handler) HandleListSeedLinks(c *echo.Context) error {
+	ctx := c.Request().Context()
+	id := c.Param("id")
+
+	if t := c.QueryParam("type"); t != "" && !db.ValidLinkType(t) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": linkTypeError})
+	}
+	seed, err := h.db.GetSeed(ctx, Namespace(c), id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if seed == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "seed not found"})
+	}
+
+	links, err := h.db.ListSeedLinks(ctx, Namespace(c), id, c.QueryParam("direction"), c.QueryParam("type"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if links == nil {
+		links = []db.SeedLink{}
+	}
+	return c.JSON(http.StatusOK, links)
+}
+
+func (h *Handler) HandleUpdateSeedLink(c *echo.Context) error {
+	var req UpdateSeedLinkRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid json"})
+	}
+	if req.Weight == nil && req.Note == nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "weight or note is required"})
+	}
+	if !validLinkWeight(req.Weight) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "weight must be between 0 and 1"})
+	}
+
+	link, err := h.db.UpdateSeedLink(c.Request().Context(), Namespace(c), c.Param("id"), c.Param("linkId"), req.Weight, req.Note)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, link)
+}
+
+// HandleDeleteSeedLink removes a link. Removing the last supersedes link to
+// a seed restores the confidence it had before.
+func (h *Handler) HandleDeleteSeedLink(c *echo.Context) error {
+	if err := h.db.DeleteSeedLink(c.Request().Context(), Namespace(c), c.Param("id"), c.Param("linkId"), Actor(c)); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, map[string]bool{"deleted": true})
+}
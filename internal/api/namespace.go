@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/labstack/echo/v5"
+
+	"jarvis-memory/internal/db"
+)
+
+// NamespaceHeader selects the namespace for routes that are not mounted
+// under /ns/:namespace.
+const NamespaceHeader = "X-Jarvis-Namespace"
+
+const namespaceKey = "namespace"
+
+var namespacePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// NamespaceMiddleware resolves the request namespace from the :namespace path
+// parameter or the X-Jarvis-Namespace header, falling back to the default
+// namespace, and stores it on the context for handlers.
+func NamespaceMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		ns := c.Param("namespace")
+		if ns == "" {
+			ns = c.Request().Header.Get(NamespaceHeader)
+		}
+		if ns == "" {
+			ns = db.DefaultNamespace
+		}
+		if !namespacePattern.MatchString(ns) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid namespace"})
+		}
+		c.Set(namespaceKey, ns)
+		return next(c)
+	}
+}
+
+// Namespace returns the namespace resolved by NamespaceMiddleware.
+func Namespace(c *echo.Context) string {
+	if ns, ok := c.Get(namespaceKey).(string); ok {
+		return ns
+	}
+	return db.DefaultNamespace
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v5"
+
+	"jarvis-memory/internal/db"
+)
+
+// PageOptions reads ?limit=, ?cursor=, ?sort= and ?order=. Limits outside
+// 1..db.MaxPageLimit fall back to the default or the maximum.
+func PageOptions(c *echo.Context) db.PageOptions {
+	p := db.PageOptions{
+		Cursor: c.QueryParam("cursor"),
+		Sort:   c.QueryParam("sort"),
+		Order:  c.QueryParam("order"),
+	}
+	if l, err := strconv.Atoi(c.QueryParam("limit")); err == nil && l > 0 {
+		p.Limit = l
+	}
+	return p
+}
+
+// SeedListOptions reads the filters, sort and page of a seed listing from
+// the query string.
+func SeedListOptions(c *echo.Context) (db.SeedListOptions, error) {
+	filters, err := queryFilters(c)
+	if err != nil {
+		return db.SeedListOptions{}, err
+	}
+	opts := db.SeedListOptions{Namespace: Namespace(c), SeedFilters: filters, PageOptions: PageOptions(c)}
+
+	if v := c.QueryParam("protected"); v != "" {
+		p, err := strconv.ParseBool(v)
+		if err != nil {
+			return db.SeedListOptions{}, fmt.Errorf("protected must be true or false")
+		}
+		opts.Protected = &p
+	}
+	for param, dst := range map[string]**float32{"minConfidence": &opts.MinConfidence, "maxConfidence": &opts.MaxConfidence} {
+		if v := c.QueryParam(param); v != "" {
+			f, err := strconv.ParseFloat(v, 32)
+			if err != nil || f < 0 || f > 1 {
+				return db.SeedListOptions{}, fmt.Errorf("%s must be between 0.0 and 1.0", param)
+			}
+			f32 := float32(f)
+			*dst = &f32
+		}
+	}
+	return opts, nil
+}
+
+// setNextLink points clients at the following page, both as an RFC 8288
+// Link header and as the bare cursor in X-Next-Cursor. The response body
+// stays a plain array.
+func setNextLink(c *echo.Context, next string) {
+	if next == "" {
+		return
+	}
+	u := *c.Request().URL
+	q := u.Query()
+	q.Set("cursor", next)
+	u.RawQuery = q.Encode()
+	h := c.Response().Header()
+	h.Set("Link", fmt.Sprintf(`<%s>; rel="next"`, u.RequestURI()))
+	h.Set("X-Next-Cursor", next)
+}
+
+// listError maps a listing failure to 400 for bad page parameters and 500
+// otherwise.
+func listError(c *echo.Context, err error) error {
+	if errors.Is(err, db.ErrInvalidPage) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+}
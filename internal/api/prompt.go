@@ -0,0 +1,210 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v5"
+
+	"jarvis-memory/internal/db"
+	"jarvis-memory/internal/recall"
+)
+
+// Ways /recall treats the memories of the current session.
+const (
+	sessionPrefer  = "prefer"
+	sessionExclude = "exclude"
+	sessionOnly    = "only"
+)
+
+// sessionBoost is added to the score of current-session hits when they are
+// preferred.
+const sessionBoost = 0.1
+
+// RecallRequest is the body of POST /recall.
+type RecallRequest struct {
+	// Message is the user message to recall memories for.
+	Message string `json:"message"`
+	// AgentID limits the agent contexts searched to one agent.
+	AgentID string `json:"agentId"`
+	// Budget is the token budget of the returned block (default 800).
+	Budget int `json:"budget"`
+	// Format is "markdown" (default) or "text".
+	Format string `json:"format"`
+	// Limit is how many candidates each source contributes (default 10).
+	Limit int `json:"limit"`
+	// Threshold is the minimum similarity of a candidate (default 0.5).
+	Threshold *float32 `json:"threshold"`
+	// Diversity is the MMR lambda between 0 and 1 (default 0.7); 1 turns
+	// diversification off.
+	Diversity *float32 `json:"diversity"`
+	// Contexts includes agent contexts (default true).
+	Contexts *bool `json:"contexts"`
+
+	// SessionID is the current session (ID or external ID). Session is
+	// "prefer" (default) to boost its memories, "exclude" to leave them out
+	// or "only" to search nothing else. An unknown session is ignored.
+	SessionID string `json:"sessionId"`
+	Session   string `json:"session"`
+
+	// Seed filters, as for /seeds/query.
+	TagsAny []string `json:"tagsAny"`
+	Types   []string `json:"types"`
+}
+
+// HandleRecall searches seeds and agent contexts for a message and returns
+// them deduplicated, diversified and trimmed to a token budget, both as a
+// ready-to-inject block and as structured hits. Only the seeds that end up
+// in the block count as recalled and are reinforced.
+func (h *Handler) HandleRecall(c *echo.Context) error {
+	var req RecallRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid json"})
+	}
+
+	if req.Message == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "message is required"})
+	}
+	if req.Format == "" {
+		req.Format = recall.FormatMarkdown
+	}
+	if req.Format != recall.FormatMarkdown && req.Format != recall.FormatText {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "format must be markdown or text"})
+	}
+	if req.Budget < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "budget must not be negative"})
+	}
+	if req.Budget == 0 {
+		req.Budget = 800
+	}
+	if req.Limit <= 0 {
+		req.Limit = 10
+	}
+	threshold := float32(0.5)
+	if req.Threshold != nil {
+		threshold = *req.Threshold
+	}
+	diversity := float32(0.7)
+	if req.Diversity != nil {
+		diversity = *req.Diversity
+	}
+	if diversity < 0 || diversity > 1 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "diversity must be between 0 and 1"})
+	}
+	if req.Session == "" {
+		req.Session = sessionPrefer
+	}
+	if req.Session != sessionPrefer && req.Session != sessionExclude && req.Session != sessionOnly {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "session must be prefer, exclude or only"})
+	}
+
+	opts := recall.Options{Budget: req.Budget, Diversity: diversity, Format: req.Format}
+	session, err := h.resolveSession(c, req.AgentID, req.SessionID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	var sessionID, onlyID, excludeID string
+	if session != nil {
+		sessionID = session.ID
+		switch req.Session {
+		case sessionOnly:
+			onlyID = sessionID
+		case sessionExclude:
+			excludeID = sessionID
+		}
+	} else if req.Session == sessionOnly {
+		return c.JSON(http.StatusOK, recall.Assemble(nil, opts))
+	}
+
+	emb, err := h.emb.Embed(req.Message)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to embed message"})
+	}
+
+	ctx := c.Request().Context()
+	seeds, err := h.db.SearchSeeds(ctx, emb, db.SeedSearchOptions{
+		Namespace:  Namespace(c),
+		Limit:      req.Limit,
+		Threshold:  threshold,
+		Mode:       db.SearchModeVector,
+		QueryText:  req.Message,
+		SkipRecall: true,
+		SeedFilters: db.SeedFilters{
+			TagsAny: normalizeTags(req.TagsAny),
+			Types:   normalizeTags(req.Types),
+
+			SessionID:        onlyID,
+			ExcludeSessionID: excludeID,
+		},
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	hits := make([]recall.Hit, 0, len(seeds))
+	similarity := make(map[string]float32, len(seeds))
+	for _, s := range seeds {
+		similarity[s.ID] = s.Similarity
+		text := s.Content
+		if s.Passage != "" {
+			text = s.Passage
+		}
+		hits = append(hits, recall.Hit{
+			Kind:      recall.KindSeed,
+			ID:        s.ID,
+			Title:     s.Title,
+			Type:      s.Type,
+			SessionID: s.SessionID,
+			Text:      text,
+			Score:     s.Similarity,
+			CreatedAt: s.CreatedAt,
+		})
+	}
+
+	if req.Contexts == nil || *req.Contexts {
+		contexts, err := h.db.SearchAgentContexts(ctx, emb, db.AgentContextSearchOptions{
+			Namespace: Namespace(c),
+			AgentID:   req.AgentID,
+			Limit:     req.Limit,
+			Threshold: threshold,
+
+			SessionID:        onlyID,
+			ExcludeSessionID: excludeID,
+		})
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+		for _, ac := range contexts {
+			hits = append(hits, recall.Hit{
+				Kind:      recall.KindContext,
+				ID:        ac.ID,
+				Type:      ac.Type,
+				AgentID:   ac.AgentID,
+				SessionID: ac.SessionID,
+				Text:      agentContextText(&ac.AgentContext),
+				Score:     ac.Similarity,
+				CreatedAt: ac.CreatedAt,
+			})
+		}
+	}
+
+	if sessionID != "" && req.Session == sessionPrefer {
+		for i := range hits {
+			if hits[i].SessionID == sessionID {
+				hits[i].Score += sessionBoost
+			}
+		}
+	}
+
+	// Only seeds that made it into the block count as recalled
+	res := recall.Assemble(hits, opts)
+	var recalled []db.RecalledSeed
+	for _, hit := range res.Hits {
+		if hit.Kind == recall.KindSeed {
+			recalled = append(recalled, db.RecalledSeed{ID: hit.ID, Score: similarity[hit.ID]})
+		}
+	}
+	if err := h.db.RecallSeeds(ctx, Namespace(c), recalled, req.Message, h.cfg.Reinforcement); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, res)
+}
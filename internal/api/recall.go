@@ -0,0 +1,28 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v5"
+
+	"jarvis-memory/internal/db"
+)
+
+// HandleSeedRecalls returns when a seed was returned by search and how each
+// recall changed its confidence (?limit=, default 50).
+func (h *Handler) HandleSeedRecalls(c *echo.Context) error {
+	limit := 50
+	if l, err := strconv.Atoi(c.QueryParam("limit")); err == nil && l > 0 {
+		limit = l
+	}
+
+	recalls, err := h.db.SeedRecalls(c.Request().Context(), Namespace(c), c.Param("id"), limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if recalls == nil {
+		recalls = []db.SeedRecall{}
+	}
+	return c.JSON(http.StatusOK, recalls)
+}
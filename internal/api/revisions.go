@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v5"
+
+	"jarvis-memory/internal/db"
+)
+
+func (h *Handler) HandleSeedHistory(c *echo.Context) error {
+	ctx := c.Request().Context()
+	id := c.Param("id")
+
+	seed, err := h.db.GetSeed(ctx, Namespace(c), id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if seed == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "seed not found"})
+	}
+
+	revs, err := h.db.SeedHistory(ctx, Namespace(c), id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if revs == nil {
+		revs = []db.SeedRevision{}
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"current": seed, "revisions": revs})
+}
+
+// HandleRevertSeed restores a seed to an earlier revision and re-embeds the
+// restored content with the current model.
+func (h *Handler) HandleRevertSeed(c *echo.Context) error {
+	ctx := c.Request().Context()
+	id := c.Param("id")
+
+	revNum, err := strconv.Atoi(c.Param("rev"))
+	if err != nil || revNum <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "rev must be a positive integer"})
+	}
+
+	rev, err := h.db.GetSeedRevision(ctx, Namespace(c), id, revNum)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if rev == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "revision not found"})
+	}
+
+	emb, ok := h.embedSeed(rev.Content)
+	if !ok {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to embed content"})
+	}
+
+	seed := &db.Seed{ID: id, Namespace: Namespace(c), EmbeddingModel: emb.model}
+	if err := h.db.RevertSeed(ctx, seed, rev, emb.embedding, emb.chunks, Actor(c)); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, seed)
+}
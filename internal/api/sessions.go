@@ -0,0 +1,176 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v5"
+
+	"jarvis-memory/internal/db"
+)
+
+// CreateSessionRequest is the body of POST /sessions.
+type CreateSessionRequest struct {
+	AgentID string `json:"agentId"`
+	// ExternalID is the client's own session ID. Starting a session that
+	// already exists for the agent returns the existing one.
+	ExternalID string `json:"externalId"`
+	Title      string `json:"title"`
+}
+
+// UpdateSessionRequest is the body of PATCH /sessions/:id. Only the fields
+// present change; ended true ends the session, false reopens it.
+type UpdateSessionRequest struct {
+	Title   *string `json:"title"`
+	Summary *string `json:"summary"`
+	Ended   *bool   `json:"ended"`
+}
+
+// resolveSession looks up a session by ID or external ID, the latter within
+// agentID if given. A nil session with nil error means it does not exist.
+func (h *Handler) resolveSession(c *echo.Context, agentID, ref string) (*db.Session, error) {
+	if ref == "" {
+		return nil, nil
+	}
+	return h.db.GetSession(c.Request().Context(), Namespace(c), agentID, ref)
+}
+
+// sessionIDs resolves the sessionId and excludeSessionId of a search
+// request to session IDs. On error, status is the HTTP status to answer with.
+func (h *Handler) sessionIDs(c *echo.Context, agentID, only, exclude string) (onlyID, excludeID string, status int, err error) {
+	for _, f := range []struct {
+		ref string
+		dst *string
+	}{{only, &onlyID}, {exclude, &excludeID}} {
+		s, err := h.resolveSession(c, agentID, f.ref)
+		if err != nil {
+			return "", "", http.StatusInternalServerError, err
+		}
+		if s == nil && f.ref != "" {
+			return "", "", http.StatusNotFound, errors.New("session not found")
+		}
+		if s != nil {
+			*f.dst = s.ID
+		}
+	}
+	return onlyID, excludeID, http.StatusOK, nil
+}
+
+// HandleCreateSession starts a session explicitly. Sessions are also started
+// by the first turn posted with a new sessionId.
+func (h *Handler) HandleCreateSession(c *echo.Context) error {
+	var req CreateSessionRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid json"})
+	}
+	if req.AgentID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "agentId is required"})
+	}
+
+	s := &db.Session{Namespace: Namespace(c), AgentID: req.AgentID, ExternalID: req.ExternalID, Title: req.Title}
+	created, err := h.db.CreateSession(c.Request().Context(), s)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if !created {
+		return c.JSON(http.StatusOK, s)
+	}
+	return c.JSON(http.StatusCreated, s)
+}
+
+// HandleListSessions handles GET /sessions (?agentId=, ?active=, paginated,
+// sort created_at or last_activity).
+func (h *Handler) HandleListSessions(c *echo.Context) error {
+	opts := db.SessionListOptions{
+		Namespace:   Namespace(c),
+		AgentID:     c.QueryParam("agentId"),
+		PageOptions: PageOptions(c),
+	}
+	if v := c.QueryParam("active"); v != "" {
+		active, err := strconv.ParseBool(v)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "active must be true or false"})
+		}
+		opts.Active = &active
+	}
+
+	sessions, next, err := h.db.ListSessions(c.Request().Context(), opts)
+	if err != nil {
+		return listError(c, err)
+	}
+	if sessions == nil {
+		sessions = []db.Session{}
+	}
+	setNextLink(c, next)
+	return c.JSON(http.StatusOK, sessions)
+}
+
+// HandleGetSession handles GET /sessions/:id, where :id is the session ID
+// or its external ID (narrowed with ?agentId=).
+func (h *Handler) HandleGetSession(c *echo.Context) error {
+	s, err := h.resolveSession(c, c.QueryParam("agentId"), c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if s == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "session not found"})
+	}
+	return c.JSON(http.StatusOK, s)
+}
+
+// HandleUpdateSession handles PATCH /sessions/:id.
+func (h *Handler) HandleUpdateSession(c *echo.Context) error {
+	var req UpdateSessionRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid json"})
+	}
+
+	s, err := h.resolveSession(c, c.QueryParam("agentId"), c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if s == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "session not found"})
+	}
+
+	s, err = h.db.UpdateSession(c.Request().Context(), Namespace(c), s.ID, db.SessionUpdate{Title: req.Title, Summary: req.Summary, Ended: req.Ended})
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, s)
+}
+
+// HandleSessionTimeline handles GET /sessions/:id/timeline: the session's
+// turns, seeds and agent contexts in chronological order, starting at
+// ?since= if given. ?limit= defaults to 200; the next page is linked like
+// the other listings.
+func (h *Handler) HandleSessionTimeline(c *echo.Context) error {
+	s, err := h.resolveSession(c, c.QueryParam("agentId"), c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if s == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "session not found"})
+	}
+
+	since := parseTimeKeyword(c.QueryParam("since"))
+	if c.QueryParam("since") != "" && since == nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "since must be a date or time keyword"})
+	}
+	limit := 200
+	if l, err := strconv.Atoi(c.QueryParam("limit")); err == nil && l > 0 {
+		limit = l
+	}
+
+	opts := db.TimelineOptions{Since: since, Limit: limit, Cursor: c.QueryParam("cursor")}
+	entries, next, err := h.db.SessionTimeline(c.Request().Context(), Namespace(c), s.ID, opts)
+	if err != nil {
+		return listError(c, err)
+	}
+	if entries == nil {
+		entries = []db.TimelineEntry{}
+	}
+	setNextLink(c, next)
+	return c.JSON(http.StatusOK, map[string]interface{}{"session": s, "entries": entries})
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v5"
+
+	"jarvis-memory/internal/db"
+)
+
+// splitTags parses a comma-separated tag list as sent in form values and
+// query strings.
+func splitTags(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	return normalizeTags(strings.Split(s, ","))
+}
+
+// normalizeTags trims tags and drops empty and repeated ones. A nil input
+// stays nil so updates can tell "unchanged" from "cleared".
+func normalizeTags(tags []string) []string {
+	if tags == nil {
+		return nil
+	}
+	out := []string{}
+	seen := make(map[string]bool)
+	for _, t := range tags {
+		t = strings.TrimSpace(t)
+		if t != "" && !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// checkSeedFields reports missing seed fields and a type too long for its
+// column.
+func checkSeedFields(content, title, typ string) error {
+	if content == "" || title == "" || typ == "" {
+		return fmt.Errorf("content, title, and type are required")
+	}
+	if utf8.RuneCountInString(typ) > db.MaxSeedTypeLength {
+		return fmt.Errorf("type must be at most %d characters", db.MaxSeedTypeLength)
+	}
+	return nil
+}
+
+// isJSONNull reports whether m is an explicit JSON null, which updates
+// treat as "clear" rather than "not provided".
+func isJSONNull(m json.RawMessage) bool {
+	return string(bytes.TrimSpace(m)) == "null"
+}
+
+// checkMetadata reports metadata that is not a JSON object.
+func checkMetadata(m json.RawMessage) error {
+	if len(m) == 0 {
+		return nil
+	}
+	trimmed := bytes.TrimSpace(m)
+	var obj map[string]json.RawMessage
+	if len(trimmed) == 0 || trimmed[0] != '{' || json.Unmarshal(trimmed, &obj) != nil {
+		return fmt.Errorf("metadata must be a JSON object")
+	}
+	return nil
+}
+
+// queryFilters reads the tag, type and metadata filters of GET /seeds:
+// ?tags=a,b (all of them), ?anyTags=a,b, ?types=a,b (also ?type=) and
+// ?metadata={"k":"v"}.
+func queryFilters(c *echo.Context) (db.SeedFilters, error) {
+	f := db.SeedFilters{
+		TagsAll:  splitTags(c.QueryParam("tags")),
+		TagsAny:  splitTags(c.QueryParam("anyTags")),
+		Types:    splitTags(c.QueryParam("types")),
+		Metadata: json.RawMessage(c.QueryParam("metadata")),
+	}
+	if t := c.QueryParam("type"); t != "" {
+		f.Types = append(f.Types, t)
+	}
+	return f, checkMetadata(f.Metadata)
+}
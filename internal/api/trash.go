@@ -0,0 +1,34 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v5"
+
+	"jarvis-memory/internal/db"
+)
+
+func (h *Handler) HandleListTrash(c *echo.Context) error {
+	limit := 50
+	if l, err := strconv.Atoi(c.QueryParam("limit")); err == nil && l > 0 {
+		limit = l
+	}
+
+	seeds, err := h.db.ListTrash(c.Request().Context(), Namespace(c), limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if seeds == nil {
+		seeds = []db.Seed{}
+	}
+	return c.JSON(http.StatusOK, seeds)
+}
+
+func (h *Handler) HandleRestoreSeed(c *echo.Context) error {
+	seed, err := h.db.RestoreSeed(c.Request().Context(), Namespace(c), c.Param("id"), Actor(c))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, seed)
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+
+	"jarvis-memory/internal/db"
+)
+
+// Scopes a key can hold. Each scope implies the ones below it:
+// admin > write > read.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+	ScopeAdmin = "admin"
+)
+
+// AllNamespaces grants a key access to every namespace.
+const AllNamespaces = "*"
+
+const keyPrefix = "jm_"
+
+var scopeRank = map[string]int{
+	ScopeRead:  1,
+	ScopeWrite: 2,
+	ScopeAdmin: 3,
+}
+
+// GenerateKey creates a new random API key. It returns the plaintext key
+// (shown to the caller once), a short display prefix, and the hash to store.
+func GenerateKey() (key, prefix, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	key = keyPrefix + hex.EncodeToString(buf)
+	return key, key[:len(keyPrefix)+8], HashKey(key), nil
+}
+
+// HashKey returns the hex-encoded SHA-256 of a plaintext key. Keys carry 256
+// bits of entropy, so a fast unsalted hash is sufficient.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func ValidScope(scope string) bool {
+	_, ok := scopeRank[scope]
+	return ok
+}
+
+// Allows reports whether k may perform an operation needing scope inside
+// namespace. An empty namespace marks a global operation (key management,
+// re-embedding, decay, maintenance), which needs a key for all namespaces.
+func Allows(k *db.APIKey, scope, namespace string) bool {
+	return HasScope(k, scope) && HasNamespace(k, namespace)
+}
+
+// HasScope reports whether one of k's scopes implies scope.
+func HasScope(k *db.APIKey, scope string) bool {
+	for _, s := range k.Scopes {
+		if scopeRank[s] >= scopeRank[scope] {
+			return true
+		}
+	}
+	return false
+}
+
+// HasNamespace reports whether k may touch namespace. The empty namespace
+// and AllNamespaces are only covered by a key for all namespaces.
+func HasNamespace(k *db.APIKey, namespace string) bool {
+	if slices.Contains(k.Namespaces, AllNamespaces) {
+		return true
+	}
+	if namespace == "" || namespace == AllNamespaces {
+		return false
+	}
+	return slices.Contains(k.Namespaces, namespace)
+}
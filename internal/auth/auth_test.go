@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"jarvis-memory/internal/db"
+)
+
+func TestAllows(t *testing.T) {
+	reader := &db.APIKey{Scopes: []string{ScopeRead}, Namespaces: []string{"work"}}
+	writer := &db.APIKey{Scopes: []string{ScopeWrite}, Namespaces: []string{"work", "home"}}
+	nsAdmin := &db.APIKey{Scopes: []string{ScopeAdmin}, Namespaces: []string{"work"}}
+	admin := &db.APIKey{Scopes: []string{ScopeAdmin}, Namespaces: []string{AllNamespaces}}
+
+	tests := []struct {
+		name      string
+		key       *db.APIKey
+		scope     string
+		namespace string
+		want      bool
+	}{
+		{"read own namespace", reader, ScopeRead, "work", true},
+		{"read cannot write", reader, ScopeWrite, "work", false},
+		{"read other namespace", reader, ScopeRead, "home", false},
+		{"write implies read", writer, ScopeRead, "home", true},
+		{"write cannot admin", writer, ScopeAdmin, "work", false},
+		{"namespace admin in namespace", nsAdmin, ScopeAdmin, "work", true},
+		{"namespace admin global operation", nsAdmin, ScopeAdmin, "", false},
+		{"namespace admin all namespaces", nsAdmin, ScopeAdmin, AllNamespaces, false},
+		{"admin global operation", admin, ScopeAdmin, "", true},
+		{"admin any namespace", admin, ScopeWrite, "elsewhere", true},
+		{"no scopes", &db.APIKey{Namespaces: []string{AllNamespaces}}, ScopeRead, "work", false},
+		{"unknown scope held", &db.APIKey{Scopes: []string{"owner"}, Namespaces: []string{"work"}}, ScopeRead, "work", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Allows(tt.key, tt.scope, tt.namespace); got != tt.want {
+				t.Errorf("Allows(%q, %q) = %v, want %v", tt.scope, tt.namespace, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateKey(t *testing.T) {
+	key, prefix, hash, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(key, keyPrefix) || !strings.HasPrefix(key, prefix) || len(prefix) != len(keyPrefix)+8 {
+		t.Errorf("key %q has display prefix %q", key, prefix)
+	}
+	if hash != HashKey(key) || len(hash) != 64 {
+		t.Errorf("hash %q does not match HashKey", hash)
+	}
+	if other, _, _, _ := GenerateKey(); other == key {
+		t.Error("two generated keys are equal")
+	}
+}
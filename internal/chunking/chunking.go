@@ -0,0 +1,292 @@
+package chunking
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Options bound chunk sizes in estimated model tokens.
+type Options struct {
+	MaxTokens     int
+	OverlapTokens int
+}
+
+// DefaultOptions keeps chunks comfortably inside GTE-Small's 512-token window.
+var DefaultOptions = Options{MaxTokens: 256, OverlapTokens: 48}
+
+type Chunk struct {
+	Index int
+	Text  string
+}
+
+// unit is an indivisible piece of text (a sentence, a list item, a code
+// block...) together with its estimated token count.
+type unit struct {
+	text    string
+	tokens  int
+	heading bool
+}
+
+var sentenceEnd = regexp.MustCompile(`[.!?]+["')\]]*\s+`)
+
+// EstimateTokens approximates the WordPiece token count of s: one token per
+// word, an extra one for every eight characters of long words, and one per
+// punctuation mark.
+func EstimateTokens(s string) int {
+	tokens := 0
+	wordLen := 0
+	flush := func() {
+		if wordLen > 0 {
+			tokens += 1 + (wordLen-1)/8
+			wordLen = 0
+		}
+	}
+	for _, r := range s {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			wordLen++
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			flush()
+			tokens++
+		}
+	}
+	flush()
+	return tokens
+}
+
+// Split breaks text into overlapping chunks of at most opts.MaxTokens. It
+// prefers to cut at markdown block boundaries (headings, paragraphs, list
+// items, code fences), then at sentence ends, and only splits inside a
+// sentence when a single sentence is too long. Text that already fits is
+// returned as a single chunk.
+func Split(text string, opts Options) []Chunk {
+	if opts.MaxTokens <= 0 {
+		opts = DefaultOptions
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	if EstimateTokens(text) <= opts.MaxTokens {
+		return []Chunk{{Index: 0, Text: text}}
+	}
+
+	var units []unit
+	for _, block := range markdownBlocks(text) {
+		units = append(units, splitBlock(block, opts.MaxTokens)...)
+	}
+
+	var chunks []Chunk
+	var cur []unit
+	curTokens := 0
+	// carried counts the leading units of cur copied from the previous chunk.
+	carried := 0
+
+	flush := func() {
+		if len(cur) == 0 {
+			return
+		}
+		parts := make([]string, len(cur))
+		for i, u := range cur {
+			parts[i] = u.text
+		}
+		chunks = append(chunks, Chunk{Index: len(chunks), Text: strings.Join(parts, "\n")})
+
+		// Carry trailing units into the next chunk as overlap.
+		var carry []unit
+		carryTokens := 0
+		for i := len(cur) - 1; i >= 0; i-- {
+			if carryTokens+cur[i].tokens > opts.OverlapTokens || cur[i].heading {
+				break
+			}
+			carry = append([]unit{cur[i]}, carry...)
+			carryTokens += cur[i].tokens
+		}
+		cur, curTokens, carried = carry, carryTokens, len(carry)
+	}
+	reset := func() {
+		cur, curTokens, carried = nil, 0, 0
+	}
+
+	for _, u := range units {
+		// Headings start a new chunk once the current one is half full, so
+		// sections stay together where possible.
+		if u.heading && curTokens >= opts.MaxTokens/2 {
+			flush()
+			reset()
+		}
+		if curTokens+u.tokens > opts.MaxTokens && curTokens > 0 {
+			flush()
+			// Drop the overlap if it would not leave room for this unit.
+			if curTokens+u.tokens > opts.MaxTokens {
+				reset()
+			}
+		}
+		cur = append(cur, u)
+		curTokens += u.tokens
+	}
+	// Skip a trailing chunk that would only repeat the previous overlap.
+	if len(cur) > carried {
+		flush()
+	}
+	return chunks
+}
+
+type block struct {
+	text    string
+	heading bool
+	code    bool
+}
+
+// markdownBlocks splits text into headings, fenced code blocks, list items
+// and paragraphs.
+func markdownBlocks(text string) []block {
+	var blocks []block
+	var para []string
+	inCode := false
+	var code []string
+
+	flushPara := func() {
+		if len(para) > 0 {
+			blocks = append(blocks, block{text: strings.Join(para, " ")})
+			para = nil
+		}
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "```"):
+			if inCode {
+				code = append(code, line)
+				blocks = append(blocks, block{text: strings.Join(code, "\n"), code: true})
+				code = nil
+				inCode = false
+			} else {
+				flushPara()
+				code = []string{line}
+				inCode = true
+			}
+		case inCode:
+			code = append(code, line)
+		case trimmed == "":
+			flushPara()
+		case strings.HasPrefix(trimmed, "#"):
+			flushPara()
+			blocks = append(blocks, block{text: trimmed, heading: true})
+		case isListItem(trimmed):
+			flushPara()
+			blocks = append(blocks, block{text: trimmed})
+		default:
+			para = append(para, trimmed)
+		}
+	}
+	if inCode {
+		blocks = append(blocks, block{text: strings.Join(code, "\n"), code: true})
+	}
+	flushPara()
+	return blocks
+}
+
+func isListItem(line string) bool {
+	if strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ") || strings.HasPrefix(line, "+ ") || strings.HasPrefix(line, "> ") {
+		return true
+	}
+	i := 0
+	for i < len(line) && line[i] >= '0' && line[i] <= '9' {
+		i++
+	}
+	return i > 0 && i < len(line)-1 && (line[i] == '.' || line[i] == ')') && line[i+1] == ' '
+}
+
+// splitBlock turns a block into units that each fit into max tokens.
+func splitBlock(b block, max int) []unit {
+	tokens := EstimateTokens(b.text)
+	if tokens <= max {
+		return []unit{{text: b.text, tokens: tokens, heading: b.heading}}
+	}
+
+	var pieces []string
+	if b.code {
+		pieces = strings.Split(b.text, "\n")
+	} else {
+		pieces = splitSentences(b.text)
+	}
+
+	var units []unit
+	for _, p := range pieces {
+		t := EstimateTokens(p)
+		if t <= max {
+			units = append(units, unit{text: p, tokens: t})
+			continue
+		}
+		units = append(units, splitWords(p, max)...)
+	}
+	return units
+}
+
+func splitSentences(text string) []string {
+	var out []string
+	last := 0
+	for _, loc := range sentenceEnd.FindAllStringIndex(text, -1) {
+		out = append(out, strings.TrimSpace(text[last:loc[1]]))
+		last = loc[1]
+	}
+	if rest := strings.TrimSpace(text[last:]); rest != "" {
+		out = append(out, rest)
+	}
+	return out
+}
+
+// splitWords is the last resort for a single oversized sentence or line.
+func splitWords(text string, max int) []unit {
+	var units []unit
+	var cur []string
+	curTokens := 0
+	for _, w := range strings.Fields(text) {
+		t := EstimateTokens(w)
+		if curTokens+t > max && len(cur) > 0 {
+			units = append(units, unit{text: strings.Join(cur, " "), tokens: curTokens})
+			cur, curTokens = nil, 0
+		}
+		if t > max {
+			units = append(units, splitRunes(w, max)...)
+			continue
+		}
+		cur = append(cur, w)
+		curTokens += t
+	}
+	if len(cur) > 0 {
+		units = append(units, unit{text: strings.Join(cur, " "), tokens: curTokens})
+	}
+	return units
+}
+
+// splitRunes cuts a single word that is too long on its own, such as a URL
+// or base64 data, into pieces of at most max tokens. It counts tokens as
+// EstimateTokens does, rune by rune.
+func splitRunes(word string, max int) []unit {
+	var units []unit
+	start, tokens, run := 0, 0, 0
+	for i, r := range word {
+		alnum := unicode.IsLetter(r) || unicode.IsDigit(r)
+		t := 1
+		if alnum && run%8 != 0 {
+			t = 0
+		}
+		if tokens+t > max && i > start {
+			units = append(units, unit{text: word[start:i], tokens: tokens})
+			start, tokens, run, t = i, 0, 0, 1
+		}
+		tokens += t
+		if alnum {
+			run++
+		} else {
+			run = 0
+		}
+	}
+	return append(units, unit{text: word[start:], tokens: tokens})
+}
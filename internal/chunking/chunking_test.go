@@ -0,0 +1,183 @@
+package chunking
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want int
+	}{
+		{"empty", "", 0},
+		{"whitespace", " \n\t ", 0},
+		{"words", "hello brave world", 3},
+		{"long word", "internationalization", 3},
+		{"punctuation", "a, b.", 4},
+		{"digits", "version 2024", 2},
+		{"unicode", "über straße", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EstimateTokens(tt.in); got != tt.want {
+				t.Errorf("EstimateTokens(%q) = %d, want %d", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	sentence := func(i int) string {
+		return "Sentence number " + strings.Repeat("x", i%5+1) + " talks about memory and recall."
+	}
+	var para []string
+	for i := 0; i < 40; i++ {
+		para = append(para, sentence(i))
+	}
+	long := strings.Join(para, " ")
+
+	tests := []struct {
+		name       string
+		text       string
+		opts       Options
+		wantChunks int // -1 means none, 0 several
+		check      func(t *testing.T, chunks []Chunk)
+	}{
+		{
+			name:       "empty",
+			text:       "  ",
+			opts:       DefaultOptions,
+			wantChunks: -1,
+		},
+		{
+			name:       "fits",
+			text:       "  A short note.  ",
+			opts:       DefaultOptions,
+			wantChunks: 1,
+			check: func(t *testing.T, chunks []Chunk) {
+				if chunks[0].Text != "A short note." {
+					t.Errorf("text = %q, want trimmed input", chunks[0].Text)
+				}
+			},
+		},
+		{
+			name: "sentences with overlap",
+			text: long,
+			opts: Options{MaxTokens: 40, OverlapTokens: 12},
+			check: func(t *testing.T, chunks []Chunk) {
+				for i := 1; i < len(chunks); i++ {
+					prev := strings.Split(chunks[i-1].Text, "\n")
+					first := strings.Split(chunks[i].Text, "\n")[0]
+					if prev[len(prev)-1] != first {
+						t.Errorf("chunk %d does not start with the last sentence of chunk %d", i, i-1)
+					}
+				}
+			},
+		},
+		{
+			name: "headings start chunks",
+			text: "# One\n" + strings.Repeat("alpha beta gamma delta. ", 8) + "\n\n# Two\n" + strings.Repeat("epsilon zeta eta theta. ", 8),
+			opts: Options{MaxTokens: 50, OverlapTokens: 10},
+			check: func(t *testing.T, chunks []Chunk) {
+				if !strings.HasPrefix(chunks[1].Text, "# Two") {
+					t.Errorf("second chunk = %q, want it to start at the heading", chunks[1].Text)
+				}
+			},
+		},
+		{
+			name: "oversized sentence split by words",
+			text: strings.Repeat("word ", 100),
+			opts: Options{MaxTokens: 30},
+		},
+		{
+			name: "oversized word split by runes",
+			text: "see https://example.com/" + strings.Repeat("aB3/", 200) + " for details",
+			opts: Options{MaxTokens: 30},
+			check: func(t *testing.T, chunks []Chunk) {
+				if !strings.HasPrefix(chunks[0].Text, "see") || !strings.HasSuffix(chunks[len(chunks)-1].Text, "for details") {
+					t.Errorf("chunks lost the words around the long one: %q ... %q", chunks[0].Text, chunks[len(chunks)-1].Text)
+				}
+			},
+		},
+		{
+			name: "oversized word of letters only",
+			text: strings.Repeat("Zm9vYmFy", 100),
+			opts: Options{MaxTokens: 20},
+			check: func(t *testing.T, chunks []Chunk) {
+				var joined string
+				for _, c := range chunks {
+					joined += c.Text
+				}
+				if joined != strings.Repeat("Zm9vYmFy", 100) {
+					t.Errorf("pieces do not add up to the word")
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := Split(tt.text, tt.opts)
+			switch {
+			case tt.wantChunks == -1 && chunks != nil:
+				t.Fatalf("got %d chunks, want none", len(chunks))
+			case tt.wantChunks > 0 && len(chunks) != tt.wantChunks:
+				t.Fatalf("got %d chunks, want %d", len(chunks), tt.wantChunks)
+			case tt.wantChunks == 0 && len(chunks) < 2:
+				t.Fatalf("got %d chunks, want several", len(chunks))
+			}
+			for i, c := range chunks {
+				if c.Index != i {
+					t.Errorf("chunk %d has index %d", i, c.Index)
+				}
+				if n := EstimateTokens(c.Text); n > tt.opts.MaxTokens {
+					t.Errorf("chunk %d has %d tokens, max %d", i, n, tt.opts.MaxTokens)
+				}
+			}
+			if tt.check != nil {
+				tt.check(t, chunks)
+			}
+		})
+	}
+}
+
+func TestMarkdownBlocks(t *testing.T) {
+	text := "# Title\nfirst line\nsecond line\n\n- item one\n1. item two\n```go\nx := 1\n\ny := 2\n```\ntail"
+	want := []block{
+		{text: "# Title", heading: true},
+		{text: "first line second line"},
+		{text: "- item one"},
+		{text: "1. item two"},
+		{text: "```go\nx := 1\n\ny := 2\n```", code: true},
+		{text: "tail"},
+	}
+	got := markdownBlocks(text)
+	if len(got) != len(want) {
+		t.Fatalf("got %d blocks %+v, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("block %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestIsListItem(t *testing.T) {
+	tests := map[string]bool{
+		"- dash":     true,
+		"* star":     true,
+		"> quote":    true,
+		"12. number": true,
+		"3) paren":   true,
+		"-nospace":   false,
+		"2024 was":   false,
+		"1.":         false,
+		"plain text": false,
+	}
+	for in, want := range tests {
+		if got := isListItem(in); got != want {
+			t.Errorf("isListItem(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// APIKey is a stored API credential. Only the SHA-256 hash of the key is
+// persisted; Prefix is kept in clear text so keys can be told apart in listings.
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	Namespaces []string   `json:"namespaces"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+func (db *DB) InsertAPIKey(ctx context.Context, k *APIKey, keyHash string) error {
+	query := `
+		INSERT INTO api_keys (name, prefix, key_hash, scopes, namespaces)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	err := db.QueryRowContext(ctx, query, k.Name, k.Prefix, keyHash, pq.Array(k.Scopes), pq.Array(k.Namespaces)).Scan(&k.ID, &k.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert api key: %w", err)
+	}
+	return nil
+}
+
+func (db *DB) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	query := `SELECT id, name, prefix, scopes, namespaces, created_at, last_used_at, revoked_at FROM api_keys ORDER BY created_at DESC`
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	return keys, nil
+}
+
+// AuthenticateAPIKey looks up a non-revoked key by hash and records its use.
+// last_used_at is only written once a minute so busy keys do not rewrite
+// their row on every request. It returns nil if no active key matches.
+func (db *DB) AuthenticateAPIKey(ctx context.Context, keyHash string) (*APIKey, error) {
+	query := `
+		WITH k AS (
+			SELECT id, name, prefix, scopes, namespaces, created_at, last_used_at, revoked_at
+			FROM api_keys
+			WHERE key_hash = $1 AND revoked_at IS NULL
+		),
+		touched AS (
+			UPDATE api_keys
+			SET last_used_at = NOW()
+			WHERE id IN (SELECT id FROM k) AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+			RETURNING id, last_used_at
+		)
+		SELECT k.id, k.name, k.prefix, k.scopes, k.namespaces, k.created_at, COALESCE(t.last_used_at, k.last_used_at), k.revoked_at
+		FROM k LEFT JOIN touched t ON t.id = k.id
+	`
+	k, err := scanAPIKey(db.QueryRowContext(ctx, query, keyHash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to authenticate api key: %w", err)
+	}
+	return k, nil
+}
+
+func (db *DB) RevokeAPIKey(ctx context.Context, id string) error {
+	query := `UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`
+	result, err := db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("api key not found")
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAPIKey(row rowScanner) (*APIKey, error) {
+	var k APIKey
+	var lastUsed, revoked sql.NullTime
+	if err := row.Scan(&k.ID, &k.Name, &k.Prefix, pq.Array(&k.Scopes), pq.Array(&k.Namespaces), &k.CreatedAt, &lastUsed, &revoked); err != nil {
+		return nil, err
+	}
+	if lastUsed.Valid {
+		k.LastUsedAt = &lastUsed.Time
+	}
+	if revoked.Valid {
+		k.RevokedAt = &revoked.Time
+	}
+	return &k, nil
+}
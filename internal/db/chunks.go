@@ -0,0 +1,48 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/pgvector/pgvector-go"
+)
+
+// SeedChunk is one embedded passage of a long seed.
+type SeedChunk struct {
+	Index     int
+	Content   string
+	Embedding []float32
+}
+
+// insertChunks stores the chunks of one seed with a multi-row INSERT.
+func insertChunks(ctx context.Context, tx *sql.Tx, s *Seed, chunks []SeedChunk) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	var values []string
+	var args []interface{}
+	for _, c := range chunks {
+		n := len(args)
+		values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, NULLIF($%d, ''), $%d)", n+1, n+2, n+3, n+4, n+5, n+6, n+7))
+		args = append(args, s.ID, s.Namespace, c.Index, c.Content, pgvector.NewVector(c.Embedding), s.EmbeddingModel, len(c.Embedding))
+	}
+
+	query := `
+		INSERT INTO seed_chunks (seed_id, namespace, chunk_index, content, embedding, embedding_model, embedding_dims)
+		VALUES ` + strings.Join(values, ", ")
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to insert seed chunks: %w", err)
+	}
+	return nil
+}
+
+// replaceChunks swaps a seed's chunks for a new set, e.g. after an update.
+func replaceChunks(ctx context.Context, tx *sql.Tx, s *Seed, chunks []SeedChunk) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM seed_chunks WHERE seed_id = $1`, s.ID); err != nil {
+		return fmt.Errorf("failed to delete seed chunks: %w", err)
+	}
+	return insertChunks(ctx, tx, s, chunks)
+}
@@ -0,0 +1,341 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pgvector/pgvector-go"
+)
+
+// ContextTypeRollup is the type of the rows that summarise expired agent
+// contexts. Rollups are never rolled up again, and only a policy naming this
+// type expires them; "*" policies leave them alone.
+const ContextTypeRollup = "rollup"
+
+// RetentionAny matches every agent ID or type in a ContextRetentionPolicy.
+const RetentionAny = "*"
+
+// contextMaintenanceLockKey serialises context maintenance across replicas.
+const contextMaintenanceLockKey = 727_411_004
+
+// ContextRetentionPolicy decides how long agent contexts of one agent and
+// type are kept. When several policies match a context, the most specific
+// one wins: agent and type, then agent, then type, then "*"/"*".
+type ContextRetentionPolicy struct {
+	AgentID string
+	Type    string
+	// Keep is the age after which contexts expire; 0 keeps them forever.
+	Keep time.Duration
+	// Rollup summarises expired contexts into one rollup row per agent, type
+	// and day before they are deleted.
+	Rollup bool
+}
+
+// Validate reports negative retention periods.
+func (p ContextRetentionPolicy) Validate() error {
+	if p.Keep < 0 {
+		return fmt.Errorf("keep must not be negative")
+	}
+	return nil
+}
+
+func (p ContextRetentionPolicy) specificity() int {
+	n := 0
+	if p.AgentID != RetentionAny {
+		n += 2
+	}
+	if p.Type != RetentionAny {
+		n++
+	}
+	return n
+}
+
+// expiredContextsCTE selects unprotected contexts whose best-matching policy
+// has expired them, as the CTE "expired" with columns id, namespace,
+// agent_id, type, summary, created_at and rollup. It uses $1 to $5.
+// Rollups are dated at the last context they summarise, which is already
+// expired, so they only match policies naming their type; otherwise a "*"
+// policy would delete them in the same pass that creates them.
+const expiredContextsCTE = `
+	policy AS (
+		SELECT * FROM unnest($1::text[], $2::text[], $3::float8[], $4::bool[]) WITH ORDINALITY AS p(agent_id, type, keep_seconds, rollup, rank)
+	),
+	expired AS (
+		SELECT ac.id, ac.namespace, ac.agent_id, ac.type, COALESCE(ac.summary, '') AS summary, ac.created_at,
+		       p.rollup AND ac.type <> 'rollup' AS rollup
+		FROM agent_contexts ac
+		CROSS JOIN LATERAL (
+			SELECT p.keep_seconds, p.rollup FROM policy p
+			WHERE p.agent_id IN ('*', ac.agent_id)
+			  AND (p.type = ac.type OR (p.type = '*' AND ac.type <> 'rollup'))
+			ORDER BY p.rank
+			LIMIT 1
+		) p
+		WHERE NOT ac.protected
+		  AND p.keep_seconds > 0
+		  AND ac.created_at < NOW() - p.keep_seconds * INTERVAL '1 second'
+		  AND ac.created_at < NOW() - $5 * INTERVAL '1 second'
+	)`
+
+// retentionArgs orders policies by specificity and returns the parameters of
+// expiredContextsCTE, or ok=false when no policy ever expires anything.
+func retentionArgs(policies []ContextRetentionPolicy) (args []interface{}, ok bool) {
+	sorted := append([]ContextRetentionPolicy(nil), policies...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].specificity() > sorted[j].specificity() })
+
+	agents, types := []string{}, []string{}
+	keeps, rollups := []float64{}, []bool{}
+	var minKeep float64
+	for _, p := range sorted {
+		agents = append(agents, p.AgentID)
+		types = append(types, p.Type)
+		keeps = append(keeps, p.Keep.Seconds())
+		rollups = append(rollups, p.Rollup)
+		if p.Keep > 0 && (minKeep == 0 || p.Keep.Seconds() < minKeep) {
+			minKeep = p.Keep.Seconds()
+		}
+	}
+	if minKeep == 0 {
+		return nil, false
+	}
+	return []interface{}{pq.Array(agents), pq.Array(types), pq.Array(keeps), pq.Array(rollups), minKeep}, true
+}
+
+// ContextRollupGroup is a day of expired contexts of one agent and type that
+// policy says to roll up.
+type ContextRollupGroup struct {
+	Namespace string
+	AgentID   string
+	Type      string
+	Day       time.Time
+	IDs       []string
+	Summaries []string
+	From      time.Time
+	To        time.Time
+}
+
+// ExpiredContextGroups returns up to limit groups of expired contexts to be
+// rolled up, oldest day first.
+func (db *DB) ExpiredContextGroups(ctx context.Context, policies []ContextRetentionPolicy, limit int) ([]ContextRollupGroup, error) {
+	args, ok := retentionArgs(policies)
+	if !ok {
+		return nil, nil
+	}
+	args = append(args, limit)
+
+	query := `
+		WITH ` + expiredContextsCTE + `
+		SELECT namespace, agent_id, type, date_trunc('day', created_at, 'UTC') AS day,
+		       array_agg(id::text ORDER BY created_at), array_agg(summary ORDER BY created_at),
+		       MIN(created_at), MAX(created_at)
+		FROM expired
+		WHERE rollup
+		GROUP BY namespace, agent_id, type, day
+		ORDER BY day
+		LIMIT $6`
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find expired agent contexts: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []ContextRollupGroup
+	for rows.Next() {
+		var g ContextRollupGroup
+		if err := rows.Scan(&g.Namespace, &g.AgentID, &g.Type, &g.Day, pq.Array(&g.IDs), pq.Array(&g.Summaries), &g.From, &g.To); err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+// RollupAgentContexts stores summary as one rollup context for g, dated at
+// the group's last context, and deletes the contexts it replaces. It returns
+// how many were deleted; contexts protected in the meantime are kept.
+func (db *DB) RollupAgentContexts(ctx context.Context, g ContextRollupGroup, summary string, embedding []float32, model string) (int64, error) {
+	meta, err := json.Marshal(map[string]interface{}{
+		"rollup": map[string]interface{}{
+			"type":  g.Type,
+			"day":   g.Day.Format("2006-01-02"),
+			"count": len(g.IDs),
+			"from":  g.From,
+			"to":    g.To,
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO agent_contexts (namespace, agent_id, type, metadata, summary, embedding, embedding_model, embedding_dims, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9)`,
+		g.Namespace, g.AgentID, ContextTypeRollup, meta, summary, pgvector.NewVector(embedding), model, len(embedding), g.To)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert rollup: %w", err)
+	}
+	result, err := tx.ExecContext(ctx, `DELETE FROM agent_contexts WHERE id = ANY($1::uuid[]) AND namespace = $2 AND NOT protected`, pq.Array(g.IDs), g.Namespace)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete rolled up agent contexts: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n, tx.Commit()
+}
+
+// DeleteExpiredAgentContexts deletes expired contexts whose policy does not
+// roll them up.
+func (db *DB) DeleteExpiredAgentContexts(ctx context.Context, policies []ContextRetentionPolicy) (int64, error) {
+	args, ok := retentionArgs(policies)
+	if !ok {
+		return 0, nil
+	}
+	query := `
+		WITH ` + expiredContextsCTE + `
+		DELETE FROM agent_contexts ac
+		USING expired e
+		WHERE ac.id = e.id AND ac.created_at = e.created_at AND NOT e.rollup`
+	result, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired agent contexts: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// agentContextPartition names the partition holding the month of t.
+func agentContextPartition(t time.Time) string {
+	return "agent_contexts_" + t.UTC().Format("2006_01")
+}
+
+// EnsureAgentContextPartitions creates the monthly partitions from the month
+// of from through months months later. Rows that already landed in the
+// default partition for such a month are moved into the new partition.
+// It returns the partitions created.
+func (db *DB) EnsureAgentContextPartitions(ctx context.Context, from time.Time, months int) ([]string, error) {
+	start := time.Date(from.UTC().Year(), from.UTC().Month(), 1, 0, 0, 0, 0, time.UTC)
+	var created []string
+	for i := 0; i <= months; i++ {
+		lo, hi := start.AddDate(0, i, 0), start.AddDate(0, i+1, 0)
+		name := agentContextPartition(lo)
+
+		var exists sql.NullString
+		if err := db.QueryRowContext(ctx, `SELECT to_regclass($1)::text`, name).Scan(&exists); err != nil {
+			return created, fmt.Errorf("failed to look up partition %s: %w", name, err)
+		}
+		if exists.Valid {
+			continue
+		}
+		if err := db.createAgentContextPartition(ctx, name, lo, hi); err != nil {
+			return created, err
+		}
+		created = append(created, name)
+	}
+	return created, nil
+}
+
+func (db *DB) createAgentContextPartition(ctx context.Context, name string, lo, hi time.Time) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	table := pq.QuoteIdentifier(name)
+	bounds := fmt.Sprintf("FROM ('%s') TO ('%s')", lo.Format(time.RFC3339), hi.Format(time.RFC3339))
+	for _, stmt := range []string{
+		`CREATE TABLE ` + table + ` (LIKE agent_contexts INCLUDING DEFAULTS)`,
+		`WITH moved AS (
+			DELETE FROM agent_contexts_default WHERE created_at >= $1 AND created_at < $2 RETURNING *
+		)
+		INSERT INTO ` + table + ` SELECT * FROM moved`,
+		`ALTER TABLE agent_contexts ATTACH PARTITION ` + table + ` FOR VALUES ` + bounds,
+	} {
+		var args []interface{}
+		if strings.Contains(stmt, "$1") {
+			args = []interface{}{lo, hi}
+		}
+		if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+			return fmt.Errorf("failed to create partition %s: %w", name, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// DropEmptyAgentContextPartitions drops monthly partitions that ended before
+// before and hold no rows. It returns the partitions dropped.
+func (db *DB) DropEmptyAgentContextPartitions(ctx context.Context, before time.Time) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT c.relname
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = 'agent_contexts'::regclass AND c.relname ~ '^agent_contexts_[0-9]{4}_[0-9]{2}$'
+		ORDER BY c.relname`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions: %w", err)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var dropped []string
+	for _, name := range names {
+		month, err := time.Parse("2006_01", strings.TrimPrefix(name, "agent_contexts_"))
+		if err != nil || month.AddDate(0, 1, 0).After(before) {
+			continue
+		}
+		table := pq.QuoteIdentifier(name)
+		var empty bool
+		if err := db.QueryRowContext(ctx, `SELECT NOT EXISTS (SELECT 1 FROM `+table+`)`).Scan(&empty); err != nil {
+			return dropped, fmt.Errorf("failed to inspect partition %s: %w", name, err)
+		}
+		if !empty {
+			continue
+		}
+		if _, err := db.ExecContext(ctx, `DROP TABLE `+table); err != nil {
+			return dropped, fmt.Errorf("failed to drop partition %s: %w", name, err)
+		}
+		dropped = append(dropped, name)
+	}
+	return dropped, nil
+}
+
+// WithContextMaintenanceLock runs fn while holding a session advisory lock,
+// so only one replica maintains agent contexts at a time. It reports
+// ok=false without running fn when another replica holds the lock.
+func (db *DB) WithContextMaintenanceLock(ctx context.Context, fn func() error) (ok bool, err error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, contextMaintenanceLockKey).Scan(&ok); err != nil {
+		return false, fmt.Errorf("failed to take maintenance lock: %w", err)
+	}
+	if !ok {
+		return false, nil
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, contextMaintenanceLockKey)
+	return true, fn()
+}
@@ -0,0 +1,139 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pgvector/pgvector-go"
+)
+
+// JSONBText returns raw as Postgres renders it after a round trip through
+// jsonb, the form the re-embed job reads metadata back in.
+func (db *DB) JSONBText(ctx context.Context, raw json.RawMessage) (json.RawMessage, error) {
+	var text string
+	if err := db.QueryRowContext(ctx, `SELECT $1::jsonb::text`, string(raw)).Scan(&text); err != nil {
+		return nil, fmt.Errorf("invalid metadata: %w", err)
+	}
+	return json.RawMessage(text), nil
+}
+
+// UpdateAgentContext rewrites the agent ID, type, metadata and summary of
+// ac. A nil embedding keeps the stored vector; pass a fresh one whenever the
+// embedded text changed. ac is filled with the stored context.
+func (db *DB) UpdateAgentContext(ctx context.Context, ac *AgentContext, embedding []float32) error {
+	var meta interface{} = ac.Metadata
+	if len(ac.Metadata) == 0 {
+		meta = nil
+	}
+
+	query := `
+		UPDATE agent_contexts
+		SET agent_id = $3, type = $4, metadata = $5, summary = $6, updated_at = NOW()
+		WHERE id = $1 AND namespace = $2
+		RETURNING ` + agentContextColumns
+	args := []interface{}{ac.ID, ac.Namespace, ac.AgentID, ac.Type, meta, ac.Summary}
+	if embedding != nil {
+		query = `
+			UPDATE agent_contexts
+			SET agent_id = $3, type = $4, metadata = $5, summary = $6, updated_at = NOW(),
+			    embedding = $7, embedding_model = NULLIF($8, ''), embedding_dims = $9
+			WHERE id = $1 AND namespace = $2
+			RETURNING ` + agentContextColumns
+		args = append(args, pgvector.NewVector(embedding), ac.EmbeddingModel, len(embedding))
+	}
+
+	err := scanAgentContext(db.QueryRowContext(ctx, query, args...), ac)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("agent context not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update agent context: %w", err)
+	}
+	return nil
+}
+
+// SetAgentContextProtected marks an agent context as protected from deletion.
+func (db *DB) SetAgentContextProtected(ctx context.Context, namespace, id string, protected bool) error {
+	result, err := db.ExecContext(ctx, `UPDATE agent_contexts SET protected = $3, updated_at = NOW() WHERE id = $1 AND namespace = $2`, id, namespace, protected)
+	if err != nil {
+		return fmt.Errorf("failed to set agent context protection: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("agent context not found")
+	}
+	return nil
+}
+
+// DeleteAgentContext removes an agent context unless it is protected.
+func (db *DB) DeleteAgentContext(ctx context.Context, namespace, id string) error {
+	var protected bool
+	err := db.QueryRowContext(ctx, `SELECT protected FROM agent_contexts WHERE id = $1 AND namespace = $2`, id, namespace).Scan(&protected)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("agent context not found")
+		}
+		return fmt.Errorf("failed to check agent context: %w", err)
+	}
+	if protected {
+		return fmt.Errorf("agent context is protected and cannot be deleted")
+	}
+
+	result, err := db.ExecContext(ctx, `DELETE FROM agent_contexts WHERE id = $1 AND namespace = $2 AND NOT protected`, id, namespace)
+	if err != nil {
+		return fmt.Errorf("failed to delete agent context: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("agent context not found")
+	}
+	return nil
+}
+
+// AgentContextDeleteOptions selects the contexts of one agent, optionally
+// within [Since, Until] by creation time.
+type AgentContextDeleteOptions struct {
+	Namespace string
+	AgentID   string
+	Since     *time.Time
+	Until     *time.Time
+}
+
+// AgentContextDeleteResult counts what a bulk delete removed and how many
+// matching contexts were kept because they are protected.
+type AgentContextDeleteResult struct {
+	Deleted   int64 `json:"deleted"`
+	Protected int64 `json:"protected"`
+}
+
+// DeleteAgentContexts removes all unprotected contexts matching opts.
+func (db *DB) DeleteAgentContexts(ctx context.Context, opts AgentContextDeleteOptions) (AgentContextDeleteResult, error) {
+	if opts.AgentID == "" {
+		return AgentContextDeleteResult{}, fmt.Errorf("agent ID is required")
+	}
+
+	filter := seedFilter{conds: []string{"namespace = $1", "agent_id = $2"}}
+	args := []interface{}{opts.Namespace, opts.AgentID}
+	if opts.Since != nil {
+		args = append(args, *opts.Since)
+		filter.conds = append(filter.conds, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if opts.Until != nil {
+		args = append(args, *opts.Until)
+		filter.conds = append(filter.conds, fmt.Sprintf("created_at <= $%d", len(args)))
+	}
+
+	query := `
+		WITH deleted AS (
+			DELETE FROM agent_contexts WHERE NOT protected` + filter.sql("") + `
+			RETURNING id
+		)
+		SELECT (SELECT COUNT(*) FROM deleted),
+		       (SELECT COUNT(*) FROM agent_contexts WHERE protected` + filter.sql("") + `)`
+	var res AgentContextDeleteResult
+	if err := db.QueryRowContext(ctx, query, args...).Scan(&res.Deleted, &res.Protected); err != nil {
+		return AgentContextDeleteResult{}, fmt.Errorf("failed to delete agent contexts: %w", err)
+	}
+	return res, nil
+}
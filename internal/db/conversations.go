@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ConversationTurn is one user message and the assistant's response, as
+// captured after an AI turn, together with the seed and agent context
+// derived from it.
+type ConversationTurn struct {
+	ID                string          `json:"id"`
+	Namespace         string          `json:"namespace"`
+	AgentID           string          `json:"agentId"`
+	SessionID         string          `json:"session_id,omitempty"`
+	UserMessage       string          `json:"userMessage"`
+	AssistantResponse string          `json:"assistantResponse"`
+	ToolCalls         json.RawMessage `json:"toolCalls"`
+	SeedID            *string         `json:"seed_id"`
+	AgentContextID    *string         `json:"agent_context_id"`
+	CreatedAt         time.Time       `json:"created_at"`
+}
+
+// ConversationTurnInsert is what InsertConversationTurn stores besides the
+// turn itself. Seed goes through dedup like any other seed; AgentContext is
+// skipped when nil. With Session set, the turn, seed and context join that
+// session.
+type ConversationTurnInsert struct {
+	Session *SessionTurn
+
+	Seed          *Seed
+	SeedEmbedding []float32
+	SeedChunks    []SeedChunk
+	Dedup         DedupOptions
+
+	AgentContext          *AgentContext
+	AgentContextEmbedding []float32
+}
+
+// InsertConversationTurn stores a turn with its derived seed and agent
+// context in one transaction. t, in.Seed and in.AgentContext are filled with
+// the stored rows; after a merge or reject the turn points at the existing
+// seed.
+func (db *DB) InsertConversationTurn(ctx context.Context, t *ConversationTurn, in ConversationTurnInsert) (InsertResult, error) {
+	if t.Namespace == "" {
+		t.Namespace = DefaultNamespace
+	}
+	if len(t.ToolCalls) == 0 {
+		t.ToolCalls = json.RawMessage("[]")
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return InsertResult{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if in.Session != nil {
+		if t.SessionID, err = recordSessionTurn(ctx, tx, t.Namespace, t.AgentID, *in.Session); err != nil {
+			return InsertResult{}, err
+		}
+		in.Seed.SessionID = t.SessionID
+		if in.AgentContext != nil {
+			in.AgentContext.SessionID = t.SessionID
+		}
+	}
+
+	if in.Dedup.enabled() {
+		if err := lockDedupScopes(ctx, tx, []*Seed{in.Seed}, in.Dedup); err != nil {
+			return InsertResult{}, err
+		}
+	}
+	res, err := insertSeedTx(ctx, tx, in.Seed, in.SeedEmbedding, in.SeedChunks, in.Dedup)
+	if err != nil {
+		return InsertResult{}, err
+	}
+	t.SeedID = &in.Seed.ID
+
+	if in.AgentContext != nil {
+		if err := insertAgentContext(ctx, tx, in.AgentContext, in.AgentContextEmbedding); err != nil {
+			return InsertResult{}, err
+		}
+		t.AgentContextID = &in.AgentContext.ID
+	}
+
+	query := `
+		INSERT INTO conversation_turns (namespace, agent_id, session_id, user_message, assistant_response, tool_calls, seed_id, agent_context_id)
+		VALUES ($1, $2, NULLIF($3, '')::uuid, $4, $5, $6::jsonb, $7, $8)
+		RETURNING id, created_at
+	`
+	err = tx.QueryRowContext(ctx, query, t.Namespace, t.AgentID, t.SessionID, t.UserMessage, t.AssistantResponse,
+		string(t.ToolCalls), t.SeedID, t.AgentContextID).Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		return InsertResult{}, fmt.Errorf("failed to insert conversation turn: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return InsertResult{}, fmt.Errorf("failed to commit conversation turn: %w", err)
+	}
+	return res, nil
+}
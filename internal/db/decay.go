@@ -0,0 +1,218 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Decay curves.
+const (
+	DecayExponential = "exponential"
+	DecayLinear      = "linear"
+	DecayNone        = "none"
+)
+
+// DecayDefaultType is the policy key that applies to every type without a
+// policy of its own.
+const DecayDefaultType = "*"
+
+// decayLockKey keeps concurrent replicas from running decay at the same time.
+const decayLockKey = 727_411_003
+
+// DecayPolicy describes how the confidence of one seed type fades over time.
+type DecayPolicy struct {
+	Curve string
+	// HalfLife is the time for confidence to halve (exponential curve).
+	HalfLife time.Duration
+	// PerDay is the confidence lost per day (linear curve).
+	PerDay float32
+	// MinAge exempts seeds accessed (or created) more recently than this.
+	MinAge time.Duration
+	// Below restricts decay to seeds with a lower confidence; 0 means all.
+	Below float32
+	// Floor is the confidence decay never goes below.
+	Floor float32
+}
+
+// Validate reports unknown curves and missing curve parameters.
+func (p DecayPolicy) Validate() error {
+	switch p.Curve {
+	case DecayNone:
+	case DecayExponential:
+		if p.HalfLife <= 0 {
+			return fmt.Errorf("exponential decay needs a positive half-life")
+		}
+	case DecayLinear:
+		if p.PerDay <= 0 {
+			return fmt.Errorf("linear decay needs a positive per-day rate")
+		}
+	default:
+		return fmt.Errorf("unknown decay curve %q", p.Curve)
+	}
+	if p.Floor < 0 || p.Floor > 1 || p.Below < 0 || p.Below > 1 {
+		return fmt.Errorf("floor and below must be between 0.0 and 1.0")
+	}
+	return nil
+}
+
+type DecayRun struct {
+	ID             int64            `json:"id"`
+	RanAt          time.Time        `json:"ran_at"`
+	ElapsedSeconds float64          `json:"elapsed_seconds"`
+	RowsAffected   int64            `json:"rows_affected"`
+	Details        map[string]int64 `json:"details"`
+}
+
+// LastDecayRun returns the most recent decay run, or nil if decay never ran.
+func (db *DB) LastDecayRun(ctx context.Context) (*DecayRun, error) {
+	runs, err := db.ListDecayRuns(ctx, 1)
+	if err != nil || len(runs) == 0 {
+		return nil, err
+	}
+	return &runs[0], nil
+}
+
+// ListDecayRuns returns the latest decay runs, newest first.
+func (db *DB) ListDecayRuns(ctx context.Context, limit int) ([]DecayRun, error) {
+	rows, err := db.QueryContext(ctx, `SELECT id, ran_at, elapsed_seconds, rows_affected, details FROM decay_runs ORDER BY ran_at DESC LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list decay runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []DecayRun
+	for rows.Next() {
+		var r DecayRun
+		var details []byte
+		if err := rows.Scan(&r.ID, &r.RanAt, &r.ElapsedSeconds, &r.RowsAffected, &details); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(details, &r.Details); err != nil {
+			return nil, fmt.Errorf("failed to decode decay run details: %w", err)
+		}
+		runs = append(runs, r)
+	}
+	return runs, rows.Err()
+}
+
+// RunDecay applies every policy for the time elapsed since the previous run
+// and records the run. Decay is proportional to elapsed time, so running it
+// twice in a row changes nothing the second time, however often the server
+// restarts. The very first run only records a starting point.
+func (db *DB) RunDecay(ctx context.Context, policies map[string]DecayPolicy) (*DecayRun, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, decayLockKey); err != nil {
+		return nil, fmt.Errorf("failed to acquire decay lock: %w", err)
+	}
+
+	var elapsed sql.NullFloat64
+	err = tx.QueryRowContext(ctx, `SELECT EXTRACT(EPOCH FROM NOW() - MAX(ran_at))::float8 FROM decay_runs`).Scan(&elapsed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read last decay run: %w", err)
+	}
+
+	run := &DecayRun{Details: map[string]int64{}}
+	if elapsed.Valid && elapsed.Float64 > 0 {
+		run.ElapsedSeconds = elapsed.Float64
+
+		explicit := []string{}
+		for typ := range policies {
+			if typ != DecayDefaultType {
+				explicit = append(explicit, typ)
+			}
+		}
+		for typ, p := range policies {
+			n, err := applyDecayPolicy(ctx, tx, typ, explicit, p, run.ElapsedSeconds)
+			if err != nil {
+				return nil, err
+			}
+			if n > 0 {
+				run.Details[typ] = n
+				run.RowsAffected += n
+			}
+		}
+	}
+
+	details, _ := json.Marshal(run.Details)
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO decay_runs (elapsed_seconds, rows_affected, details)
+		VALUES ($1, $2, $3)
+		RETURNING id, ran_at
+	`, run.ElapsedSeconds, run.RowsAffected, details).Scan(&run.ID, &run.RanAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record decay run: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit decay run: %w", err)
+	}
+	return run, nil
+}
+
+// applyDecayPolicy decays the seeds of one type, or for DecayDefaultType all
+// types without their own policy.
+func applyDecayPolicy(ctx context.Context, tx *sql.Tx, typ string, explicit []string, p DecayPolicy, elapsedSeconds float64) (int64, error) {
+	query, args, ok := decayQuery(typ, explicit, p, elapsedSeconds)
+	if !ok {
+		return 0, nil
+	}
+	var n int64
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to decay %s seeds: %w", typ, err)
+	}
+	return n, nil
+}
+
+// decayQuery builds the statement applying p and its arguments, or ok=false
+// when p does not decay. It records coalesced revisions of the decayed seeds
+// and returns their count. A seed only decays for the time it has been idle,
+// counted from its last access (or creation) plus MinAge, and at most for the
+// time since the previous run.
+func decayQuery(typ string, explicit []string, p DecayPolicy, elapsedSeconds float64) (query string, args []interface{}, ok bool) {
+	const idle = `LEAST($5::float8, EXTRACT(EPOCH FROM NOW() - COALESCE(last_accessed, created_at))::float8 - $2::float8)`
+
+	var decayed string
+	var rate float64
+	switch p.Curve {
+	case DecayExponential:
+		decayed = "confidence * power(0.5, " + idle + " / $6::float8)"
+		rate = p.HalfLife.Seconds()
+	case DecayLinear:
+		decayed = "confidence - $6::float8 * " + idle + " / 86400"
+		rate = float64(p.PerDay)
+	default:
+		return "", nil, false
+	}
+
+	typeCond := `type = $4`
+	var typeArg interface{} = typ
+	if typ == DecayDefaultType {
+		typeCond = `NOT (type = ANY($4))`
+		typeArg = pq.Array(explicit)
+	}
+	query = `
+		WITH decayed AS (
+			UPDATE seeds
+			SET confidence = GREATEST($1::real, ` + decayed + `)
+			WHERE NOT protected
+			  AND deleted_at IS NULL
+			  AND confidence > $1
+			  AND COALESCE(last_accessed, created_at) < NOW() - $2 * INTERVAL '1 second'
+			  AND ($3::real = 0 OR confidence < $3)
+			  AND ` + typeCond + `
+			RETURNING id
+		),
+		revised AS (` + coalescedRevisionsQuery("decayed", ChangeDecay) + `
+		)
+		SELECT COUNT(*) FROM decayed`
+	return query, []interface{}{p.Floor, p.MinAge.Seconds(), p.Below, typeArg, elapsedSeconds, rate}, true
+}
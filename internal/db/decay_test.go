@@ -0,0 +1,112 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func TestDecayQuery(t *testing.T) {
+	explicit := []string{"semantic", "procedural"}
+	tests := []struct {
+		name     string
+		typ      string
+		policy   DecayPolicy
+		wantOK   bool
+		wantExpr string
+		wantType string
+		wantRate float64
+	}{
+		{
+			name:     "exponential",
+			typ:      "episodic",
+			policy:   DecayPolicy{Curve: DecayExponential, HalfLife: 30 * 24 * time.Hour, MinAge: time.Hour, Below: 0.8, Floor: 0.1},
+			wantOK:   true,
+			wantExpr: "confidence * power(0.5, ",
+			wantType: "AND type = $4",
+			wantRate: (30 * 24 * time.Hour).Seconds(),
+		},
+		{
+			name:     "linear",
+			typ:      "episodic",
+			policy:   DecayPolicy{Curve: DecayLinear, PerDay: 0.02, Floor: 0.1},
+			wantOK:   true,
+			wantExpr: "confidence - $6::float8 * ",
+			wantType: "AND type = $4",
+			wantRate: float64(float32(0.02)),
+		},
+		{
+			name:     "default policy skips explicit types",
+			typ:      DecayDefaultType,
+			policy:   DecayPolicy{Curve: DecayLinear, PerDay: 0.01},
+			wantOK:   true,
+			wantExpr: "confidence - $6::float8 * ",
+			wantType: "AND NOT (type = ANY($4))",
+			wantRate: float64(float32(0.01)),
+		},
+		{name: "none", typ: "semantic", policy: DecayPolicy{Curve: DecayNone}},
+		{name: "unknown curve", typ: "semantic", policy: DecayPolicy{Curve: "step"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args, ok := decayQuery(tt.typ, explicit, tt.policy, 3600)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				if query != "" || args != nil {
+					t.Errorf("got query %q and args %v for a policy that does not decay", query, args)
+				}
+				return
+			}
+			for _, want := range []string{"UPDATE seeds", "NOT protected", "deleted_at IS NULL", "GREATEST($1::real, " + tt.wantExpr, tt.wantType, "INSERT INTO seed_revisions", "'" + ChangeDecay + "'"} {
+				if !strings.Contains(query, want) {
+					t.Errorf("query lacks %q:\n%s", want, query)
+				}
+			}
+			if len(args) != 6 {
+				t.Fatalf("got %d args, want 6", len(args))
+			}
+			if args[0] != tt.policy.Floor || args[1] != tt.policy.MinAge.Seconds() || args[2] != tt.policy.Below {
+				t.Errorf("floor/min age/below args = %v", args[:3])
+			}
+			if tt.typ == DecayDefaultType {
+				if a, isArray := args[3].(*pq.StringArray); !isArray || strings.Join(*a, ",") != "semantic,procedural" {
+					t.Errorf("type arg = %#v, want the explicit types", args[3])
+				}
+			} else if args[3] != tt.typ {
+				t.Errorf("type arg = %v, want %q", args[3], tt.typ)
+			}
+			if args[4] != 3600.0 || args[5] != tt.wantRate {
+				t.Errorf("elapsed/rate args = %v, want 3600/%v", args[4:], tt.wantRate)
+			}
+		})
+	}
+}
+
+func TestDecayPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy DecayPolicy
+		ok     bool
+	}{
+		{"none", DecayPolicy{Curve: DecayNone}, true},
+		{"exponential", DecayPolicy{Curve: DecayExponential, HalfLife: time.Hour}, true},
+		{"linear", DecayPolicy{Curve: DecayLinear, PerDay: 0.1, Floor: 0.2, Below: 0.9}, true},
+		{"exponential without half-life", DecayPolicy{Curve: DecayExponential}, false},
+		{"linear without rate", DecayPolicy{Curve: DecayLinear}, false},
+		{"unknown curve", DecayPolicy{Curve: "step"}, false},
+		{"empty curve", DecayPolicy{}, false},
+		{"floor above one", DecayPolicy{Curve: DecayNone, Floor: 1.5}, false},
+		{"negative below", DecayPolicy{Curve: DecayNone, Below: -0.1}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.Validate(); (err == nil) != tt.ok {
+				t.Errorf("Validate() = %v, want ok=%v", err, tt.ok)
+			}
+		})
+	}
+}
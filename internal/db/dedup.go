@@ -0,0 +1,251 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"github.com/pgvector/pgvector-go"
+)
+
+// Policies for near-duplicate seeds found on insert.
+const (
+	DedupOff    = "off"
+	DedupReject = "reject"
+	DedupMerge  = "merge"
+	DedupLink   = "link"
+)
+
+// Scopes in which near-duplicates are looked for.
+const (
+	DedupScopeType      = "type"
+	DedupScopeNamespace = "namespace"
+)
+
+// Actions reported by InsertSeed and InsertSeeds.
+const (
+	ActionCreated  = "created"
+	ActionMerged   = "merged"
+	ActionRejected = "rejected"
+	ActionLinked   = "linked"
+)
+
+// mergeConfidenceBump is added to a seed's confidence each time a
+// near-duplicate is merged into it.
+const mergeConfidenceBump = 0.1
+
+// dedupLockClass namespaces the advisory locks taken while checking for
+// duplicates so they cannot collide with the migration lock.
+const dedupLockClass = 727_411_002
+
+type DedupOptions struct {
+	// Policy is one of DedupOff, DedupReject, DedupMerge or DedupLink.
+	Policy string
+	// Threshold is the cosine similarity at or above which an existing seed
+	// counts as a duplicate.
+	Threshold float32
+	// Scope limits the comparison to seeds of the same type or to the whole
+	// namespace.
+	Scope string
+}
+
+func (o DedupOptions) enabled() bool {
+	return o.Policy != "" && o.Policy != DedupOff
+}
+
+// Validate reports unknown policies, scopes and out-of-range thresholds.
+func (o DedupOptions) Validate() error {
+	switch o.Policy {
+	case "", DedupOff, DedupReject, DedupMerge, DedupLink:
+	default:
+		return fmt.Errorf("unknown dedup policy %q", o.Policy)
+	}
+	switch o.Scope {
+	case "", DedupScopeType, DedupScopeNamespace:
+	default:
+		return fmt.Errorf("unknown dedup scope %q", o.Scope)
+	}
+	if o.enabled() && (o.Threshold <= 0 || o.Threshold > 1) {
+		return fmt.Errorf("dedup threshold must be in (0, 1], got %v", o.Threshold)
+	}
+	return nil
+}
+
+// InsertResult describes what an insert did. SeedID is the new seed for
+// ActionCreated and ActionLinked, and the existing seed for ActionMerged and
+// ActionRejected.
+type InsertResult struct {
+	Action     string   `json:"action"`
+	SeedID     string   `json:"seed_id"`
+	Similarity *float32 `json:"similarity,omitempty"`
+	// Err is set by InsertSeeds for a seed that could not be stored.
+	Err error `json:"-"`
+}
+
+// insertSeedTx inserts one seed inside tx, applying the dedup policy first.
+// On merge and reject s is overwritten with the existing seed.
+func insertSeedTx(ctx context.Context, tx *sql.Tx, s *Seed, embedding []float32, chunks []SeedChunk, dedup DedupOptions) (InsertResult, error) {
+	if s.Namespace == "" {
+		s.Namespace = DefaultNamespace
+	}
+	if s.Confidence <= 0 {
+		s.Confidence = 1.0
+	}
+
+	if dedup.enabled() {
+		dupID, similarity, err := findDuplicate(ctx, tx, s, embedding, dedup)
+		if err != nil {
+			return InsertResult{}, err
+		}
+		if dupID != "" {
+			switch dedup.Policy {
+			case DedupReject:
+				if err := loadSeed(ctx, tx, s, dupID); err != nil {
+					return InsertResult{}, err
+				}
+				return InsertResult{Action: ActionRejected, SeedID: dupID, Similarity: &similarity}, nil
+			case DedupMerge:
+				if err := mergeSeed(ctx, tx, s, dupID, similarity); err != nil {
+					return InsertResult{}, err
+				}
+				return InsertResult{Action: ActionMerged, SeedID: dupID, Similarity: &similarity}, nil
+			case DedupLink:
+				s.DuplicateOf = dupID
+			}
+		}
+	}
+
+	query := `
+		INSERT INTO seeds (namespace, content, title, type, embedding, confidence, embedding_model, embedding_dims, duplicate_of, tags, metadata, session_id)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, NULLIF($9, '')::uuid, $10, $11::jsonb, NULLIF($12, '')::uuid)
+		RETURNING id, created_at, last_accessed
+	`
+	err := tx.QueryRowContext(ctx, query, s.Namespace, s.Content, s.Title, s.Type, pgvector.NewVector(embedding), s.Confidence, s.EmbeddingModel, len(embedding), s.DuplicateOf,
+		seedTags(s), seedMetadata(s), s.SessionID).Scan(&s.ID, &s.CreatedAt, &s.LastAccessed)
+	if err != nil {
+		return InsertResult{}, fmt.Errorf("failed to insert seed: %w", err)
+	}
+	if err := insertChunks(ctx, tx, s, chunks); err != nil {
+		return InsertResult{}, err
+	}
+
+	if s.DuplicateOf != "" {
+		return InsertResult{Action: ActionLinked, SeedID: s.ID}, nil
+	}
+	return InsertResult{Action: ActionCreated, SeedID: s.ID}, nil
+}
+
+// dedupLockKey identifies the scope a seed is deduplicated in.
+func dedupLockKey(s *Seed, dedup DedupOptions) string {
+	ns := s.Namespace
+	if ns == "" {
+		ns = DefaultNamespace
+	}
+	if dedup.Scope == DedupScopeNamespace {
+		return ns
+	}
+	return ns + "/" + s.Type
+}
+
+// lockDedupScopes serialises concurrent inserts into the same dedup scopes
+// until tx ends, so two near-identical seeds cannot both slip in. Keys are
+// locked in sorted order to avoid deadlocks between batches.
+func lockDedupScopes(ctx context.Context, tx *sql.Tx, seeds []*Seed, dedup DedupOptions) error {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, s := range seeds {
+		if k := dedupLockKey(s, dedup); !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1, hashtext($2))`, dedupLockClass, k); err != nil {
+			return fmt.Errorf("failed to lock dedup scope: %w", err)
+		}
+	}
+	return nil
+}
+
+// findDuplicate returns the closest seed in s's scope if its similarity
+// reaches the threshold.
+//
+// The HNSW index applies the scope filter after retrieval, so when none of
+// the ef_search nearest neighbours lies in the scope it returns nothing even
+// though the scope has seeds. An empty index result is therefore confirmed
+// with an exact scan of the scope, which the "+ 0" keeps off the index.
+func findDuplicate(ctx context.Context, tx *sql.Tx, s *Seed, embedding []float32, dedup DedupOptions) (string, float32, error) {
+	query := `
+		SELECT id, 1 - (embedding <=> $1) AS similarity
+		FROM seeds
+		WHERE namespace = $2 AND deleted_at IS NULL AND embedding IS NOT NULL`
+	args := []interface{}{pgvector.NewVector(embedding), s.Namespace}
+	if dedup.Scope != DedupScopeNamespace {
+		query += ` AND type = $3`
+		args = append(args, s.Type)
+	}
+
+	var id string
+	var similarity float32
+	err := tx.QueryRowContext(ctx, query+` ORDER BY embedding <=> $1 LIMIT 1`, args...).Scan(&id, &similarity)
+	if err == sql.ErrNoRows {
+		err = tx.QueryRowContext(ctx, query+` ORDER BY (embedding <=> $1) + 0 LIMIT 1`, args...).Scan(&id, &similarity)
+	}
+	if err == sql.ErrNoRows {
+		return "", 0, nil
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to check for duplicates: %w", err)
+	}
+	if similarity < dedup.Threshold {
+		return "", 0, nil
+	}
+	return id, similarity, nil
+}
+
+// mergeSeed folds s into the existing seed id: confidence is bumped,
+// last_accessed refreshed, s's tags added and its metadata keys filled in
+// where the existing seed has none. A provenance entry keeps everything
+// else s carried, including its content, so nothing the caller sent is lost.
+// s is overwritten with the updated seed.
+func mergeSeed(ctx context.Context, tx *sql.Tx, s *Seed, id string, similarity float32) error {
+	if err := snapshotSeed(ctx, tx, s.Namespace, id, ChangeMerge, "dedup"); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE seeds
+		SET confidence = LEAST(1.0, confidence + $3),
+		    last_accessed = NOW(),
+		    tags = ARRAY(SELECT t FROM unnest(tags || $7::text[]) WITH ORDINALITY AS u(t, n) GROUP BY t ORDER BY MIN(n)),
+		    metadata = $8::jsonb || metadata,
+		    provenance = provenance || jsonb_build_array(jsonb_build_object(
+		        'merged_at', NOW(),
+		        'title', $4::text,
+		        'type', $5::text,
+		        'similarity', $6::real,
+		        'content', $9::text,
+		        'tags', to_jsonb($7::text[]),
+		        'metadata', $8::jsonb,
+		        'session_id', NULLIF($10::text, '')
+		    ))
+		WHERE id = $1 AND namespace = $2
+		RETURNING ` + seedColumns
+	row := tx.QueryRowContext(ctx, query, id, s.Namespace, mergeConfidenceBump, s.Title, s.Type, similarity,
+		seedTags(s), seedMetadata(s), s.Content, s.SessionID)
+	if err := scanSeed(row, s); err != nil {
+		return fmt.Errorf("failed to merge seed: %w", err)
+	}
+	return nil
+}
+
+// loadSeed reads the seed id into s.
+func loadSeed(ctx context.Context, tx *sql.Tx, s *Seed, id string) error {
+	row := tx.QueryRowContext(ctx, `SELECT `+seedColumns+` FROM seeds WHERE id = $1 AND namespace = $2`, id, s.Namespace)
+	if err := scanSeed(row, s); err != nil {
+		return fmt.Errorf("failed to load seed: %w", err)
+	}
+	return nil
+}
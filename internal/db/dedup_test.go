@@ -0,0 +1,47 @@
+package db
+
+import "testing"
+
+func TestDedupOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		opts DedupOptions
+		ok   bool
+	}{
+		{"zero value", DedupOptions{}, true},
+		{"off ignores threshold", DedupOptions{Policy: DedupOff}, true},
+		{"reject", DedupOptions{Policy: DedupReject, Threshold: 0.95, Scope: DedupScopeType}, true},
+		{"merge", DedupOptions{Policy: DedupMerge, Threshold: 1, Scope: DedupScopeNamespace}, true},
+		{"link default scope", DedupOptions{Policy: DedupLink, Threshold: 0.9}, true},
+		{"unknown policy", DedupOptions{Policy: "replace", Threshold: 0.9}, false},
+		{"unknown scope", DedupOptions{Policy: DedupReject, Threshold: 0.9, Scope: "agent"}, false},
+		{"zero threshold", DedupOptions{Policy: DedupReject}, false},
+		{"threshold above one", DedupOptions{Policy: DedupMerge, Threshold: 1.1}, false},
+		{"negative threshold", DedupOptions{Policy: DedupLink, Threshold: -0.5}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.opts.Validate(); (err == nil) != tt.ok {
+				t.Errorf("Validate() = %v, want ok=%v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestDedupLockKey(t *testing.T) {
+	a := &Seed{Namespace: "work", Type: "semantic"}
+	b := &Seed{Namespace: "work", Type: "episodic"}
+	c := &Seed{Namespace: "home", Type: "semantic"}
+
+	byType := DedupOptions{Policy: DedupReject, Threshold: 0.9, Scope: DedupScopeType}
+	if dedupLockKey(a, byType) == dedupLockKey(b, byType) {
+		t.Error("type scope shares a lock across types")
+	}
+	byNamespace := DedupOptions{Policy: DedupReject, Threshold: 0.9, Scope: DedupScopeNamespace}
+	if dedupLockKey(a, byNamespace) != dedupLockKey(b, byNamespace) {
+		t.Error("namespace scope splits a lock by type")
+	}
+	if dedupLockKey(a, byNamespace) == dedupLockKey(c, byNamespace) {
+		t.Error("namespaces share a lock")
+	}
+}
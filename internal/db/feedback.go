@@ -0,0 +1,217 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Feedback signals an agent can give about a recalled seed.
+const (
+	FeedbackHelpful    = "helpful"
+	FeedbackIrrelevant = "irrelevant"
+	FeedbackOutdated   = "outdated"
+	FeedbackIncorrect  = "incorrect"
+)
+
+// FeedbackAdjustment moves confidence a fraction Rate of the way towards
+// Target: confidence += Rate × (Target − confidence).
+type FeedbackAdjustment struct {
+	Target float32 `json:"target"`
+	Rate   float32 `json:"rate"`
+}
+
+func (a FeedbackAdjustment) apply(confidence float32) float32 {
+	return confidence + a.Rate*(a.Target-confidence)
+}
+
+// FeedbackRules maps each signal to its confidence adjustment.
+type FeedbackRules map[string]FeedbackAdjustment
+
+// DefaultFeedbackRules reward helpful recalls gently, shrug off irrelevant
+// ones, and cut outdated and incorrect memories hard.
+var DefaultFeedbackRules = FeedbackRules{
+	FeedbackHelpful:    {Target: 1, Rate: 0.2},
+	FeedbackIrrelevant: {Target: 0, Rate: 0.05},
+	FeedbackOutdated:   {Target: 0, Rate: 0.3},
+	FeedbackIncorrect:  {Target: 0, Rate: 0.6},
+}
+
+// Validate reports adjustments outside [0, 1].
+func (r FeedbackRules) Validate() error {
+	for signal, a := range r {
+		if a.Target < 0 || a.Target > 1 || a.Rate < 0 || a.Rate > 1 {
+			return fmt.Errorf("feedback rule %q: target and rate must be between 0.0 and 1.0", signal)
+		}
+	}
+	return nil
+}
+
+type SeedFeedback struct {
+	ID               int64     `json:"id"`
+	SeedID           string    `json:"seed_id"`
+	Namespace        string    `json:"namespace"`
+	Signal           string    `json:"signal"`
+	Comment          string    `json:"comment,omitempty"`
+	Query            string    `json:"query,omitempty"`
+	Score            *float32  `json:"score,omitempty"`
+	ConfidenceBefore float32   `json:"confidence_before"`
+	ConfidenceAfter  float32   `json:"confidence_after"`
+	Actor            string    `json:"actor,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// RecordFeedback stores a feedback event and applies its adjustment to the
+// seed's confidence. The change is recorded as a seed revision like any
+// other confidence change. fb is filled with the stored event.
+func (db *DB) RecordFeedback(ctx context.Context, fb *SeedFeedback, adj FeedbackAdjustment) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := snapshotSeed(ctx, tx, fb.Namespace, fb.SeedID, ChangeFeedback, fb.Actor); err != nil {
+		return err
+	}
+	err = tx.QueryRowContext(ctx, `SELECT confidence FROM seeds WHERE id = $1 AND namespace = $2`, fb.SeedID, fb.Namespace).Scan(&fb.ConfidenceBefore)
+	if err != nil {
+		return fmt.Errorf("failed to read confidence: %w", err)
+	}
+	fb.ConfidenceAfter = adj.apply(fb.ConfidenceBefore)
+	if _, err := tx.ExecContext(ctx, setConfidenceQuery, fb.ConfidenceAfter, fb.SeedID, fb.Namespace); err != nil {
+		return fmt.Errorf("failed to set confidence: %w", err)
+	}
+
+	query := `
+		INSERT INTO seed_feedback (seed_id, namespace, signal, comment, query, score, confidence_before, confidence_after, actor)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, $7, $8, NULLIF($9, ''))
+		RETURNING id, created_at
+	`
+	err = tx.QueryRowContext(ctx, query, fb.SeedID, fb.Namespace, fb.Signal, fb.Comment, fb.Query, fb.Score,
+		fb.ConfidenceBefore, fb.ConfidenceAfter, fb.Actor).Scan(&fb.ID, &fb.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record feedback: %w", err)
+	}
+	return tx.Commit()
+}
+
+type FeedbackQuery struct {
+	Namespace string
+	SeedID    string
+	Signal    string
+	Since     *time.Time
+	Until     *time.Time
+	Limit     int
+}
+
+// where renders the query's filters as a WHERE clause.
+func (q FeedbackQuery) where() (string, []interface{}) {
+	conds := []string{"namespace = $1"}
+	args := []interface{}{q.Namespace}
+	add := func(cond string, v interface{}) {
+		args = append(args, v)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if q.SeedID != "" {
+		add("seed_id = $%d", q.SeedID)
+	}
+	if q.Signal != "" {
+		add("signal = $%d", q.Signal)
+	}
+	if q.Since != nil {
+		add("created_at >= $%d", *q.Since)
+	}
+	if q.Until != nil {
+		add("created_at <= $%d", *q.Until)
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// ListFeedback returns feedback events, newest first.
+func (db *DB) ListFeedback(ctx context.Context, q FeedbackQuery) ([]SeedFeedback, error) {
+	if q.Limit <= 0 {
+		q.Limit = 100
+	}
+	where, args := q.where()
+	args = append(args, q.Limit)
+	query := `
+		SELECT id, seed_id, namespace, signal, COALESCE(comment, ''), COALESCE(query, ''), score,
+		       confidence_before, confidence_after, COALESCE(actor, ''), created_at
+		FROM seed_feedback` + where + fmt.Sprintf(`
+		ORDER BY created_at DESC
+		LIMIT $%d`, len(args))
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list feedback: %w", err)
+	}
+	defer rows.Close()
+
+	var events []SeedFeedback
+	for rows.Next() {
+		var fb SeedFeedback
+		var score sql.NullFloat64
+		if err := rows.Scan(&fb.ID, &fb.SeedID, &fb.Namespace, &fb.Signal, &fb.Comment, &fb.Query, &score,
+			&fb.ConfidenceBefore, &fb.ConfidenceAfter, &fb.Actor, &fb.CreatedAt); err != nil {
+			return nil, err
+		}
+		if score.Valid {
+			s := float32(score.Float64)
+			fb.Score = &s
+		}
+		events = append(events, fb)
+	}
+	return events, rows.Err()
+}
+
+// FeedbackStats summarises the events of one signal. Comparing score
+// distributions of helpful and irrelevant recalls shows where a search
+// threshold should sit.
+type FeedbackStats struct {
+	Signal   string   `json:"signal"`
+	Count    int64    `json:"count"`
+	AvgScore *float32 `json:"avg_score,omitempty"`
+	MinScore *float32 `json:"min_score,omitempty"`
+	MaxScore *float32 `json:"max_score,omitempty"`
+	P50Score *float32 `json:"p50_score,omitempty"`
+}
+
+// FeedbackStatistics aggregates feedback per signal. Limit is ignored.
+func (db *DB) FeedbackStatistics(ctx context.Context, q FeedbackQuery) ([]FeedbackStats, error) {
+	where, args := q.where()
+	query := `
+		SELECT signal, COUNT(*), AVG(score), MIN(score), MAX(score),
+		       percentile_cont(0.5) WITHIN GROUP (ORDER BY score)
+		FROM seed_feedback` + where + `
+		GROUP BY signal
+		ORDER BY signal`
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate feedback: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []FeedbackStats
+	for rows.Next() {
+		var st FeedbackStats
+		var avg, lo, hi, p50 sql.NullFloat64
+		if err := rows.Scan(&st.Signal, &st.Count, &avg, &lo, &hi, &p50); err != nil {
+			return nil, err
+		}
+		st.AvgScore, st.MinScore, st.MaxScore, st.P50Score = nullFloat32(avg), nullFloat32(lo), nullFloat32(hi), nullFloat32(p50)
+		stats = append(stats, st)
+	}
+	return stats, rows.Err()
+}
+
+func nullFloat32(v sql.NullFloat64) *float32 {
+	if !v.Valid {
+		return nil
+	}
+	f := float32(v.Float64)
+	return &f
+}
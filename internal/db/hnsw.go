@@ -0,0 +1,204 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// An HNSW scan returns at most hnsw.ef_search rows. defaultEfSearch is
+// pgvector's default, maxEfSearch the largest value it accepts.
+const (
+	defaultEfSearch = 40
+	maxEfSearch     = 1000
+)
+
+// efSearch returns the hnsw.ef_search a search fetching candidates rows
+// per index scan should run with, or 0 to keep the server default.
+func efSearch(requested, candidates int) int {
+	ef := requested
+	if ef <= 0 {
+		if candidates <= defaultEfSearch {
+			return 0
+		}
+		ef = candidates
+	}
+	if ef > maxEfSearch {
+		ef = maxEfSearch
+	}
+	return ef
+}
+
+// withEfSearch runs fn in a transaction with hnsw.ef_search set to ef, or
+// directly on the pool when ef is 0.
+func (db *DB) withEfSearch(ctx context.Context, ef int, fn func(q queryer) error) error {
+	if ef <= 0 {
+		return fn(db.DB)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT set_config('hnsw.ef_search', $1, true)`, strconv.Itoa(ef)); err != nil {
+		return fmt.Errorf("failed to set ef_search: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// syntheticMetadata marks seeds created by InsertSyntheticSeeds.
+const syntheticMetadata = `{"synthetic": true}`
+
+// BenchNamespacePrefix starts the name of every namespace synthetic seeds
+// may live in, keeping them apart from real memories.
+const BenchNamespacePrefix = "bench"
+
+// checkBenchNamespace refuses namespaces not reserved for benchmarks.
+func checkBenchNamespace(namespace string) error {
+	if namespace == DefaultNamespace || !strings.HasPrefix(namespace, BenchNamespacePrefix) {
+		return fmt.Errorf("benchmarks need a dedicated namespace starting with %q, got %q", BenchNamespacePrefix, namespace)
+	}
+	return nil
+}
+
+// InsertSyntheticSeeds tops the synthetic seeds of a namespace up to n,
+// each with a random embedding of dims dimensions, and analyzes the table.
+// It returns how many seeds were inserted. The namespace must be reserved
+// for benchmarks and hold no real seeds.
+func (db *DB) InsertSyntheticSeeds(ctx context.Context, namespace string, n, dims int, progress func(done int)) (int, error) {
+	if err := checkBenchNamespace(namespace); err != nil {
+		return 0, err
+	}
+	var have, others int
+	err := db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FILTER (WHERE metadata @> $2::jsonb), COUNT(*) FILTER (WHERE NOT metadata @> $2::jsonb)
+		FROM seeds WHERE namespace = $1`,
+		namespace, syntheticMetadata).Scan(&have, &others)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count synthetic seeds: %w", err)
+	}
+	if others > 0 {
+		return 0, fmt.Errorf("namespace %q holds %d seeds that are not synthetic; pick another one", namespace, others)
+	}
+
+	// The correlated WHERE makes Postgres draw a new vector for every row.
+	query := `
+		INSERT INTO seeds (namespace, content, title, type, embedding, embedding_model, embedding_dims, metadata)
+		SELECT $1, 'Synthetic seed ' || g, 'Synthetic ' || g, 'text',
+		       (SELECT array_agg(random() - 0.5)::real[] FROM generate_series(1, $2) d WHERE g > 0)::vector,
+		       'synthetic', $2, $3::jsonb
+		FROM generate_series($4::int, $5::int) g`
+	const batch = 5000
+	inserted := 0
+	for from := have + 1; from <= n; from += batch {
+		to := from + batch - 1
+		if to > n {
+			to = n
+		}
+		if _, err := db.ExecContext(ctx, query, namespace, dims, syntheticMetadata, from, to); err != nil {
+			return inserted, fmt.Errorf("failed to insert synthetic seeds: %w", err)
+		}
+		inserted += to - from + 1
+		if progress != nil {
+			progress(have + inserted)
+		}
+	}
+
+	if _, err := db.ExecContext(ctx, `ANALYZE seeds`); err != nil {
+		return inserted, fmt.Errorf("failed to analyze seeds: %w", err)
+	}
+	return inserted, nil
+}
+
+// DeleteSyntheticSeeds removes the synthetic seeds of a namespace.
+func (db *DB) DeleteSyntheticSeeds(ctx context.Context, namespace string) (int64, error) {
+	if err := checkBenchNamespace(namespace); err != nil {
+		return 0, err
+	}
+	result, err := db.ExecContext(ctx, `DELETE FROM seeds WHERE namespace = $1 AND metadata @> $2::jsonb`, namespace, syntheticMetadata)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete synthetic seeds: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// ExplainSeedSearch returns the EXPLAIN ANALYZE plan of the retrieval stage
+// of SearchSeeds, which reads the same candidates without recalling them.
+func (db *DB) ExplainSeedSearch(ctx context.Context, embedding []float32, opts SeedSearchOptions) (string, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = 10
+	}
+	hits, args := seedHitsCTE(embedding, opts, opts.Limit)
+
+	var lines []string
+	err := db.withEfSearch(ctx, efSearch(opts.EfSearch, opts.Limit*candidateFactor), func(q queryer) error {
+		rows, err := q.QueryContext(ctx, `EXPLAIN (ANALYZE, BUFFERS) WITH `+hits+` SELECT id, score FROM hits`, args...)
+		if err != nil {
+			return fmt.Errorf("failed to explain search: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var line string
+			if err := rows.Scan(&line); err != nil {
+				return err
+			}
+			lines = append(lines, line)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// SeedSearchHits runs the retrieval stage of SearchSeeds in a benchmark
+// namespace without recalling anything and returns the IDs of the hits,
+// best first. exact disables index
+// scans, so the hits are the true nearest neighbours; benchmarks compare
+// both to measure recall.
+func (db *DB) SeedSearchHits(ctx context.Context, embedding []float32, opts SeedSearchOptions, exact bool) ([]string, error) {
+	if err := checkBenchNamespace(opts.Namespace); err != nil {
+		return nil, err
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 10
+	}
+	hits, args := seedHitsCTE(embedding, opts, opts.Limit)
+
+	ef := efSearch(opts.EfSearch, opts.Limit*candidateFactor)
+	if exact && ef == 0 {
+		// Any value opens the transaction the planner setting needs.
+		ef = defaultEfSearch
+	}
+	var ids []string
+	err := db.withEfSearch(ctx, ef, func(q queryer) error {
+		if exact {
+			if _, err := q.ExecContext(ctx, `SET LOCAL enable_indexscan = off`); err != nil {
+				return fmt.Errorf("failed to disable index scans: %w", err)
+			}
+		}
+		rows, err := q.QueryContext(ctx, `WITH `+hits+` SELECT id FROM hits ORDER BY score DESC`, args...)
+		if err != nil {
+			return fmt.Errorf("failed to query seeds: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
@@ -0,0 +1,45 @@
+package db
+
+import "testing"
+
+func TestEfSearch(t *testing.T) {
+	tests := []struct {
+		name                  string
+		requested, candidates int
+		want                  int
+	}{
+		{"server default", 0, 10, 0},
+		{"server default covers candidates", 0, defaultEfSearch, 0},
+		{"raised to candidates", 0, 200, 200},
+		{"candidates capped", 0, 5000, maxEfSearch},
+		{"requested wins", 100, 10, 100},
+		{"requested below candidates", 20, 200, 20},
+		{"requested capped", 5000, 10, maxEfSearch},
+		{"negative is unset", -1, 80, 80},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := efSearch(tt.requested, tt.candidates); got != tt.want {
+				t.Errorf("efSearch(%d, %d) = %d, want %d", tt.requested, tt.candidates, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckBenchNamespace(t *testing.T) {
+	tests := []struct {
+		namespace string
+		ok        bool
+	}{
+		{BenchNamespacePrefix + "run1", true},
+		{BenchNamespacePrefix, true},
+		{DefaultNamespace, false},
+		{"work", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if err := checkBenchNamespace(tt.namespace); (err == nil) != tt.ok {
+			t.Errorf("checkBenchNamespace(%q) = %v, want ok=%v", tt.namespace, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,507 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Types of seed links. A link reads "from <type> to": the newer seed
+// supersedes the older one, a detail elaborates a summary, an effect is
+// caused_by its cause.
+const (
+	LinkSupersedes  = "supersedes"
+	LinkContradicts = "contradicts"
+	LinkElaborates  = "elaborates"
+	LinkCausedBy    = "caused_by"
+	LinkRelated     = "related"
+)
+
+// LinkTypes lists every valid link type.
+var LinkTypes = []string{LinkSupersedes, LinkContradicts, LinkElaborates, LinkCausedBy, LinkRelated}
+
+// ValidLinkType reports whether t is one of LinkTypes.
+func ValidLinkType(t string) bool {
+	for _, lt := range LinkTypes {
+		if t == lt {
+			return true
+		}
+	}
+	return false
+}
+
+// supersededFactor scales the confidence of a seed once something
+// supersedes it.
+const supersededFactor = 0.5
+
+// Link directions relative to a seed.
+const (
+	LinkOut  = "out"
+	LinkIn   = "in"
+	LinkBoth = "both"
+)
+
+// SeedLink is a typed, directed edge between two seeds. DemotedFrom is the
+// confidence the target had before a supersedes link lowered it.
+type SeedLink struct {
+	ID          string    `json:"id"`
+	Namespace   string    `json:"namespace"`
+	FromID      string    `json:"from_id"`
+	ToID        string    `json:"to_id"`
+	Type        string    `json:"type"`
+	Weight      float32   `json:"weight"`
+	Note        string    `json:"note,omitempty"`
+	DemotedFrom *float32  `json:"demoted_from,omitempty"`
+	CreatedBy   string    `json:"created_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	// Direction and Seed describe the other end when listing a seed's
+	// links: "out" links point from the seed to Seed.
+	Direction string      `json:"direction,omitempty"`
+	Seed      *LinkedSeed `json:"seed,omitempty"`
+}
+
+// LinkedSeed is the other end of a listed link.
+type LinkedSeed struct {
+	ID         string  `json:"id"`
+	Title      string  `json:"title"`
+	Type       string  `json:"type"`
+	Confidence float32 `json:"confidence"`
+	Deleted    bool    `json:"deleted,omitempty"`
+}
+
+// seedLinkColumns is the column list read by scanSeedLink.
+const seedLinkColumns = `id, namespace, from_id, to_id, type, weight, note, demoted_from, COALESCE(created_by, ''), created_at`
+
+func scanSeedLink(row rowScanner, l *SeedLink, extra ...interface{}) error {
+	var demoted sql.NullFloat64
+	dest := []interface{}{&l.ID, &l.Namespace, &l.FromID, &l.ToID, &l.Type, &l.Weight, &l.Note, &demoted, &l.CreatedBy, &l.CreatedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+	l.DemotedFrom = nil
+	if demoted.Valid {
+		d := float32(demoted.Float64)
+		l.DemotedFrom = &d
+	}
+	return nil
+}
+
+// CreateSeedLink links l.FromID to l.ToID. Both seeds must be live seeds of
+// the namespace. A supersedes link lowers the target's confidence unless
+// the target is protected or already superseded. l is filled with the
+// stored link.
+func (db *DB) CreateSeedLink(ctx context.Context, l *SeedLink) error {
+	if !ValidLinkType(l.Type) {
+		return fmt.Errorf("unknown link type %q", l.Type)
+	}
+	if l.FromID == l.ToID {
+		return fmt.Errorf("a seed cannot link to itself")
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var live int
+	err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM seeds WHERE id IN ($1, $2) AND namespace = $3 AND deleted_at IS NULL`,
+		l.FromID, l.ToID, l.Namespace).Scan(&live)
+	if err != nil {
+		return fmt.Errorf("failed to check seeds: %w", err)
+	}
+	if live != 2 {
+		return fmt.Errorf("seed not found")
+	}
+	var exists bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM seed_links WHERE from_id = $1 AND to_id = $2 AND type = $3)`,
+		l.FromID, l.ToID, l.Type).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check link: %w", err)
+	}
+	if exists {
+		return fmt.Errorf("link already exists")
+	}
+
+	var demoted *float32
+	if l.Type == LinkSupersedes {
+		if demoted, err = demoteSuperseded(ctx, tx, l.Namespace, l.ToID, l.CreatedBy); err != nil {
+			return err
+		}
+	}
+
+	query := `
+		INSERT INTO seed_links (namespace, from_id, to_id, type, weight, note, demoted_from, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''))
+		RETURNING ` + seedLinkColumns
+	err = scanSeedLink(tx.QueryRowContext(ctx, query, l.Namespace, l.FromID, l.ToID, l.Type, l.Weight, l.Note, demoted, l.CreatedBy), l)
+	if err != nil {
+		return fmt.Errorf("failed to create link: %w", err)
+	}
+	return tx.Commit()
+}
+
+// demoteSuperseded lowers the confidence of a newly superseded seed and
+// returns the confidence it had. Protected seeds and seeds whose demotion a
+// supersedes link already holds are left alone and nil is returned.
+func demoteSuperseded(ctx context.Context, tx *sql.Tx, namespace, id, actor string) (*float32, error) {
+	var confidence float32
+	var protected, superseded bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT confidence, protected,
+		       EXISTS (SELECT 1 FROM seed_links WHERE to_id = $1 AND type = $3 AND demoted_from IS NOT NULL)
+		FROM seeds WHERE id = $1 AND namespace = $2`, id, namespace, LinkSupersedes).Scan(&confidence, &protected, &superseded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seed: %w", err)
+	}
+	if protected || superseded {
+		return nil, nil
+	}
+
+	if err := snapshotSeed(ctx, tx, namespace, id, ChangeSupersede, actor); err != nil {
+		return nil, err
+	}
+	demoted := confidence * supersededFactor
+	if demoted < 0.01 {
+		demoted = 0.01
+	}
+	if _, err := tx.ExecContext(ctx, setConfidenceQuery, demoted, id, namespace); err != nil {
+		return nil, fmt.Errorf("failed to demote superseded seed: %w", err)
+	}
+	return &confidence, nil
+}
+
+// restoreSuperseded gives a seed that is no longer superseded back the
+// confidence it had, unless it has risen above that since. Seeds in the
+// trash get it back too, so they come out of it as they were.
+func restoreSuperseded(ctx context.Context, tx *sql.Tx, namespace, id string, confidence float32, actor string) error {
+	var current float32
+	err := tx.QueryRowContext(ctx, `SELECT confidence FROM seeds WHERE id = $1 AND namespace = $2 FOR UPDATE`, id, namespace).Scan(&current)
+	if err == sql.ErrNoRows || (err == nil && current >= confidence) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read seed: %w", err)
+	}
+	if err := recordRevision(ctx, tx, namespace, id, ChangeSupersede, actor); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, setConfidenceQuery, confidence, id, namespace); err != nil {
+		return fmt.Errorf("failed to restore confidence: %w", err)
+	}
+	return nil
+}
+
+// releaseDemotion hands the demotion of id, which had confidence before it
+// was superseded, to its oldest remaining supersedes link from a live seed
+// other than gone. Without one, the confidence is restored.
+func releaseDemotion(ctx context.Context, tx *sql.Tx, namespace, id string, confidence float32, gone, actor string) error {
+	result, err := tx.ExecContext(ctx, `
+		UPDATE seed_links SET demoted_from = $1
+		WHERE id = (
+			SELECT l.id FROM seed_links l JOIN seeds s ON s.id = l.from_id
+			WHERE l.to_id = $2 AND l.type = $3 AND s.deleted_at IS NULL AND l.from_id IS DISTINCT FROM NULLIF($4, '')::uuid
+			ORDER BY l.created_at, l.id LIMIT 1)`,
+		confidence, id, LinkSupersedes, gone)
+	if err != nil {
+		return fmt.Errorf("failed to hand over demotion: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		return nil
+	}
+	return restoreSuperseded(ctx, tx, namespace, id, confidence, actor)
+}
+
+// settleSuperseded brings the demotion of id in line with the seeds that
+// supersede it, counting gone as already removed. A demotion held by a link
+// from a trashed or removed seed is handed over or undone, and a live seed
+// that a live seed supersedes without demoting it is demoted.
+func settleSuperseded(ctx context.Context, tx *sql.Tx, namespace, id, gone, actor string) error {
+	var holder string
+	var demotedFrom float32
+	var live bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT l.id, l.demoted_from, s.deleted_at IS NULL AND l.from_id IS DISTINCT FROM NULLIF($3, '')::uuid
+		FROM seed_links l JOIN seeds s ON s.id = l.from_id
+		WHERE l.to_id = $1 AND l.type = $2 AND l.demoted_from IS NOT NULL
+		LIMIT 1`, id, LinkSupersedes, gone).Scan(&holder, &demotedFrom, &live)
+	switch {
+	case err == nil && live:
+		return nil
+	case err == nil:
+		if _, err := tx.ExecContext(ctx, `UPDATE seed_links SET demoted_from = NULL WHERE id = $1`, holder); err != nil {
+			return fmt.Errorf("failed to release demotion: %w", err)
+		}
+		return releaseDemotion(ctx, tx, namespace, id, demotedFrom, gone, actor)
+	case err != sql.ErrNoRows:
+		return fmt.Errorf("failed to read supersedes links: %w", err)
+	}
+
+	var link string
+	err = tx.QueryRowContext(ctx, `
+		SELECT l.id FROM seed_links l
+		JOIN seeds s ON s.id = l.from_id
+		JOIN seeds t ON t.id = l.to_id
+		WHERE l.to_id = $1 AND l.type = $2 AND s.deleted_at IS NULL AND t.deleted_at IS NULL
+		  AND l.from_id IS DISTINCT FROM NULLIF($3, '')::uuid
+		ORDER BY l.created_at, l.id LIMIT 1`, id, LinkSupersedes, gone).Scan(&link)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read supersedes links: %w", err)
+	}
+	demoted, err := demoteSuperseded(ctx, tx, namespace, id, actor)
+	if err != nil || demoted == nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE seed_links SET demoted_from = $1 WHERE id = $2`, *demoted, link); err != nil {
+		return fmt.Errorf("failed to record demotion: %w", err)
+	}
+	return nil
+}
+
+// settleSupersededBy runs settleSuperseded for every seed that from
+// supersedes.
+func settleSupersededBy(ctx context.Context, tx *sql.Tx, namespace, from, gone, actor string) error {
+	rows, err := tx.QueryContext(ctx, `SELECT to_id FROM seed_links WHERE from_id = $1 AND type = $2`, from, LinkSupersedes)
+	if err != nil {
+		return fmt.Errorf("failed to read supersedes links: %w", err)
+	}
+	var targets []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		targets = append(targets, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read supersedes links: %w", err)
+	}
+	for _, id := range targets {
+		if err := settleSuperseded(ctx, tx, namespace, id, gone, actor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListSeedLinks returns the links of a seed in the given direction
+// (LinkOut, LinkIn or LinkBoth), optionally of one type, newest first.
+func (db *DB) ListSeedLinks(ctx context.Context, namespace, seedID, direction, linkType string) ([]SeedLink, error) {
+	var where string
+	switch direction {
+	case LinkOut:
+		where = "l.from_id = $2"
+	case LinkIn:
+		where = "l.to_id = $2"
+	case LinkBoth, "":
+		where = "(l.from_id = $2 OR l.to_id = $2)"
+	default:
+		return nil, fmt.Errorf("direction must be out, in or both")
+	}
+	args := []interface{}{namespace, seedID}
+	if linkType != "" {
+		args = append(args, linkType)
+		where += fmt.Sprintf(" AND l.type = $%d", len(args))
+	}
+
+	query := `
+		SELECT l.id, l.namespace, l.from_id, l.to_id, l.type, l.weight, l.note, l.demoted_from, COALESCE(l.created_by, ''), l.created_at,
+		       s.id, s.title, s.type, s.confidence, s.deleted_at IS NOT NULL
+		FROM seed_links l
+		JOIN seeds s ON s.id = CASE WHEN l.from_id = $2 THEN l.to_id ELSE l.from_id END
+		WHERE l.namespace = $1 AND ` + where + `
+		ORDER BY l.created_at DESC, l.id DESC`
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list links: %w", err)
+	}
+	defer rows.Close()
+
+	var links []SeedLink
+	for rows.Next() {
+		var l SeedLink
+		other := &LinkedSeed{}
+		if err := scanSeedLink(rows, &l, &other.ID, &other.Title, &other.Type, &other.Confidence, &other.Deleted); err != nil {
+			return nil, err
+		}
+		l.Seed = other
+		l.Direction = LinkOut
+		if l.ToID == seedID {
+			l.Direction = LinkIn
+		}
+		links = append(links, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list links: %w", err)
+	}
+	return links, nil
+}
+
+// UpdateSeedLink changes the weight and note of a link of seedID. nil
+// fields are kept.
+func (db *DB) UpdateSeedLink(ctx context.Context, namespace, seedID, linkID string, weight *float32, note *string) (*SeedLink, error) {
+	query := `
+		UPDATE seed_links
+		SET weight = COALESCE($4, weight), note = COALESCE($5, note)
+		WHERE id = $1 AND namespace = $2 AND (from_id = $3 OR to_id = $3)
+		RETURNING ` + seedLinkColumns
+	var l SeedLink
+	if err := scanSeedLink(db.QueryRowContext(ctx, query, linkID, namespace, seedID, weight, note), &l); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("link not found")
+		}
+		return nil, fmt.Errorf("failed to update link: %w", err)
+	}
+	return &l, nil
+}
+
+// DeleteSeedLink removes a link of seedID. Removing the supersedes link
+// that demoted a seed restores its earlier confidence, unless it has risen
+// since or another live seed still supersedes it; that seed's link then
+// takes over the confidence to restore.
+func (db *DB) DeleteSeedLink(ctx context.Context, namespace, seedID, linkID, actor string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var l SeedLink
+	err = scanSeedLink(tx.QueryRowContext(ctx, `
+		DELETE FROM seed_links
+		WHERE id = $1 AND namespace = $2 AND (from_id = $3 OR to_id = $3)
+		RETURNING `+seedLinkColumns, linkID, namespace, seedID), &l)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("link not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete link: %w", err)
+	}
+
+	if l.Type == LinkSupersedes && l.DemotedFrom != nil {
+		if err := releaseDemotion(ctx, tx, namespace, l.ToID, *l.DemotedFrom, "", actor); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// DefaultExpansionDecay is the score factor applied per hop when
+// GraphExpansion.Decay is unset.
+const DefaultExpansionDecay = 0.5
+
+// GraphExpansion pulls the one-hop neighbours of search hits into the
+// results. A neighbour scores hit score × Decay × link weight × its own
+// confidence, so superseded seeds stay low.
+type GraphExpansion struct {
+	Decay float32
+	// Types limits the links followed; empty follows all.
+	Types []string
+	// Limit caps how many neighbours are added.
+	Limit int
+}
+
+// LinkVia explains why a neighbour was added: it is linked to the hit
+// SeedID. Direction "out" means the hit links to the neighbour.
+type LinkVia struct {
+	SeedID    string `json:"seed_id"`
+	LinkType  string `json:"link_type"`
+	Direction string `json:"direction"`
+}
+
+// expandNeighbours returns the best-scoring linked neighbours of hits that
+// are not hits themselves and pass opts' filters. Neighbours are not
+// counted as recalls.
+func expandNeighbours(ctx context.Context, q queryer, hits []SeedSearchResult, opts SeedSearchOptions) ([]SeedSearchResult, error) {
+	if len(hits) == 0 || opts.Expand == nil {
+		return nil, nil
+	}
+	ids := make([]string, len(hits))
+	scores := make([]float64, len(hits))
+	for i, h := range hits {
+		ids[i], scores[i] = h.ID, float64(h.Similarity)
+	}
+	decay := opts.Expand.Decay
+	if decay <= 0 {
+		decay = DefaultExpansionDecay
+	}
+	limit := opts.Expand.Limit
+	if limit <= 0 {
+		limit = opts.Limit
+	}
+
+	args := []interface{}{pq.Array(ids), pq.Array(scores), decay, opts.Namespace, limit}
+	typeFilter := ""
+	if len(opts.Expand.Types) > 0 {
+		args = append(args, pq.Array(opts.Expand.Types))
+		typeFilter = fmt.Sprintf(" AND link_type = ANY($%d)", len(args))
+	}
+	filter := seedFilter{conds: []string{"deleted_at IS NULL", "namespace = $4"}}
+	args = opts.SeedFilters.appendTo(&filter, args)
+
+	query := fmt.Sprintf(`
+		WITH hits AS (
+			SELECT * FROM unnest($1::uuid[], $2::real[]) AS h(id, score)
+		),
+		edges AS (
+			SELECT h.id AS via_id, h.score * l.weight * $3::real AS link_score, l.type AS link_type, 'out' AS direction, l.to_id AS neighbour_id
+			FROM seed_links l JOIN hits h ON h.id = l.from_id
+			WHERE l.namespace = $4
+			UNION ALL
+			SELECT h.id, h.score * l.weight * $3::real, l.type, 'in', l.from_id
+			FROM seed_links l JOIN hits h ON h.id = l.to_id
+			WHERE l.namespace = $4
+		),
+		best AS (
+			SELECT DISTINCT ON (neighbour_id) *
+			FROM edges
+			WHERE neighbour_id <> ALL($1::uuid[])%s
+			ORDER BY neighbour_id, link_score DESC
+		)
+		SELECT b.via_id, b.link_type, b.direction, b.link_score * seeds.confidence, %s
+		FROM best b JOIN seeds ON seeds.id = b.neighbour_id
+		WHERE true%s
+		ORDER BY 4 DESC
+		LIMIT $5`, typeFilter, seedColumns, filter.sql("seeds."))
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand search hits: %w", err)
+	}
+	defer rows.Close()
+
+	var out []SeedSearchResult
+	for rows.Next() {
+		var res SeedSearchResult
+		via := &LinkVia{}
+		if err := scanSeed(prefixScanner{rows, []interface{}{&via.SeedID, &via.LinkType, &via.Direction, &res.Similarity}}, &res.Seed); err != nil {
+			return nil, err
+		}
+		res.Via = via
+		out = append(out, res)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to expand search hits: %w", err)
+	}
+	return out, nil
+}
+
+// prefixScanner scans extra leading columns before handing the rest of a
+// row to a scan function that expects only its own.
+type prefixScanner struct {
+	row   rowScanner
+	extra []interface{}
+}
+
+func (p prefixScanner) Scan(dest ...interface{}) error {
+	return p.row.Scan(append(p.extra, dest...)...)
+}
@@ -0,0 +1,164 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// migrationLockID is the pg_advisory_lock key that serialises migrations
+// across replicas booting at the same time.
+const migrationLockID = 727_411_001
+
+type MigrationState struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+// AutoMigrate applies every pending migration.
+func (db *DB) AutoMigrate(ctx context.Context) error {
+	return db.MigrateUp(ctx, 0)
+}
+
+// MigrateUp applies pending migrations up to and including target. A target
+// of 0 means the latest migration.
+func (db *DB) MigrateUp(ctx context.Context, target int) error {
+	if target == 0 {
+		target = migrations[len(migrations)-1].Version
+	}
+
+	return db.withMigrationLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		pending := 0
+		for _, m := range migrations {
+			if m.Version > target || applied[m.Version] != nil {
+				continue
+			}
+			log.Printf("Applying migration %d (%s)...", m.Version, m.Name)
+			err := runInTx(ctx, conn, m.Up, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.Version, m.Name)
+			if err != nil {
+				return fmt.Errorf("failed to apply migration %d (%s): %w", m.Version, m.Name, err)
+			}
+			pending++
+		}
+
+		log.Printf("Database migration completed (%d applied).", pending)
+		return nil
+	})
+}
+
+// MigrateDown reverts the most recently applied migrations, newest first.
+func (db *DB) MigrateDown(ctx context.Context, steps int) error {
+	if steps <= 0 {
+		steps = 1
+	}
+
+	return db.withMigrationLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+			m := migrations[i]
+			if applied[m.Version] == nil {
+				continue
+			}
+			log.Printf("Reverting migration %d (%s)...", m.Version, m.Name)
+			err := runInTx(ctx, conn, m.Down, `DELETE FROM schema_migrations WHERE version = $1`, m.Version)
+			if err != nil {
+				return fmt.Errorf("failed to revert migration %d (%s): %w", m.Version, m.Name, err)
+			}
+			steps--
+		}
+		return nil
+	})
+}
+
+// MigrationStatus lists every known migration and when it was applied.
+func (db *DB) MigrationStatus(ctx context.Context) ([]MigrationState, error) {
+	var states []MigrationState
+	err := db.withMigrationLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			states = append(states, MigrationState{Version: m.Version, Name: m.Name, AppliedAt: applied[m.Version]})
+		}
+		return nil
+	})
+	return states, err
+}
+
+// withMigrationLock pins a single connection (advisory locks are
+// session-scoped), takes the migration lock and makes sure the
+// schema_migrations table exists before calling fn.
+func (db *DB) withMigrationLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	return fn(conn)
+}
+
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[int]*time.Time, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]*time.Time)
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = &at
+	}
+	return applied, rows.Err()
+}
+
+// runInTx executes a migration body and its bookkeeping statement atomically.
+func runInTx(ctx context.Context, conn *sql.Conn, body, record string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, body); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,575 @@
+package db
+
+// Migration is one numbered schema change. Up and Down may contain several
+// statements; each migration runs in its own transaction.
+//
+// Never edit a migration that has shipped, append a new one instead. The
+// first migrations use IF NOT EXISTS so databases created by the old
+// statement-list AutoMigrate adopt the versioned history cleanly.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "initial_schema",
+		Up: `
+			CREATE EXTENSION IF NOT EXISTS vector;
+
+			CREATE TABLE IF NOT EXISTS seeds (
+				id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+				content TEXT NOT NULL,
+				title TEXT NOT NULL,
+				type VARCHAR(50) NOT NULL,
+				embedding vector(384),
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+			);
+
+			CREATE INDEX IF NOT EXISTS seeds_embedding_idx ON seeds USING hnsw (embedding vector_l2_ops);
+
+			-- Confidence, decay tracking, and protection
+			ALTER TABLE seeds ADD COLUMN IF NOT EXISTS confidence REAL NOT NULL DEFAULT 1.0;
+			ALTER TABLE seeds ADD COLUMN IF NOT EXISTS last_accessed TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP;
+			ALTER TABLE seeds ADD COLUMN IF NOT EXISTS protected BOOLEAN NOT NULL DEFAULT FALSE;
+
+			CREATE TABLE IF NOT EXISTS agent_contexts (
+				id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+				agent_id VARCHAR(255) NOT NULL,
+				type VARCHAR(50) NOT NULL,
+				metadata JSONB,
+				summary TEXT,
+				embedding vector(384),
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+			);
+
+			CREATE INDEX IF NOT EXISTS agent_contexts_embedding_idx ON agent_contexts USING hnsw (embedding vector_l2_ops);
+		`,
+		Down: `
+			DROP TABLE IF EXISTS agent_contexts;
+			DROP TABLE IF EXISTS seeds;
+		`,
+	},
+	{
+		Version: 2,
+		Name:    "seeds_search_vector",
+		// 'simple' config avoids stemming so identifiers and error codes match verbatim.
+		Up: `
+			ALTER TABLE seeds ADD COLUMN IF NOT EXISTS search_vector tsvector
+				GENERATED ALWAYS AS (
+					setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+					setweight(to_tsvector('simple', coalesce(content, '')), 'B')
+				) STORED;
+			CREATE INDEX IF NOT EXISTS seeds_search_vector_idx ON seeds USING gin (search_vector);
+		`,
+		Down: `
+			DROP INDEX IF EXISTS seeds_search_vector_idx;
+			ALTER TABLE seeds DROP COLUMN IF EXISTS search_vector;
+		`,
+	},
+	{
+		Version: 3,
+		Name:    "namespaces",
+		// Namespaces isolate tenants; existing rows land in 'default'
+		Up: `
+			ALTER TABLE seeds ADD COLUMN IF NOT EXISTS namespace VARCHAR(64) NOT NULL DEFAULT 'default';
+			ALTER TABLE agent_contexts ADD COLUMN IF NOT EXISTS namespace VARCHAR(64) NOT NULL DEFAULT 'default';
+			CREATE INDEX IF NOT EXISTS seeds_namespace_created_idx ON seeds (namespace, created_at DESC);
+			CREATE INDEX IF NOT EXISTS agent_contexts_namespace_agent_idx ON agent_contexts (namespace, agent_id, created_at DESC);
+		`,
+		Down: `
+			DROP INDEX IF EXISTS agent_contexts_namespace_agent_idx;
+			DROP INDEX IF EXISTS seeds_namespace_created_idx;
+			ALTER TABLE agent_contexts DROP COLUMN IF EXISTS namespace;
+			ALTER TABLE seeds DROP COLUMN IF EXISTS namespace;
+		`,
+	},
+	{
+		Version: 4,
+		Name:    "api_keys",
+		Up: `
+			CREATE TABLE IF NOT EXISTS api_keys (
+				id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+				name TEXT NOT NULL,
+				prefix VARCHAR(16) NOT NULL,
+				key_hash CHAR(64) NOT NULL UNIQUE,
+				scopes TEXT[] NOT NULL DEFAULT '{}',
+				namespaces TEXT[] NOT NULL DEFAULT '{}',
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+				last_used_at TIMESTAMP WITH TIME ZONE,
+				revoked_at TIMESTAMP WITH TIME ZONE
+			);
+		`,
+		Down: `
+			DROP TABLE IF EXISTS api_keys;
+		`,
+	},
+	{
+		Version: 5,
+		Name:    "embedding_model_tracking",
+		// Everything embedded so far came from GTE-Small. The trigger function
+		// invalidates a row's shadow embedding whenever its live embedding
+		// changes during a re-embed job.
+		Up: `
+			ALTER TABLE seeds ADD COLUMN IF NOT EXISTS embedding_model TEXT;
+			ALTER TABLE seeds ADD COLUMN IF NOT EXISTS embedding_dims INTEGER;
+			ALTER TABLE agent_contexts ADD COLUMN IF NOT EXISTS embedding_model TEXT;
+			ALTER TABLE agent_contexts ADD COLUMN IF NOT EXISTS embedding_dims INTEGER;
+			UPDATE seeds SET embedding_model = 'gte-small', embedding_dims = 384 WHERE embedding IS NOT NULL AND embedding_model IS NULL;
+			UPDATE agent_contexts SET embedding_model = 'gte-small', embedding_dims = 384 WHERE embedding IS NOT NULL AND embedding_model IS NULL;
+
+			CREATE TABLE IF NOT EXISTS reembed_jobs (
+				id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+				status VARCHAR(20) NOT NULL,
+				target_model TEXT NOT NULL,
+				target_dims INTEGER NOT NULL,
+				processed INTEGER NOT NULL DEFAULT 0,
+				total INTEGER NOT NULL DEFAULT 0,
+				error TEXT,
+				started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				finished_at TIMESTAMP WITH TIME ZONE
+			);
+			CREATE UNIQUE INDEX IF NOT EXISTS reembed_jobs_running_idx ON reembed_jobs ((status)) WHERE status = 'running';
+
+			CREATE OR REPLACE FUNCTION reset_embedding_next() RETURNS trigger AS $$
+			BEGIN
+				IF NEW.embedding IS DISTINCT FROM OLD.embedding THEN
+					NEW.embedding_next := NULL;
+				END IF;
+				RETURN NEW;
+			END
+			$$ LANGUAGE plpgsql;
+		`,
+		Down: `
+			DROP FUNCTION IF EXISTS reset_embedding_next();
+			DROP TABLE IF EXISTS reembed_jobs;
+			ALTER TABLE agent_contexts DROP COLUMN IF EXISTS embedding_dims;
+			ALTER TABLE agent_contexts DROP COLUMN IF EXISTS embedding_model;
+			ALTER TABLE seeds DROP COLUMN IF EXISTS embedding_dims;
+			ALTER TABLE seeds DROP COLUMN IF EXISTS embedding_model;
+		`,
+	},
+	{
+		Version: 6,
+		Name:    "seed_chunks",
+		// Long seeds are split into overlapping chunks, each with its own
+		// embedding. namespace is denormalised so the chunk index can be
+		// filtered without joining seeds.
+		Up: `
+			CREATE TABLE IF NOT EXISTS seed_chunks (
+				id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+				seed_id UUID NOT NULL REFERENCES seeds(id) ON DELETE CASCADE,
+				namespace VARCHAR(64) NOT NULL,
+				chunk_index INTEGER NOT NULL,
+				content TEXT NOT NULL,
+				embedding vector(384),
+				embedding_model TEXT,
+				embedding_dims INTEGER,
+				UNIQUE (seed_id, chunk_index)
+			);
+			CREATE INDEX IF NOT EXISTS seed_chunks_embedding_idx ON seed_chunks USING hnsw (embedding vector_l2_ops);
+		`,
+		Down: `
+			DROP TABLE IF EXISTS seed_chunks;
+		`,
+	},
+	{
+		Version: 7,
+		Name:    "seed_dedup",
+		// provenance records every near-duplicate merged into a seed;
+		// duplicate_of links a seed inserted under the "link" policy to the
+		// seed it resembles.
+		Up: `
+			ALTER TABLE seeds ADD COLUMN IF NOT EXISTS provenance JSONB NOT NULL DEFAULT '[]';
+			ALTER TABLE seeds ADD COLUMN IF NOT EXISTS duplicate_of UUID REFERENCES seeds(id) ON DELETE SET NULL;
+			CREATE INDEX IF NOT EXISTS seeds_duplicate_of_idx ON seeds (duplicate_of) WHERE duplicate_of IS NOT NULL;
+		`,
+		Down: `
+			DROP INDEX IF EXISTS seeds_duplicate_of_idx;
+			ALTER TABLE seeds DROP COLUMN IF EXISTS duplicate_of;
+			ALTER TABLE seeds DROP COLUMN IF EXISTS provenance;
+		`,
+	},
+	{
+		Version: 8,
+		Name:    "seed_revisions",
+		// Each revision is a snapshot of a seed taken just before a change;
+		// change, actor and created_at describe the change that replaced it.
+		Up: `
+			CREATE TABLE IF NOT EXISTS seed_revisions (
+				id BIGSERIAL PRIMARY KEY,
+				seed_id UUID NOT NULL REFERENCES seeds(id) ON DELETE CASCADE,
+				namespace VARCHAR(64) NOT NULL,
+				revision INTEGER NOT NULL,
+				change VARCHAR(32) NOT NULL,
+				content TEXT NOT NULL,
+				title TEXT NOT NULL,
+				type VARCHAR(50) NOT NULL,
+				confidence REAL NOT NULL,
+				protected BOOLEAN NOT NULL,
+				embedding_model TEXT,
+				actor TEXT,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE (seed_id, revision)
+			);
+		`,
+		Down: `
+			DROP TABLE IF EXISTS seed_revisions;
+		`,
+	},
+	{
+		Version: 9,
+		Name:    "seed_soft_delete",
+		// Deleted seeds keep their row with a tombstone until purged.
+		Up: `
+			ALTER TABLE seeds ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+			ALTER TABLE seeds ADD COLUMN IF NOT EXISTS deleted_by TEXT;
+			CREATE INDEX IF NOT EXISTS seeds_trash_idx ON seeds (namespace, deleted_at) WHERE deleted_at IS NOT NULL;
+		`,
+		Down: `
+			DELETE FROM seeds WHERE deleted_at IS NOT NULL;
+			DROP INDEX IF EXISTS seeds_trash_idx;
+			ALTER TABLE seeds DROP COLUMN IF EXISTS deleted_by;
+			ALTER TABLE seeds DROP COLUMN IF EXISTS deleted_at;
+		`,
+	},
+	{
+		Version: 10,
+		Name:    "decay_runs",
+		// Each decay run records when it ran and how much time it covered, so
+		// the next run only applies decay for the time since.
+		Up: `
+			CREATE TABLE IF NOT EXISTS decay_runs (
+				id BIGSERIAL PRIMARY KEY,
+				ran_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				elapsed_seconds DOUBLE PRECISION NOT NULL,
+				rows_affected BIGINT NOT NULL,
+				details JSONB NOT NULL DEFAULT '{}'
+			);
+			CREATE INDEX IF NOT EXISTS decay_runs_ran_at_idx ON decay_runs (ran_at DESC);
+		`,
+		Down: `
+			DROP TABLE IF EXISTS decay_runs;
+		`,
+	},
+	{
+		Version: 11,
+		Name:    "seed_recalls",
+		// access_count and seed_recalls track every time a seed is returned by
+		// search, feeding reinforcement and decay from last access.
+		Up: `
+			ALTER TABLE seeds ADD COLUMN IF NOT EXISTS access_count INTEGER NOT NULL DEFAULT 0;
+			CREATE TABLE IF NOT EXISTS seed_recalls (
+				id BIGSERIAL PRIMARY KEY,
+				seed_id UUID NOT NULL REFERENCES seeds(id) ON DELETE CASCADE,
+				namespace VARCHAR(64) NOT NULL,
+				recalled_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				score REAL NOT NULL,
+				confidence_before REAL NOT NULL,
+				confidence_after REAL NOT NULL,
+				query TEXT
+			);
+			CREATE INDEX IF NOT EXISTS seed_recalls_seed_idx ON seed_recalls (seed_id, recalled_at DESC);
+		`,
+		Down: `
+			DROP TABLE IF EXISTS seed_recalls;
+			ALTER TABLE seeds DROP COLUMN IF EXISTS access_count;
+		`,
+	},
+	{
+		Version: 12,
+		Name:    "seed_feedback",
+		// Explicit feedback on recalled seeds. score and query describe the
+		// recall being judged so thresholds can be tuned against the events.
+		Up: `
+			CREATE TABLE IF NOT EXISTS seed_feedback (
+				id BIGSERIAL PRIMARY KEY,
+				seed_id UUID NOT NULL REFERENCES seeds(id) ON DELETE CASCADE,
+				namespace VARCHAR(64) NOT NULL,
+				signal VARCHAR(32) NOT NULL,
+				comment TEXT,
+				query TEXT,
+				score REAL,
+				confidence_before REAL NOT NULL,
+				confidence_after REAL NOT NULL,
+				actor TEXT,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS seed_feedback_seed_idx ON seed_feedback (seed_id, created_at DESC);
+			CREATE INDEX IF NOT EXISTS seed_feedback_namespace_idx ON seed_feedback (namespace, created_at DESC);
+		`,
+		Down: `
+			DROP TABLE IF EXISTS seed_feedback;
+		`,
+	},
+	{
+		Version: 13,
+		Name:    "seed_tags_metadata",
+		// Tags and free-form metadata for filtering, GIN-indexed so the
+		// filters can be applied inside the vector query.
+		Up: `
+			ALTER TABLE seeds ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+			ALTER TABLE seeds ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}';
+			CREATE INDEX IF NOT EXISTS seeds_tags_idx ON seeds USING gin (tags);
+			CREATE INDEX IF NOT EXISTS seeds_metadata_idx ON seeds USING gin (metadata jsonb_path_ops);
+
+			ALTER TABLE seed_revisions ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+			ALTER TABLE seed_revisions ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}';
+		`,
+		Down: `
+			ALTER TABLE seed_revisions DROP COLUMN IF EXISTS metadata;
+			ALTER TABLE seed_revisions DROP COLUMN IF EXISTS tags;
+			DROP INDEX IF EXISTS seeds_metadata_idx;
+			DROP INDEX IF EXISTS seeds_tags_idx;
+			ALTER TABLE seeds DROP COLUMN IF EXISTS metadata;
+			ALTER TABLE seeds DROP COLUMN IF EXISTS tags;
+		`,
+	},
+	{
+		Version: 14,
+		Name:    "keyset_pagination",
+		// Listing pages through (created_at, id); id breaks ties between
+		// rows created in the same transaction.
+		Up: `
+			CREATE INDEX IF NOT EXISTS seeds_namespace_created_id_idx ON seeds (namespace, created_at DESC, id DESC) WHERE deleted_at IS NULL;
+			CREATE INDEX IF NOT EXISTS agent_contexts_namespace_created_id_idx ON agent_contexts (namespace, created_at DESC, id DESC);
+		`,
+		Down: `
+			DROP INDEX IF EXISTS agent_contexts_namespace_created_id_idx;
+			DROP INDEX IF EXISTS seeds_namespace_created_id_idx;
+		`,
+	},
+	{
+		Version: 15,
+		Name:    "agent_context_protection",
+		// Agent contexts become editable; protected ones cannot be deleted.
+		Up: `
+			ALTER TABLE agent_contexts ADD COLUMN IF NOT EXISTS protected BOOLEAN NOT NULL DEFAULT false;
+			ALTER TABLE agent_contexts ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE;
+		`,
+		Down: `
+			ALTER TABLE agent_contexts DROP COLUMN IF EXISTS updated_at;
+			ALTER TABLE agent_contexts DROP COLUMN IF EXISTS protected;
+		`,
+	},
+	{
+		Version: 16,
+		Name:    "agent_contexts_partitioning",
+		// agent_contexts becomes partitioned by month of created_at so old
+		// months can be pruned from scans and dropped once emptied. Existing
+		// rows are copied into monthly partitions; rows outside every
+		// partition land in agent_contexts_default until maintenance moves
+		// them. The primary key must include the partition key.
+		Up: `
+			ALTER TABLE agent_contexts RENAME TO agent_contexts_unpartitioned;
+			ALTER INDEX IF EXISTS agent_contexts_pkey RENAME TO agent_contexts_unpartitioned_pkey;
+			DROP INDEX IF EXISTS agent_contexts_embedding_idx;
+			DROP INDEX IF EXISTS agent_contexts_namespace_agent_idx;
+			DROP INDEX IF EXISTS agent_contexts_namespace_created_id_idx;
+			UPDATE agent_contexts_unpartitioned SET created_at = NOW() WHERE created_at IS NULL;
+
+			CREATE TABLE agent_contexts (LIKE agent_contexts_unpartitioned INCLUDING DEFAULTS) PARTITION BY RANGE (created_at);
+			ALTER TABLE agent_contexts ALTER COLUMN created_at SET NOT NULL;
+			ALTER TABLE agent_contexts ADD PRIMARY KEY (id, created_at);
+			CREATE TABLE agent_contexts_default PARTITION OF agent_contexts DEFAULT;
+
+			DO $$
+			DECLARE
+				m timestamptz;
+			BEGIN
+				FOR m IN
+					SELECT generate_series(
+						date_trunc('month', COALESCE(MIN(created_at), NOW()), 'UTC'),
+						date_trunc('month', NOW(), 'UTC') + INTERVAL '2 months',
+						INTERVAL '1 month')
+					FROM agent_contexts_unpartitioned
+				LOOP
+					EXECUTE format('CREATE TABLE %I PARTITION OF agent_contexts FOR VALUES FROM (%L) TO (%L)',
+						'agent_contexts_' || to_char(m AT TIME ZONE 'UTC', 'YYYY_MM'), m, m + INTERVAL '1 month');
+				END LOOP;
+			END
+			$$;
+
+			INSERT INTO agent_contexts SELECT * FROM agent_contexts_unpartitioned;
+			DROP TABLE agent_contexts_unpartitioned;
+
+			CREATE INDEX agent_contexts_embedding_idx ON agent_contexts USING hnsw (embedding vector_l2_ops);
+			CREATE INDEX agent_contexts_namespace_agent_idx ON agent_contexts (namespace, agent_id, created_at DESC);
+			CREATE INDEX agent_contexts_namespace_created_id_idx ON agent_contexts (namespace, created_at DESC, id DESC);
+		`,
+		Down: `
+			CREATE TABLE agent_contexts_unpartitioned (LIKE agent_contexts INCLUDING DEFAULTS);
+			INSERT INTO agent_contexts_unpartitioned SELECT * FROM agent_contexts;
+			DROP TABLE agent_contexts;
+			ALTER TABLE agent_contexts_unpartitioned RENAME TO agent_contexts;
+			ALTER TABLE agent_contexts ADD PRIMARY KEY (id);
+
+			CREATE INDEX agent_contexts_embedding_idx ON agent_contexts USING hnsw (embedding vector_l2_ops);
+			CREATE INDEX agent_contexts_namespace_agent_idx ON agent_contexts (namespace, agent_id, created_at DESC);
+			CREATE INDEX agent_contexts_namespace_created_id_idx ON agent_contexts (namespace, created_at DESC, id DESC);
+		`,
+	},
+	{
+		Version: 17,
+		Name:    "conversation_turns",
+		// Raw conversation turns as posted by the capture hook, with the seed
+		// and agent context derived from each. agent_contexts is partitioned
+		// and keyed by (id, created_at), so agent_context_id has no foreign key.
+		Up: `
+			CREATE TABLE conversation_turns (
+				id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+				namespace VARCHAR(64) NOT NULL DEFAULT 'default',
+				agent_id VARCHAR(255) NOT NULL,
+				session_id TEXT NOT NULL DEFAULT '',
+				user_message TEXT NOT NULL DEFAULT '',
+				assistant_response TEXT NOT NULL DEFAULT '',
+				tool_calls JSONB NOT NULL DEFAULT '[]',
+				seed_id UUID REFERENCES seeds(id) ON DELETE SET NULL,
+				agent_context_id UUID,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+			);
+			CREATE INDEX conversation_turns_session_idx ON conversation_turns (namespace, agent_id, session_id, created_at);
+		`,
+		Down: `
+			DROP TABLE IF EXISTS conversation_turns;
+		`,
+	},
+	{
+		Version: 18,
+		Name:    "sessions",
+		// Sessions group the turns, seeds and agent contexts of one
+		// conversation. Turns captured so far are folded into sessions by
+		// their client session ID, and the seeds and contexts derived from
+		// them are attached to the same session.
+		Up: `
+			CREATE TABLE sessions (
+				id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+				namespace VARCHAR(64) NOT NULL DEFAULT 'default',
+				agent_id VARCHAR(255) NOT NULL,
+				external_id VARCHAR(255) NOT NULL DEFAULT '',
+				title TEXT NOT NULL DEFAULT '',
+				summary TEXT NOT NULL DEFAULT '',
+				turns INTEGER NOT NULL DEFAULT 0,
+				started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				last_activity_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				ended_at TIMESTAMPTZ
+			);
+			CREATE UNIQUE INDEX sessions_external_idx ON sessions (namespace, agent_id, external_id) WHERE external_id <> '';
+			CREATE INDEX sessions_namespace_started_id_idx ON sessions (namespace, started_at DESC, id DESC);
+
+			INSERT INTO sessions (namespace, agent_id, external_id, title, turns, started_at, last_activity_at)
+			SELECT namespace, agent_id, session_id,
+			       LEFT((ARRAY_AGG(user_message ORDER BY created_at))[1], 80),
+			       COUNT(*), MIN(created_at), MAX(created_at)
+			FROM conversation_turns
+			WHERE session_id <> ''
+			GROUP BY namespace, agent_id, session_id;
+
+			DROP INDEX conversation_turns_session_idx;
+			ALTER TABLE conversation_turns RENAME COLUMN session_id TO external_session_id;
+			ALTER TABLE conversation_turns ADD COLUMN session_id UUID REFERENCES sessions(id) ON DELETE SET NULL;
+			UPDATE conversation_turns t SET session_id = s.id
+			FROM sessions s
+			WHERE s.namespace = t.namespace AND s.agent_id = t.agent_id AND s.external_id = t.external_session_id;
+			ALTER TABLE conversation_turns DROP COLUMN external_session_id;
+			CREATE INDEX conversation_turns_session_idx ON conversation_turns (session_id, created_at) WHERE session_id IS NOT NULL;
+
+			ALTER TABLE seeds ADD COLUMN session_id UUID REFERENCES sessions(id) ON DELETE SET NULL;
+			ALTER TABLE agent_contexts ADD COLUMN session_id UUID REFERENCES sessions(id) ON DELETE SET NULL;
+			UPDATE seeds s SET session_id = t.session_id
+			FROM conversation_turns t
+			WHERE t.seed_id = s.id AND t.session_id IS NOT NULL;
+			UPDATE agent_contexts ac SET session_id = t.session_id
+			FROM conversation_turns t
+			WHERE t.agent_context_id = ac.id AND t.session_id IS NOT NULL;
+			CREATE INDEX seeds_session_idx ON seeds (session_id, created_at) WHERE session_id IS NOT NULL;
+			CREATE INDEX agent_contexts_session_idx ON agent_contexts (session_id, created_at) WHERE session_id IS NOT NULL;
+		`,
+		Down: `
+			DROP INDEX IF EXISTS agent_contexts_session_idx;
+			DROP INDEX IF EXISTS seeds_session_idx;
+			ALTER TABLE agent_contexts DROP COLUMN IF EXISTS session_id;
+			ALTER TABLE seeds DROP COLUMN IF EXISTS session_id;
+
+			DROP INDEX IF EXISTS conversation_turns_session_idx;
+			ALTER TABLE conversation_turns ADD COLUMN external_session_id VARCHAR(255) NOT NULL DEFAULT '';
+			UPDATE conversation_turns t SET external_session_id = s.external_id
+			FROM sessions s
+			WHERE s.id = t.session_id;
+			ALTER TABLE conversation_turns DROP COLUMN session_id;
+			ALTER TABLE conversation_turns RENAME COLUMN external_session_id TO session_id;
+			CREATE INDEX conversation_turns_session_idx ON conversation_turns (namespace, agent_id, session_id, created_at);
+
+			DROP TABLE IF EXISTS sessions;
+		`,
+	},
+	{
+		Version: 19,
+		Name:    "seed_links",
+		// Typed, directed edges between seeds. demoted_from keeps the
+		// confidence a superseded seed had before its supersedes edge was
+		// added, so removing the edge can restore it.
+		Up: `
+			CREATE TABLE seed_links (
+				id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+				namespace VARCHAR(64) NOT NULL DEFAULT 'default',
+				from_id UUID NOT NULL REFERENCES seeds(id) ON DELETE CASCADE,
+				to_id UUID NOT NULL REFERENCES seeds(id) ON DELETE CASCADE,
+				type VARCHAR(32) NOT NULL,
+				weight REAL NOT NULL DEFAULT 1.0,
+				note TEXT NOT NULL DEFAULT '',
+				demoted_from REAL,
+				created_by TEXT,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+				CHECK (from_id <> to_id),
+				CHECK (weight >= 0 AND weight <= 1),
+				UNIQUE (from_id, to_id, type)
+			);
+			CREATE INDEX seed_links_to_idx ON seed_links (to_id, type);
+		`,
+		Down: `
+			DROP TABLE IF EXISTS seed_links;
+		`,
+	},
+	{
+		Version: 20,
+		Name:    "cosine_hnsw_indexes",
+		// Similarity is cosine everywhere, so the HNSW indexes must be built
+		// with cosine ops for ORDER BY embedding <=> $1 to use them.
+		Up: `
+			DROP INDEX IF EXISTS seeds_embedding_idx;
+			CREATE INDEX seeds_embedding_idx ON seeds USING hnsw (embedding vector_cosine_ops);
+			DROP INDEX IF EXISTS seed_chunks_embedding_idx;
+			CREATE INDEX seed_chunks_embedding_idx ON seed_chunks USING hnsw (embedding vector_cosine_ops);
+			DROP INDEX IF EXISTS agent_contexts_embedding_idx;
+			CREATE INDEX agent_contexts_embedding_idx ON agent_contexts USING hnsw (embedding vector_cosine_ops);
+		`,
+		Down: `
+			DROP INDEX IF EXISTS seeds_embedding_idx;
+			CREATE INDEX seeds_embedding_idx ON seeds USING hnsw (embedding vector_l2_ops);
+			DROP INDEX IF EXISTS seed_chunks_embedding_idx;
+			CREATE INDEX seed_chunks_embedding_idx ON seed_chunks USING hnsw (embedding vector_l2_ops);
+			DROP INDEX IF EXISTS agent_contexts_embedding_idx;
+			CREATE INDEX agent_contexts_embedding_idx ON agent_contexts USING hnsw (embedding vector_l2_ops);
+		`,
+	},
+	{
+		Version: 21,
+		Name:    "seed_feedback_outlives_seeds",
+		// Feedback is kept for tuning thresholds, and the seeds judged
+		// outdated or incorrect are the ones most likely to be purged, so
+		// events keep the ID of a deleted seed instead of going with it.
+		Up: `
+			ALTER TABLE seed_feedback DROP CONSTRAINT IF EXISTS seed_feedback_seed_id_fkey;
+		`,
+		Down: `
+			DELETE FROM seed_feedback f WHERE NOT EXISTS (SELECT 1 FROM seeds s WHERE s.id = f.seed_id);
+			ALTER TABLE seed_feedback ADD CONSTRAINT seed_feedback_seed_id_fkey
+				FOREIGN KEY (seed_id) REFERENCES seeds(id) ON DELETE CASCADE;
+		`,
+	},
+}
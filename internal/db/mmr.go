@@ -0,0 +1,215 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/lib/pq"
+	"github.com/pgvector/pgvector-go"
+)
+
+// mmrCandidateFactor is how many candidates per requested result MMR picks
+// from when MMROptions.Candidates is unset.
+const mmrCandidateFactor = 4
+
+// MMROptions configure maximal marginal relevance re-ranking. Each pick
+// maximises Lambda × relevance − (1 − Lambda) × redundancy, where relevance
+// is the candidate's score relative to the best candidate and redundancy is
+// its largest cosine similarity to a seed picked before.
+type MMROptions struct {
+	// Lambda between 0 and 1: 1 ranks purely by relevance, lower values
+	// increasingly favour seeds unlike those already picked.
+	Lambda float32
+	// Candidates is how many nearest neighbours to pick from (default
+	// four per requested result).
+	Candidates int
+}
+
+func (o MMROptions) candidates(limit int) int {
+	if o.Candidates > limit {
+		return o.Candidates
+	}
+	return limit * mmrCandidateFactor
+}
+
+// MMRExplain shows why a seed was picked.
+type MMRExplain struct {
+	// Rank is the pick order, CandidateRank the position among the
+	// candidates by score alone.
+	Rank          int `json:"rank"`
+	CandidateRank int `json:"candidate_rank"`
+	// Relevance is the score relative to the best candidate.
+	Relevance float32 `json:"relevance"`
+	// Redundancy is the cosine similarity to MostSimilarTo, the closest
+	// seed picked before; both are empty for the first pick.
+	Redundancy    float32 `json:"redundancy"`
+	MostSimilarTo string  `json:"most_similar_to,omitempty"`
+	// Score is the MMR score the seed was picked with.
+	Score float32 `json:"mmr_score"`
+}
+
+// mmrCandidate is one row of the over-fetched hits with its embedding.
+type mmrCandidate struct {
+	id        string
+	score     float32
+	semantic  sql.NullFloat64
+	lexical   sql.NullFloat64
+	passage   sql.NullString
+	embedding []float32
+	explain   MMRExplain
+}
+
+// nullVector scans a vector column that may be NULL.
+type nullVector struct {
+	vec []float32
+}
+
+func (n *nullVector) Scan(src interface{}) error {
+	if src == nil {
+		n.vec = nil
+		return nil
+	}
+	var v pgvector.Vector
+	if err := v.Scan(src); err != nil {
+		return err
+	}
+	n.vec = v.Slice()
+	return nil
+}
+
+// mmrCandidates reads the "hits" relation built by the CTEs in hits,
+// without marking anything as recalled, best first.
+func mmrCandidates(ctx context.Context, q queryer, hits string, args []interface{}) ([]mmrCandidate, error) {
+	query := fmt.Sprintf(`
+		WITH %s
+		SELECT h.id, h.score, h.semantic_score, h.lexical_score, h.passage, s.embedding
+		FROM hits h JOIN seeds s ON s.id = h.id
+		ORDER BY h.score DESC, h.id`, hits)
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch candidates: %w", err)
+	}
+	defer rows.Close()
+
+	var out []mmrCandidate
+	for rows.Next() {
+		var c mmrCandidate
+		var emb nullVector
+		if err := rows.Scan(&c.id, &c.score, &c.semantic, &c.lexical, &c.passage, &emb); err != nil {
+			return nil, err
+		}
+		c.embedding = emb.vec
+		out = append(out, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch candidates: %w", err)
+	}
+	return out, nil
+}
+
+// selectMMR greedily picks up to k candidates, which must be sorted best
+// first, filling in their explanations.
+func selectMMR(candidates []mmrCandidate, lambda float32, k int) []mmrCandidate {
+	if len(candidates) == 0 {
+		return nil
+	}
+	top := candidates[0].score
+	for i := range candidates {
+		candidates[i].explain.CandidateRank = i + 1
+		if top > 0 {
+			candidates[i].explain.Relevance = candidates[i].score / top
+		}
+	}
+
+	// redundancy[i] and closest[i] track the most similar pick so far.
+	redundancy := make([]float32, len(candidates))
+	closest := make([]string, len(candidates))
+	taken := make([]bool, len(candidates))
+	var picked []mmrCandidate
+	for len(picked) < k && len(picked) < len(candidates) {
+		best, bestScore := -1, float32(0)
+		for i, c := range candidates {
+			if taken[i] {
+				continue
+			}
+			score := lambda*c.explain.Relevance - (1-lambda)*redundancy[i]
+			if best < 0 || score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+
+		taken[best] = true
+		c := candidates[best]
+		c.explain.Rank = len(picked) + 1
+		c.explain.Redundancy = redundancy[best]
+		c.explain.MostSimilarTo = closest[best]
+		c.explain.Score = bestScore
+		picked = append(picked, c)
+
+		for i := range candidates {
+			if taken[i] {
+				continue
+			}
+			if sim := cosineSimilarity(candidates[i].embedding, c.embedding); sim > redundancy[i] {
+				redundancy[i], closest[i] = sim, c.id
+			}
+		}
+	}
+	return picked
+}
+
+// cosineSimilarity returns 0 when either vector is missing or zero.
+func cosineSimilarity(a, b []float32) float32 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return float32(dot / math.Sqrt(na*nb))
+}
+
+// pickedHitsCTE returns a "hits" relation holding exactly the picked
+// candidates, and its parameters.
+func pickedHitsCTE(picked []mmrCandidate) (string, []interface{}) {
+	ids := make([]string, len(picked))
+	scores := make([]float64, len(picked))
+	semantic := make([]sql.NullFloat64, len(picked))
+	lexical := make([]sql.NullFloat64, len(picked))
+	passages := make([]sql.NullString, len(picked))
+	for i, c := range picked {
+		ids[i], scores[i] = c.id, float64(c.score)
+		semantic[i], lexical[i], passages[i] = c.semantic, c.lexical, c.passage
+	}
+	cte := `
+		hits AS (
+			SELECT * FROM unnest($1::uuid[], $2::real[], $3::real[], $4::real[], $5::text[])
+			    AS h(id, score, semantic_score, lexical_score, passage)
+		)`
+	return cte, []interface{}{pq.Array(ids), pq.Array(scores), pq.Array(semantic), pq.Array(lexical), pq.Array(passages)}
+}
+
+// orderByMMR attaches the explanations of picked to results and puts them
+// in pick order.
+func orderByMMR(results []SeedSearchResult, picked []mmrCandidate) {
+	explain := make(map[string]MMRExplain, len(picked))
+	for _, c := range picked {
+		explain[c.id] = c.explain
+	}
+	for i := range results {
+		e := explain[results[i].ID]
+		results[i].MMR = &e
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].MMR.Rank < results[j].MMR.Rank
+	})
+}
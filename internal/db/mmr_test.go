@@ -0,0 +1,103 @@
+package db
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestCosineSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float32
+		want float32
+	}{
+		{"identical", []float32{1, 2, 3}, []float32{1, 2, 3}, 1},
+		{"scaled", []float32{1, 0}, []float32{5, 0}, 1},
+		{"orthogonal", []float32{1, 0}, []float32{0, 1}, 0},
+		{"opposite", []float32{1, 1}, []float32{-1, -1}, -1},
+		{"missing", nil, []float32{1}, 0},
+		{"length mismatch", []float32{1, 0}, []float32{1, 0, 0}, 0},
+		{"zero vector", []float32{0, 0}, []float32{1, 0}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cosineSimilarity(tt.a, tt.b); math.Abs(float64(got-tt.want)) > 1e-6 {
+				t.Errorf("cosineSimilarity = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectMMR(t *testing.T) {
+	// a and b are near-identical; c is less relevant but unlike both.
+	candidates := func() []mmrCandidate {
+		return []mmrCandidate{
+			{id: "a", score: 0.9, embedding: []float32{1, 0, 0}},
+			{id: "b", score: 0.88, embedding: []float32{0.99, 0.1, 0}},
+			{id: "c", score: 0.6, embedding: []float32{0, 0, 1}},
+		}
+	}
+	tests := []struct {
+		name   string
+		in     []mmrCandidate
+		lambda float32
+		k      int
+		want   string
+	}{
+		{"empty", nil, 0.5, 3, ""},
+		{"pure relevance", candidates(), 1, 3, "a,b,c"},
+		{"diversified", candidates(), 0.5, 3, "a,c,b"},
+		{"k limits picks", candidates(), 0.5, 2, "a,c"},
+		{"k beyond candidates", candidates(), 0.5, 10, "a,c,b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			picked := selectMMR(tt.in, tt.lambda, tt.k)
+			got := make([]string, len(picked))
+			for i, c := range picked {
+				got[i] = c.id
+				if c.explain.Rank != i+1 {
+					t.Errorf("%s rank = %d, want %d", c.id, c.explain.Rank, i+1)
+				}
+			}
+			if s := strings.Join(got, ","); s != tt.want {
+				t.Errorf("picked %q, want %q", s, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectMMRExplain(t *testing.T) {
+	picked := selectMMR([]mmrCandidate{
+		{id: "a", score: 0.8, embedding: []float32{1, 0}},
+		{id: "b", score: 0.4, embedding: []float32{1, 0}},
+	}, 0.5, 2)
+	first, second := picked[0].explain, picked[1].explain
+	if first.MostSimilarTo != "" || first.Redundancy != 0 || first.Relevance != 1 {
+		t.Errorf("first pick explain = %+v", first)
+	}
+	if second.CandidateRank != 2 || second.MostSimilarTo != "a" || second.Redundancy != 1 || second.Relevance != 0.5 {
+		t.Errorf("second pick explain = %+v", second)
+	}
+	if want := float32(0.5*0.5 - 0.5*1); second.Score != want {
+		t.Errorf("second pick score = %v, want %v", second.Score, want)
+	}
+}
+
+func TestMMROptionsCandidates(t *testing.T) {
+	tests := []struct {
+		opts  MMROptions
+		limit int
+		want  int
+	}{
+		{MMROptions{}, 10, 40},
+		{MMROptions{Candidates: 5}, 10, 40},
+		{MMROptions{Candidates: 100}, 10, 100},
+	}
+	for _, tt := range tests {
+		if got := tt.opts.candidates(tt.limit); got != tt.want {
+			t.Errorf("%+v.candidates(%d) = %d, want %d", tt.opts, tt.limit, got, tt.want)
+		}
+	}
+}
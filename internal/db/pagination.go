@@ -0,0 +1,163 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Sort keys for listing. Agent contexts only sort by SortCreatedAt.
+const (
+	SortCreatedAt    = "created_at"
+	SortLastAccessed = "last_accessed"
+	SortConfidence   = "confidence"
+	SortTitle        = "title"
+)
+
+// Sort orders.
+const (
+	OrderDesc = "desc"
+	OrderAsc  = "asc"
+)
+
+// MaxPageLimit caps how many rows one page may hold.
+const MaxPageLimit = 1000
+
+// ErrInvalidPage wraps errors caused by a bad cursor, sort or order.
+var ErrInvalidPage = errors.New("invalid page")
+
+// PageOptions selects one page of a listing. Cursor is the opaque value
+// returned with the previous page; it carries its own sort and order, which
+// then take precedence over Sort and Order.
+type PageOptions struct {
+	Limit  int
+	Cursor string
+	Sort   string
+	Order  string
+}
+
+// pageCursor is the position after the last row of a page: the row's sort
+// value (as text) and its id as tie-breaker.
+type pageCursor struct {
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+// sortKey is the SQL expression behind a sort and the type its cursor value
+// is cast to.
+type sortKey struct {
+	expr string
+	cast string
+}
+
+var seedSortKeys = map[string]sortKey{
+	SortCreatedAt:    {"created_at", "timestamptz"},
+	SortLastAccessed: {"COALESCE(last_accessed, created_at)", "timestamptz"},
+	SortConfidence:   {"confidence", "real"},
+	SortTitle:        {"title", "text"},
+}
+
+var agentContextSortKeys = map[string]sortKey{
+	SortCreatedAt: {"created_at", "timestamptz"},
+}
+
+// page is a validated PageOptions.
+type page struct {
+	limit  int
+	sort   string
+	order  string
+	key    sortKey
+	cursor *pageCursor
+}
+
+func (p PageOptions) resolve(keys map[string]sortKey) (page, error) {
+	pg := page{limit: p.Limit, sort: p.Sort, order: p.Order}
+	if p.Cursor != "" {
+		var c pageCursor
+		if err := decodeCursor(p.Cursor, &c); err != nil || c.ID == "" {
+			return page{}, fmt.Errorf("%w: malformed cursor", ErrInvalidPage)
+		}
+		pg.cursor, pg.sort, pg.order = &c, c.Sort, c.Order
+	}
+	if pg.limit <= 0 {
+		pg.limit = 50
+	}
+	if pg.limit > MaxPageLimit {
+		pg.limit = MaxPageLimit
+	}
+	if pg.sort == "" {
+		pg.sort = SortCreatedAt
+	}
+	if pg.order == "" {
+		pg.order = OrderDesc
+	}
+	key, ok := keys[pg.sort]
+	if !ok {
+		return page{}, fmt.Errorf("%w: unknown sort %q", ErrInvalidPage, pg.sort)
+	}
+	if pg.order != OrderDesc && pg.order != OrderAsc {
+		return page{}, fmt.Errorf("%w: order must be asc or desc", ErrInvalidPage)
+	}
+	pg.key = key
+	return pg, nil
+}
+
+// where adds the keyset condition for the cursor to f.
+func (pg page) where(f *seedFilter, args []interface{}) []interface{} {
+	if pg.cursor == nil {
+		return args
+	}
+	op := "<"
+	if pg.order == OrderAsc {
+		op = ">"
+	}
+	args = append(args, pg.cursor.Value, pg.cursor.ID)
+	f.conds = append(f.conds, fmt.Sprintf("(%s, id) %s ($%d::%s, $%d::uuid)", pg.key.expr, op, len(args)-1, pg.key.cast, len(args)))
+	return args
+}
+
+// orderBy returns the ORDER BY and LIMIT clause, fetching one extra row to
+// tell whether another page follows.
+func (pg page) orderBy(args []interface{}) (string, []interface{}) {
+	args = append(args, pg.limit+1)
+	return fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT $%d", pg.key.expr, pg.order, pg.order, len(args)), args
+}
+
+// next encodes the cursor following a row with the given sort value and id.
+func (pg page) next(value, id string) string {
+	return encodeCursor(pageCursor{Sort: pg.sort, Order: pg.order, Value: value, ID: id})
+}
+
+// encodeCursor renders a cursor struct as the opaque string handed to
+// clients.
+func encodeCursor(v interface{}) string {
+	raw, _ := json.Marshal(v)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeCursor reads a cursor produced by encodeCursor into v.
+func decodeCursor(s string, v interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// seedSortValue renders s's value for the page's sort as cursor text.
+func (pg page) seedSortValue(s *Seed) string {
+	switch pg.sort {
+	case SortLastAccessed:
+		return s.LastAccessed.Format(time.RFC3339Nano)
+	case SortConfidence:
+		return strconv.FormatFloat(float64(s.Confidence), 'g', -1, 32)
+	case SortTitle:
+		return s.Title
+	}
+	return s.CreatedAt.Format(time.RFC3339Nano)
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPageResolve(t *testing.T) {
+	cursor := page{sort: SortTitle, order: OrderAsc}.next("alpha", "0b6c7a52-4a5e-4f57-9c2f-1f1d5c3e9a10")
+
+	tests := []struct {
+		name      string
+		opts      PageOptions
+		keys      map[string]sortKey
+		wantLimit int
+		wantSort  string
+		wantOrder string
+		wantErr   bool
+	}{
+		{"defaults", PageOptions{}, seedSortKeys, 50, SortCreatedAt, OrderDesc, false},
+		{"limit capped", PageOptions{Limit: 5000}, seedSortKeys, MaxPageLimit, SortCreatedAt, OrderDesc, false},
+		{"explicit sort", PageOptions{Limit: 10, Sort: SortConfidence, Order: OrderAsc}, seedSortKeys, 10, SortConfidence, OrderAsc, false},
+		{"cursor overrides sort", PageOptions{Cursor: cursor, Sort: SortConfidence, Order: OrderDesc}, seedSortKeys, 50, SortTitle, OrderAsc, false},
+		{"unknown sort", PageOptions{Sort: "id"}, seedSortKeys, 0, "", "", true},
+		{"sort not allowed for contexts", PageOptions{Sort: SortTitle}, agentContextSortKeys, 0, "", "", true},
+		{"bad order", PageOptions{Order: "up"}, seedSortKeys, 0, "", "", true},
+		{"cursor not base64", PageOptions{Cursor: "!!"}, seedSortKeys, 0, "", "", true},
+		{"cursor not json", PageOptions{Cursor: "bm9wZQ"}, seedSortKeys, 0, "", "", true},
+		{"cursor without id", PageOptions{Cursor: page{sort: SortTitle, order: OrderAsc}.next("alpha", "")}, seedSortKeys, 0, "", "", true},
+		{"cursor with unknown sort", PageOptions{Cursor: page{sort: "id", order: OrderAsc}.next("1", "x")}, seedSortKeys, 0, "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pg, err := tt.opts.resolve(tt.keys)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidPage) {
+					t.Fatalf("err = %v, want ErrInvalidPage", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pg.limit != tt.wantLimit || pg.sort != tt.wantSort || pg.order != tt.wantOrder {
+				t.Errorf("page = %d/%s/%s, want %d/%s/%s", pg.limit, pg.sort, pg.order, tt.wantLimit, tt.wantSort, tt.wantOrder)
+			}
+			if pg.key != tt.keys[tt.wantSort] {
+				t.Errorf("key = %+v, want %+v", pg.key, tt.keys[tt.wantSort])
+			}
+		})
+	}
+}
+
+func TestPageCursorRoundTrip(t *testing.T) {
+	pg, err := PageOptions{Sort: SortConfidence, Order: OrderAsc}.resolve(seedSortKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := PageOptions{Cursor: pg.next("0.75", "row-1")}.resolve(seedSortKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.sort != SortConfidence || next.order != OrderAsc {
+		t.Errorf("cursor page = %s/%s, want confidence/asc", next.sort, next.order)
+	}
+	if c := next.cursor; c == nil || c.Value != "0.75" || c.ID != "row-1" {
+		t.Errorf("cursor = %+v", c)
+	}
+
+	f := &seedFilter{}
+	args := next.where(f, []interface{}{"ns"})
+	if len(args) != 3 || args[1] != "0.75" || args[2] != "row-1" {
+		t.Errorf("args = %v", args)
+	}
+	if want := "(confidence, id) > ($2::real, $3::uuid)"; len(f.conds) != 1 || f.conds[0] != want {
+		t.Errorf("conds = %q, want %q", f.conds, want)
+	}
+	clause, args := next.orderBy(args)
+	if want := " ORDER BY confidence asc, id asc LIMIT $4"; clause != want {
+		t.Errorf("orderBy = %q, want %q", clause, want)
+	}
+	if args[3] != 51 {
+		t.Errorf("limit arg = %v, want 51", args[3])
+	}
+}
@@ -0,0 +1,130 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Reinforcement curves applied when search returns a seed.
+const (
+	ReinforceNone   = "none"
+	ReinforceFixed  = "fixed"
+	ReinforceSpaced = "spaced"
+)
+
+// ReinforcementOptions control how much a seed's confidence grows each time
+// it is recalled. The gain is Boost × (1 − confidence), so it shrinks as
+// confidence approaches Ceiling. With the spaced curve the gain is further
+// scaled by the time since the last access relative to Spacing, capped at
+// 1: a seed recalled again after a long gap gains more than one recalled
+// twice in a row, as in spaced repetition.
+type ReinforcementOptions struct {
+	Curve   string
+	Boost   float32
+	Spacing time.Duration
+	Ceiling float32
+}
+
+// Validate reports unknown curves and out-of-range parameters.
+func (o ReinforcementOptions) Validate() error {
+	switch o.Curve {
+	case "", ReinforceNone:
+		return nil
+	case ReinforceFixed, ReinforceSpaced:
+	default:
+		return fmt.Errorf("unknown reinforcement curve %q", o.Curve)
+	}
+	if o.Boost < 0 || o.Boost > 1 || o.Ceiling <= 0 || o.Ceiling > 1 {
+		return fmt.Errorf("boost and ceiling must be between 0.0 and 1.0")
+	}
+	if o.Curve == ReinforceSpaced && o.Spacing <= 0 {
+		return fmt.Errorf("spaced reinforcement needs a positive spacing")
+	}
+	return nil
+}
+
+// sql returns the reinforced confidence of seeds row s and its arguments,
+// numbered from paramIdx.
+func (o ReinforcementOptions) sql(paramIdx int) (string, []interface{}) {
+	var gain string
+	args := []interface{}{o.Boost, o.Ceiling}
+	switch o.Curve {
+	case ReinforceFixed:
+		gain = "1"
+	case ReinforceSpaced:
+		gain = fmt.Sprintf("LEAST(1, EXTRACT(EPOCH FROM NOW() - COALESCE(s.last_accessed, s.created_at))::float8 / $%d::float8)", paramIdx+2)
+		args = append(args, o.Spacing.Seconds())
+	default:
+		return "s.confidence", nil
+	}
+	return fmt.Sprintf("GREATEST(s.confidence, LEAST($%[2]d::real, s.confidence + $%[1]d::real * %[3]s * (1 - s.confidence)))", paramIdx, paramIdx+1, gain), args
+}
+
+// RecalledSeed is a search hit that was used, with the score it was found
+// with.
+type RecalledSeed struct {
+	ID    string
+	Score float32
+}
+
+// RecallSeeds marks seeds of namespace as recalled exactly as SearchSeeds
+// marks its hits. It is meant for hits found with SkipRecall of which only
+// some were used, e.g. those that fit into a recall block.
+func (db *DB) RecallSeeds(ctx context.Context, namespace string, seeds []RecalledSeed, queryText string, reinforcement ReinforcementOptions) error {
+	if len(seeds) == 0 {
+		return nil
+	}
+	ids := make([]string, len(seeds))
+	scores := make([]float64, len(seeds))
+	for i, s := range seeds {
+		ids[i], scores[i] = s.ID, float64(s.Score)
+	}
+	hits := `
+		hits AS (
+			SELECT h.id, h.score, NULL::real AS semantic_score, NULL::real AS lexical_score, NULL::text AS passage
+			FROM unnest($1::uuid[], $2::real[]) AS h(id, score)
+			JOIN seeds sd ON sd.id = h.id
+			WHERE sd.namespace = $3 AND sd.deleted_at IS NULL
+		)`
+	args := []interface{}{pq.Array(ids), pq.Array(scores), namespace}
+	_, err := recallHits(ctx, db.DB, hits, args, SeedSearchOptions{QueryText: queryText, Reinforcement: reinforcement})
+	return err
+}
+
+// SeedRecall is one appearance of a seed in search results.
+type SeedRecall struct {
+	RecalledAt       time.Time `json:"recalled_at"`
+	Score            float32   `json:"score"`
+	ConfidenceBefore float32   `json:"confidence_before"`
+	ConfidenceAfter  float32   `json:"confidence_after"`
+	Query            string    `json:"query,omitempty"`
+}
+
+// SeedRecalls returns the recall history of a seed, newest first.
+func (db *DB) SeedRecalls(ctx context.Context, namespace, id string, limit int) ([]SeedRecall, error) {
+	query := `
+		SELECT recalled_at, score, confidence_before, confidence_after, COALESCE(query, '')
+		FROM seed_recalls
+		WHERE seed_id = $1 AND namespace = $2
+		ORDER BY recalled_at DESC
+		LIMIT $3
+	`
+	rows, err := db.QueryContext(ctx, query, id, namespace, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list seed recalls: %w", err)
+	}
+	defer rows.Close()
+
+	var recalls []SeedRecall
+	for rows.Next() {
+		var r SeedRecall
+		if err := rows.Scan(&r.RecalledAt, &r.Score, &r.ConfidenceBefore, &r.ConfidenceAfter, &r.Query); err != nil {
+			return nil, err
+		}
+		recalls = append(recalls, r)
+	}
+	return recalls, rows.Err()
+}
@@ -0,0 +1,316 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/pgvector/pgvector-go"
+)
+
+// Re-embed job statuses.
+const (
+	ReembedRunning   = "running"
+	ReembedCompleted = "completed"
+	ReembedFailed    = "failed"
+)
+
+type ReembedJob struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	TargetModel string     `json:"target_model"`
+	TargetDims  int        `json:"target_dims"`
+	Processed   int        `json:"processed"`
+	Total       int        `json:"total"`
+	Error       string     `json:"error,omitempty"`
+	StartedAt   time.Time  `json:"started_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	FinishedAt  *time.Time `json:"finished_at"`
+}
+
+// ReembedTarget describes one table whose embedding column can be rebuilt.
+// TextExpr must produce the same text the API embeds for that table; for
+// agent contexts the API embeds metadata in this same jsonb rendering.
+type ReembedTarget struct {
+	Table     string
+	TextExpr  string
+	IndexName string
+	IndexOps  string
+}
+
+var ReembedTargets = []ReembedTarget{
+	{Table: "seeds", TextExpr: "content", IndexName: "seeds_embedding_idx", IndexOps: "vector_cosine_ops"},
+	{Table: "agent_contexts", TextExpr: "COALESCE(NULLIF(summary, ''), metadata::text, type)", IndexName: "agent_contexts_embedding_idx", IndexOps: "vector_cosine_ops"},
+	{Table: "seed_chunks", TextExpr: "content", IndexName: "seed_chunks_embedding_idx", IndexOps: "vector_cosine_ops"},
+}
+
+// ShadowRow is a row still waiting for its new embedding.
+type ShadowRow struct {
+	ID   string
+	Text string
+}
+
+// EmbeddingColumnDimensions returns the declared size of table.embedding.
+func (db *DB) EmbeddingColumnDimensions(ctx context.Context, table string) (int, error) {
+	var dims int
+	query := `SELECT atttypmod FROM pg_attribute WHERE attrelid = $1::regclass AND attname = 'embedding'`
+	if err := db.QueryRowContext(ctx, query, table).Scan(&dims); err != nil {
+		return 0, fmt.Errorf("failed to read embedding dimensions of %s: %w", table, err)
+	}
+	return dims, nil
+}
+
+// StoredEmbeddingModel returns the model of the most recently embedded seed,
+// or "" if no seed records one.
+func (db *DB) StoredEmbeddingModel(ctx context.Context) (string, error) {
+	var model sql.NullString
+	err := db.QueryRowContext(ctx, `SELECT embedding_model FROM seeds WHERE embedding_model IS NOT NULL ORDER BY created_at DESC LIMIT 1`).Scan(&model)
+	if err != nil && err != sql.ErrNoRows {
+		return "", fmt.Errorf("failed to read stored embedding model: %w", err)
+	}
+	return model.String, nil
+}
+
+// CreateReembedJob registers a new running job. Jobs left running by a
+// crashed process are failed first; a job still making progress blocks the
+// new one via the reembed_jobs_running_idx unique index.
+func (db *DB) CreateReembedJob(ctx context.Context, model string, dims int) (*ReembedJob, error) {
+	_, err := db.ExecContext(ctx, `
+		UPDATE reembed_jobs
+		SET status = 'failed', error = 'interrupted', finished_at = NOW()
+		WHERE status = 'running' AND updated_at < NOW() - INTERVAL '10 minutes'
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire stale re-embed jobs: %w", err)
+	}
+
+	job := &ReembedJob{Status: ReembedRunning, TargetModel: model, TargetDims: dims}
+	query := `
+		INSERT INTO reembed_jobs (status, target_model, target_dims)
+		VALUES ($1, $2, $3)
+		RETURNING id, started_at, updated_at
+	`
+	if err := db.QueryRowContext(ctx, query, job.Status, model, dims).Scan(&job.ID, &job.StartedAt, &job.UpdatedAt); err != nil {
+		return nil, fmt.Errorf("failed to create re-embed job (is one already running?): %w", err)
+	}
+	return job, nil
+}
+
+func (db *DB) UpdateReembedJob(ctx context.Context, job *ReembedJob) error {
+	query := `
+		UPDATE reembed_jobs
+		SET status = $1, processed = $2, total = $3, error = NULLIF($4, ''), updated_at = NOW(),
+		    finished_at = CASE WHEN $1 = 'running' THEN NULL ELSE NOW() END
+		WHERE id = $5
+		RETURNING updated_at, finished_at
+	`
+	var finished sql.NullTime
+	err := db.QueryRowContext(ctx, query, job.Status, job.Processed, job.Total, job.Error, job.ID).Scan(&job.UpdatedAt, &finished)
+	if err != nil {
+		return fmt.Errorf("failed to update re-embed job: %w", err)
+	}
+	if finished.Valid {
+		job.FinishedAt = &finished.Time
+	}
+	return nil
+}
+
+// LatestReembedJob returns the most recent job, or nil if none ever ran.
+func (db *DB) LatestReembedJob(ctx context.Context) (*ReembedJob, error) {
+	query := `
+		SELECT id, status, target_model, target_dims, processed, total, error, started_at, updated_at, finished_at
+		FROM reembed_jobs
+		ORDER BY started_at DESC
+		LIMIT 1
+	`
+	var job ReembedJob
+	var errMsg sql.NullString
+	var finished sql.NullTime
+	err := db.QueryRowContext(ctx, query).Scan(&job.ID, &job.Status, &job.TargetModel, &job.TargetDims, &job.Processed, &job.Total, &errMsg, &job.StartedAt, &job.UpdatedAt, &finished)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load re-embed job: %w", err)
+	}
+	job.Error = errMsg.String
+	if finished.Valid {
+		job.FinishedAt = &finished.Time
+	}
+	return &job, nil
+}
+
+// PrepareShadowColumn (re)creates t.Table.embedding_next with the target size
+// and installs the trigger that invalidates it when the live embedding
+// changes underneath the job.
+func (db *DB) PrepareShadowColumn(ctx context.Context, t ReembedTarget, dims int) error {
+	stmts := []string{
+		fmt.Sprintf(`ALTER TABLE %s DROP COLUMN IF EXISTS embedding_next`, t.Table),
+		fmt.Sprintf(`ALTER TABLE %s ADD COLUMN embedding_next vector(%d)`, t.Table, dims),
+		fmt.Sprintf(`DROP TRIGGER IF EXISTS %[1]s_reset_embedding_next ON %[1]s`, t.Table),
+		fmt.Sprintf(`CREATE TRIGGER %[1]s_reset_embedding_next BEFORE UPDATE ON %[1]s FOR EACH ROW EXECUTE FUNCTION reset_embedding_next()`, t.Table),
+	}
+	for _, q := range stmts {
+		if _, err := db.ExecContext(ctx, q); err != nil {
+			return fmt.Errorf("failed to prepare shadow column on %s: %w", t.Table, err)
+		}
+	}
+	return nil
+}
+
+// CountShadowRows returns how many rows of t need a new embedding in total.
+func (db *DB) CountShadowRows(ctx context.Context, t ReembedTarget) (int, error) {
+	var n int
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE embedding IS NOT NULL`, t.Table)
+	if err := db.QueryRowContext(ctx, query).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count %s: %w", t.Table, err)
+	}
+	return n, nil
+}
+
+// PendingShadowRows returns up to limit rows whose shadow embedding is missing.
+func (db *DB) PendingShadowRows(ctx context.Context, t ReembedTarget, limit int) ([]ShadowRow, error) {
+	return pendingShadowRows(ctx, db.DB, t, limit)
+}
+
+// WriteShadowEmbeddings stores new embeddings in the shadow column.
+func (db *DB) WriteShadowEmbeddings(ctx context.Context, t ReembedTarget, rows []ShadowRow, embs [][]float32) error {
+	return writeShadowEmbeddings(ctx, db.DB, t, rows, embs)
+}
+
+// CreateShadowIndex builds the HNSW index for the shadow column ahead of the
+// swap, so the swap itself only renames.
+func (db *DB) CreateShadowIndex(ctx context.Context, t ReembedTarget) error {
+	query := fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_next ON %s USING hnsw (embedding_next %s)`, t.IndexName, t.Table, t.IndexOps)
+	if _, err := db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to index shadow column on %s: %w", t.Table, err)
+	}
+	return nil
+}
+
+// SwapShadowColumns atomically replaces every target's embedding column with
+// its shadow column. Writes are blocked for the duration; rows written since
+// the last batch are embedded with embed inside the transaction so nothing is
+// lost.
+func (db *DB) SwapShadowColumns(ctx context.Context, targets []ReembedTarget, model string, dims int, embed func([]string) ([][]float32, error)) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, t := range targets {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`LOCK TABLE %s IN SHARE ROW EXCLUSIVE MODE`, t.Table)); err != nil {
+			return fmt.Errorf("failed to lock %s: %w", t.Table, err)
+		}
+
+		rows, err := pendingShadowRows(ctx, tx, t, -1)
+		if err != nil {
+			return err
+		}
+		if len(rows) > 0 {
+			texts := make([]string, len(rows))
+			for i, r := range rows {
+				texts[i] = r.Text
+			}
+			embs, err := embed(texts)
+			if err != nil {
+				return fmt.Errorf("failed to embed remaining %s rows: %w", t.Table, err)
+			}
+			if err := writeShadowEmbeddings(ctx, tx, t, rows, embs); err != nil {
+				return err
+			}
+		}
+
+		stmts := []string{
+			fmt.Sprintf(`DROP TRIGGER IF EXISTS %[1]s_reset_embedding_next ON %[1]s`, t.Table),
+			fmt.Sprintf(`ALTER TABLE %s DROP COLUMN embedding`, t.Table),
+			fmt.Sprintf(`ALTER TABLE %s RENAME COLUMN embedding_next TO embedding`, t.Table),
+			fmt.Sprintf(`ALTER INDEX %[1]s_next RENAME TO %[1]s`, t.IndexName),
+		}
+		for _, q := range stmts {
+			if _, err := tx.ExecContext(ctx, q); err != nil {
+				return fmt.Errorf("failed to swap embedding column on %s: %w", t.Table, err)
+			}
+		}
+
+		query := fmt.Sprintf(`UPDATE %s SET embedding_model = $1, embedding_dims = $2 WHERE embedding IS NOT NULL`, t.Table)
+		if _, err := tx.ExecContext(ctx, query, model, dims); err != nil {
+			return fmt.Errorf("failed to record embedding model on %s: %w", t.Table, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// StaleEmbeddingRows returns up to limit rows of t embedded by a model other
+// than model.
+func (db *DB) StaleEmbeddingRows(ctx context.Context, t ReembedTarget, model string, limit int) ([]ShadowRow, error) {
+	query := fmt.Sprintf(`SELECT id, %s FROM %s WHERE embedding IS NOT NULL AND embedding_model IS DISTINCT FROM $1 ORDER BY id LIMIT %d`, t.TextExpr, t.Table, limit)
+	rows, err := db.QueryContext(ctx, query, model)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load stale %s rows: %w", t.Table, err)
+	}
+	defer rows.Close()
+
+	var out []ShadowRow
+	for rows.Next() {
+		var r ShadowRow
+		if err := rows.Scan(&r.ID, &r.Text); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// WriteEmbeddings replaces the live embeddings of rows with embs made by
+// model. Rows rewritten with model in the meantime are left alone.
+func (db *DB) WriteEmbeddings(ctx context.Context, t ReembedTarget, rows []ShadowRow, embs [][]float32, model string, dims int) error {
+	query := fmt.Sprintf(`UPDATE %s SET embedding = $1, embedding_model = $2, embedding_dims = $3 WHERE id = $4 AND embedding_model IS DISTINCT FROM $2`, t.Table)
+	for i, r := range rows {
+		if _, err := db.ExecContext(ctx, query, pgvector.NewVector(embs[i]), model, dims, r.ID); err != nil {
+			return fmt.Errorf("failed to write embedding for %s %s: %w", t.Table, r.ID, err)
+		}
+	}
+	return nil
+}
+
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func pendingShadowRows(ctx context.Context, q queryer, t ReembedTarget, limit int) ([]ShadowRow, error) {
+	query := fmt.Sprintf(`SELECT id, %s FROM %s WHERE embedding IS NOT NULL AND embedding_next IS NULL ORDER BY id`, t.TextExpr, t.Table)
+	if limit > 0 {
+		query += fmt.Sprintf(` LIMIT %d`, limit)
+	}
+	rows, err := q.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pending %s rows: %w", t.Table, err)
+	}
+	defer rows.Close()
+
+	var out []ShadowRow
+	for rows.Next() {
+		var r ShadowRow
+		if err := rows.Scan(&r.ID, &r.Text); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+func writeShadowEmbeddings(ctx context.Context, q queryer, t ReembedTarget, rows []ShadowRow, embs [][]float32) error {
+	query := fmt.Sprintf(`UPDATE %s SET embedding_next = $1 WHERE id = $2`, t.Table)
+	for i, r := range rows {
+		if _, err := q.ExecContext(ctx, query, pgvector.NewVector(embs[i]), r.ID); err != nil {
+			return fmt.Errorf("failed to write shadow embedding for %s %s: %w", t.Table, r.ID, err)
+		}
+	}
+	return nil
+}
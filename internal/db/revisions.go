@@ -0,0 +1,177 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pgvector/pgvector-go"
+)
+
+// Kinds of change recorded in seed_revisions. Recall reinforcement and
+// scheduled decay touch many seeds on every search or run, so their
+// revisions are coalesced (see coalescedRevisionsQuery); the individual
+// steps are in seed_recalls and decay_runs.
+const (
+	ChangeUpdate     = "update"
+	ChangeConfidence = "confidence"
+	ChangeProtect    = "protect"
+	ChangeRevert     = "revert"
+	ChangeMerge      = "merge"
+	ChangeFeedback   = "feedback"
+	ChangeSupersede  = "supersede"
+	ChangeReinforce  = "reinforce"
+	ChangeDecay      = "decay"
+)
+
+// SeedRevision is a seed as it was just before a change. Change, Actor and
+// CreatedAt describe the change that replaced it.
+type SeedRevision struct {
+	SeedID         string          `json:"seed_id"`
+	Revision       int             `json:"revision"`
+	Change         string          `json:"change"`
+	Content        string          `json:"content"`
+	Title          string          `json:"title"`
+	Type           string          `json:"type"`
+	Confidence     float32         `json:"confidence"`
+	Protected      bool            `json:"protected"`
+	Tags           []string        `json:"tags"`
+	Metadata       json.RawMessage `json:"metadata,omitempty"`
+	EmbeddingModel string          `json:"embedding_model,omitempty"`
+	Actor          string          `json:"actor,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+const revisionColumns = `seed_id, revision, change, content, title, type, confidence, protected, tags, metadata,
+	COALESCE(embedding_model, ''), COALESCE(actor, ''), created_at`
+
+func scanRevision(row rowScanner) (SeedRevision, error) {
+	var r SeedRevision
+	var metadata []byte
+	err := row.Scan(&r.SeedID, &r.Revision, &r.Change, &r.Content, &r.Title, &r.Type, &r.Confidence, &r.Protected, pq.Array(&r.Tags), &metadata,
+		&r.EmbeddingModel, &r.Actor, &r.CreatedAt)
+	r.Metadata = metadata
+	return r, err
+}
+
+// snapshotSeed records the current state of a seed as its next revision. The
+// seed row stays locked until tx ends so concurrent changes cannot claim the
+// same revision number.
+func snapshotSeed(ctx context.Context, tx *sql.Tx, namespace, id, change, actor string) error {
+	var locked string
+	err := tx.QueryRowContext(ctx, `SELECT id FROM seeds WHERE id = $1 AND namespace = $2 AND deleted_at IS NULL FOR UPDATE`, id, namespace).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("seed not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock seed: %w", err)
+	}
+	return recordRevision(ctx, tx, namespace, id, change, actor)
+}
+
+// recordRevision records the current state of a seed the caller has locked,
+// trashed or not, as its next revision.
+func recordRevision(ctx context.Context, tx *sql.Tx, namespace, id, change, actor string) error {
+	query := `
+		INSERT INTO seed_revisions (seed_id, namespace, revision, change, content, title, type, confidence, protected, tags, metadata, embedding_model, actor)
+		SELECT s.id, s.namespace,
+		       COALESCE((SELECT MAX(revision) FROM seed_revisions WHERE seed_id = s.id), 0) + 1,
+		       $3, s.content, s.title, s.type, s.confidence, s.protected, s.tags, s.metadata, s.embedding_model, NULLIF($4, '')
+		FROM seeds s
+		WHERE s.id = $1 AND s.namespace = $2
+	`
+	if _, err := tx.ExecContext(ctx, query, id, namespace, change, actor); err != nil {
+		return fmt.Errorf("failed to record seed revision: %w", err)
+	}
+	return nil
+}
+
+// coalescedRevisionsQuery returns an INSERT recording a change revision of
+// each seed in the id column of rel. It must run in the statement that
+// changes the seeds, so that it still reads their earlier state. Seeds whose
+// latest revision is already of that change are skipped: a run of
+// reinforcements, or of decay, is one revision holding the seed as it was
+// before the run. A concurrent statement that claimed the same revision
+// number wins, which coalesces as well.
+func coalescedRevisionsQuery(rel, change string) string {
+	return fmt.Sprintf(`
+		INSERT INTO seed_revisions (seed_id, namespace, revision, change, content, title, type, confidence, protected, tags, metadata, embedding_model)
+		SELECT s.id, s.namespace, COALESCE(latest.revision, 0) + 1, '%[2]s', s.content, s.title, s.type, s.confidence, s.protected, s.tags, s.metadata, s.embedding_model
+		FROM %[1]s c
+		JOIN seeds s ON s.id = c.id
+		LEFT JOIN LATERAL (
+			SELECT revision, change FROM seed_revisions WHERE seed_id = s.id ORDER BY revision DESC LIMIT 1
+		) latest ON true
+		WHERE latest.change IS DISTINCT FROM '%[2]s'
+		ON CONFLICT (seed_id, revision) DO NOTHING`, rel, change)
+}
+
+// SeedHistory returns all revisions of a seed, newest first.
+func (db *DB) SeedHistory(ctx context.Context, namespace, id string) ([]SeedRevision, error) {
+	query := `SELECT ` + revisionColumns + ` FROM seed_revisions WHERE seed_id = $1 AND namespace = $2 ORDER BY revision DESC`
+	rows, err := db.QueryContext(ctx, query, id, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list seed revisions: %w", err)
+	}
+	defer rows.Close()
+
+	var revs []SeedRevision
+	for rows.Next() {
+		r, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revs = append(revs, r)
+	}
+	return revs, rows.Err()
+}
+
+// GetSeedRevision returns one revision, or nil if it does not exist.
+func (db *DB) GetSeedRevision(ctx context.Context, namespace, id string, revision int) (*SeedRevision, error) {
+	query := `SELECT ` + revisionColumns + ` FROM seed_revisions WHERE seed_id = $1 AND namespace = $2 AND revision = $3`
+	r, err := scanRevision(db.QueryRowContext(ctx, query, id, namespace, revision))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get seed revision: %w", err)
+	}
+	return &r, nil
+}
+
+// RevertSeed restores content, title, type, confidence, protection, tags and
+// metadata from rev, storing the freshly computed embedding and chunks. The
+// state being replaced is recorded as a new revision first, so a revert can
+// itself be reverted. s must carry ID, Namespace and EmbeddingModel; it is filled with
+// the restored seed.
+func (db *DB) RevertSeed(ctx context.Context, s *Seed, rev *SeedRevision, embedding []float32, chunks []SeedChunk, actor string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := snapshotSeed(ctx, tx, s.Namespace, s.ID, ChangeRevert, actor); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE seeds
+		SET content = $3, title = $4, type = $5, confidence = $6, protected = $7,
+		    embedding = $8, embedding_model = NULLIF($9, ''), embedding_dims = $10,
+		    tags = $11, metadata = $12::jsonb
+		WHERE id = $1 AND namespace = $2
+		RETURNING ` + seedColumns
+	row := tx.QueryRowContext(ctx, query, s.ID, s.Namespace, rev.Content, rev.Title, rev.Type, rev.Confidence, rev.Protected,
+		pgvector.NewVector(embedding), s.EmbeddingModel, len(embedding), seedTags(&Seed{Tags: rev.Tags}), seedMetadata(&Seed{Metadata: rev.Metadata}))
+	if err := scanSeed(row, s); err != nil {
+		return fmt.Errorf("failed to revert seed: %w", err)
+	}
+	if err := replaceChunks(ctx, tx, s, chunks); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
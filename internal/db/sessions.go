@@ -0,0 +1,366 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SortLastActivity sorts sessions by their latest turn. Sessions sort by
+// SortCreatedAt, their start, by default.
+const SortLastActivity = "last_activity"
+
+// maxSessionSummary caps a session's rolling summary in bytes; the oldest
+// lines are dropped first.
+const maxSessionSummary = 4000
+
+// Session groups the turns, seeds and agent contexts of one conversation.
+// ExternalID is the client's own session ID, if it has one.
+type Session struct {
+	ID             string     `json:"id"`
+	Namespace      string     `json:"namespace"`
+	AgentID        string     `json:"agentId"`
+	ExternalID     string     `json:"externalId,omitempty"`
+	Title          string     `json:"title"`
+	Summary        string     `json:"summary"`
+	Turns          int        `json:"turns"`
+	StartedAt      time.Time  `json:"started_at"`
+	LastActivityAt time.Time  `json:"last_activity_at"`
+	EndedAt        *time.Time `json:"ended_at,omitempty"`
+}
+
+// sessionColumns is the column list read by scanSession.
+const sessionColumns = `id, namespace, agent_id, external_id, title, summary, turns, started_at, last_activity_at, ended_at`
+
+func scanSession(row rowScanner, s *Session) error {
+	var endedAt sql.NullTime
+	if err := row.Scan(&s.ID, &s.Namespace, &s.AgentID, &s.ExternalID, &s.Title, &s.Summary, &s.Turns, &s.StartedAt, &s.LastActivityAt, &endedAt); err != nil {
+		return err
+	}
+	s.EndedAt = nil
+	if endedAt.Valid {
+		s.EndedAt = &endedAt.Time
+	}
+	return nil
+}
+
+var sessionSortKeys = map[string]sortKey{
+	SortCreatedAt:    {"started_at", "timestamptz"},
+	SortLastActivity: {"last_activity_at", "timestamptz"},
+}
+
+// CreateSession starts a session. If one with the same external ID already
+// exists for the agent, s is filled with it and created is false.
+func (db *DB) CreateSession(ctx context.Context, s *Session) (created bool, err error) {
+	if s.Namespace == "" {
+		s.Namespace = DefaultNamespace
+	}
+	query := `
+		INSERT INTO sessions (namespace, agent_id, external_id, title)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (namespace, agent_id, external_id) WHERE external_id <> '' DO NOTHING
+		RETURNING ` + sessionColumns
+	err = scanSession(db.QueryRowContext(ctx, query, s.Namespace, s.AgentID, s.ExternalID, s.Title), s)
+	if err == sql.ErrNoRows {
+		query = `SELECT ` + sessionColumns + ` FROM sessions WHERE namespace = $1 AND agent_id = $2 AND external_id = $3`
+		if err := scanSession(db.QueryRowContext(ctx, query, s.Namespace, s.AgentID, s.ExternalID), s); err != nil {
+			return false, fmt.Errorf("failed to load session: %w", err)
+		}
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create session: %w", err)
+	}
+	return true, nil
+}
+
+// GetSession finds a session by its ID or, failing that, by external ID. An
+// external ID is matched within agentID when given, else across agents, the
+// most recently active session winning. It returns nil if nothing matches.
+func (db *DB) GetSession(ctx context.Context, namespace, agentID, ref string) (*Session, error) {
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM sessions
+		WHERE namespace = $1
+		  AND (id::text = $2 OR (external_id = $2 AND external_id <> '' AND ($3 = '' OR agent_id = $3)))
+		ORDER BY id::text = $2 DESC, last_activity_at DESC
+		LIMIT 1`
+	var s Session
+	if err := scanSession(db.QueryRowContext(ctx, query, namespace, ref, agentID), &s); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	return &s, nil
+}
+
+type SessionListOptions struct {
+	Namespace string
+	AgentID   string
+	// Active, when set, keeps only open or only ended sessions.
+	Active *bool
+	PageOptions
+}
+
+// ListSessions returns one page of sessions and the cursor of the next page,
+// or "" on the last page.
+func (db *DB) ListSessions(ctx context.Context, opts SessionListOptions) ([]Session, string, error) {
+	pg, err := opts.PageOptions.resolve(sessionSortKeys)
+	if err != nil {
+		return nil, "", err
+	}
+
+	filter := seedFilter{conds: []string{"namespace = $1"}}
+	args := []interface{}{opts.Namespace}
+	if opts.AgentID != "" {
+		args = append(args, opts.AgentID)
+		filter.conds = append(filter.conds, fmt.Sprintf("agent_id = $%d", len(args)))
+	}
+	if opts.Active != nil {
+		if *opts.Active {
+			filter.conds = append(filter.conds, "ended_at IS NULL")
+		} else {
+			filter.conds = append(filter.conds, "ended_at IS NOT NULL")
+		}
+	}
+	args = pg.where(&filter, args)
+	order, args := pg.orderBy(args)
+
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE true` + filter.sql("") + order
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []Session
+	for rows.Next() {
+		var s Session
+		if err := scanSession(rows, &s); err != nil {
+			return nil, "", err
+		}
+		sessions = append(sessions, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if len(sessions) > pg.limit {
+		sessions = sessions[:pg.limit]
+		last := &sessions[pg.limit-1]
+		at := last.StartedAt
+		if pg.sort == SortLastActivity {
+			at = last.LastActivityAt
+		}
+		next = pg.next(at.Format(time.RFC3339Nano), last.ID)
+	}
+	return sessions, next, nil
+}
+
+// SessionUpdate changes the given fields of a session. Ended true ends an
+// open session, false reopens it.
+type SessionUpdate struct {
+	Title   *string
+	Summary *string
+	Ended   *bool
+}
+
+// UpdateSession applies u to the session id.
+func (db *DB) UpdateSession(ctx context.Context, namespace, id string, u SessionUpdate) (*Session, error) {
+	var ended interface{}
+	if u.Ended != nil {
+		ended = *u.Ended
+	}
+	query := `
+		UPDATE sessions
+		SET title = COALESCE($3, title),
+		    summary = COALESCE($4, summary),
+		    ended_at = CASE WHEN $5::boolean IS NULL THEN ended_at
+		                    WHEN $5 THEN COALESCE(ended_at, NOW())
+		                    ELSE NULL END
+		WHERE id = $1 AND namespace = $2
+		RETURNING ` + sessionColumns
+	var s Session
+	if err := scanSession(db.QueryRowContext(ctx, query, id, namespace, u.Title, u.Summary, ended), &s); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("session not found")
+		}
+		return nil, fmt.Errorf("failed to update session: %w", err)
+	}
+	return &s, nil
+}
+
+// SessionTurn is how a captured turn advances its session: ref is the
+// session's ID or external ID, Title names a new session and SummaryLine is
+// appended to the rolling summary.
+type SessionTurn struct {
+	Ref         string
+	Title       string
+	SummaryLine string
+}
+
+// recordSessionTurn counts a turn against the agent's session t.Ref,
+// starting the session if needed and reopening it if it had ended. It
+// returns the session ID.
+func recordSessionTurn(ctx context.Context, tx *sql.Tx, namespace, agentID string, t SessionTurn) (string, error) {
+	var id, summary string
+	err := tx.QueryRowContext(ctx, `
+		SELECT id, summary FROM sessions
+		WHERE namespace = $1 AND agent_id = $2 AND (id::text = $3 OR external_id = $3)
+		ORDER BY id::text = $3 DESC
+		LIMIT 1
+		FOR UPDATE`, namespace, agentID, t.Ref).Scan(&id, &summary)
+	if err == sql.ErrNoRows {
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO sessions (namespace, agent_id, external_id, title)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (namespace, agent_id, external_id) WHERE external_id <> '' DO UPDATE SET external_id = EXCLUDED.external_id
+			RETURNING id, summary`, namespace, agentID, t.Ref, t.Title).Scan(&id, &summary)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to find session: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE sessions
+		SET turns = turns + 1,
+		    summary = $2,
+		    title = CASE WHEN title = '' THEN $3 ELSE title END,
+		    last_activity_at = NOW(),
+		    ended_at = NULL
+		WHERE id = $1`, id, rollSummary(summary, t.SummaryLine), t.Title)
+	if err != nil {
+		return "", fmt.Errorf("failed to update session: %w", err)
+	}
+	return id, nil
+}
+
+// rollSummary appends line to summary and drops the oldest lines until it
+// fits maxSessionSummary.
+func rollSummary(summary, line string) string {
+	line = strings.TrimSpace(strings.ReplaceAll(line, "\n", " "))
+	if line == "" {
+		return summary
+	}
+	if summary != "" {
+		summary += "\n"
+	}
+	summary += "- " + line
+	for len(summary) > maxSessionSummary {
+		i := strings.IndexByte(summary, '\n')
+		if i < 0 {
+			return summary[len(summary)-maxSessionSummary:]
+		}
+		summary = summary[i+1:]
+	}
+	return summary
+}
+
+// Timeline entry kinds.
+const (
+	TimelineTurn    = "turn"
+	TimelineSeed    = "seed"
+	TimelineContext = "context"
+)
+
+// TimelineEntry is one turn, seed or agent context of a session. Text is
+// the user message of a turn, the content of a seed and the summary of a
+// context; Response is only set for turns.
+type TimelineEntry struct {
+	Kind      string    `json:"kind"`
+	ID        string    `json:"id"`
+	At        time.Time `json:"at"`
+	Title     string    `json:"title,omitempty"`
+	Type      string    `json:"type,omitempty"`
+	Text      string    `json:"text"`
+	Response  string    `json:"response,omitempty"`
+	SeedID    string    `json:"seed_id,omitempty"`
+	ContextID string    `json:"agent_context_id,omitempty"`
+}
+
+// timelineCursor is the position after the last entry of a timeline page.
+// Entries of one turn share their created_at, so the kind and ID break ties.
+type timelineCursor struct {
+	At   time.Time `json:"at"`
+	Kind string    `json:"k"`
+	ID   string    `json:"id"`
+}
+
+// timelineKindRank orders the entries of one turn: the turn, then its seed,
+// then its agent context.
+const timelineKindRank = `CASE kind WHEN 'turn' THEN 0 WHEN 'seed' THEN 1 ELSE 2 END`
+
+type TimelineOptions struct {
+	// Since, when set, skips entries from before it.
+	Since *time.Time
+	// Limit defaults to MaxPageLimit; Cursor continues a previous page.
+	Limit  int
+	Cursor string
+}
+
+// SessionTimeline lists what happened in a session in chronological order
+// and returns the cursor of the next page, or "" on the last page.
+func (db *DB) SessionTimeline(ctx context.Context, namespace, id string, opts TimelineOptions) ([]TimelineEntry, string, error) {
+	limit := opts.Limit
+	if limit <= 0 || limit > MaxPageLimit {
+		limit = MaxPageLimit
+	}
+	var since, cursorAt, cursorKind, cursorID interface{}
+	if opts.Since != nil {
+		since = *opts.Since
+	}
+	if opts.Cursor != "" {
+		var c timelineCursor
+		if err := decodeCursor(opts.Cursor, &c); err != nil || c.ID == "" || c.Kind == "" {
+			return nil, "", fmt.Errorf("%w: malformed cursor", ErrInvalidPage)
+		}
+		cursorAt, cursorKind, cursorID = c.At, c.Kind, c.ID
+	}
+
+	query := `
+		SELECT kind, id, at, title, type, text, response, seed_id, context_id FROM (
+			SELECT 'turn' AS kind, id, created_at AS at, '' AS title, '' AS type, user_message AS text, assistant_response AS response,
+			       COALESCE(seed_id::text, '') AS seed_id, COALESCE(agent_context_id::text, '') AS context_id
+			FROM conversation_turns WHERE session_id = $1 AND namespace = $2
+			UNION ALL
+			SELECT 'seed', id, created_at, title, type, content, '', '', ''
+			FROM seeds WHERE session_id = $1 AND namespace = $2 AND deleted_at IS NULL
+			UNION ALL
+			SELECT 'context', id, created_at, '', type, COALESCE(summary, ''), '', '', ''
+			FROM agent_contexts WHERE session_id = $1 AND namespace = $2
+		) t
+		WHERE ($3::timestamptz IS NULL OR at >= $3)
+		  AND ($4::timestamptz IS NULL OR (at, ` + timelineKindRank + `, id) >
+		       ($4, CASE $5::text WHEN 'turn' THEN 0 WHEN 'seed' THEN 1 ELSE 2 END, $6::uuid))
+		ORDER BY at, ` + timelineKindRank + `, id
+		LIMIT $7`
+	rows, err := db.QueryContext(ctx, query, id, namespace, since, cursorAt, cursorKind, cursorID, limit+1)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load session timeline: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []TimelineEntry
+	for rows.Next() {
+		var e TimelineEntry
+		if err := rows.Scan(&e.Kind, &e.ID, &e.At, &e.Title, &e.Type, &e.Text, &e.Response, &e.SeedID, &e.ContextID); err != nil {
+			return nil, "", err
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to load session timeline: %w", err)
+	}
+
+	var next string
+	if len(entries) > limit {
+		entries = entries[:limit]
+		last := entries[limit-1]
+		next = encodeCursor(timelineCursor{At: last.At, Kind: last.Kind, ID: last.ID})
+	}
+	return entries, next, nil
+}
@@ -2,16 +2,28 @@ package db
 
 import (
 	"context"
+	"crypto/rand"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/pgvector/pgvector-go"
 )
 
+// DefaultNamespace is used when a request does not name a namespace. Rows
+// created before namespaces existed were backfilled into it.
+const DefaultNamespace = "default"
+
+// MaxSeedTypeLength is the size of the seeds.type column.
+const MaxSeedTypeLength = 50
+
 type Seed struct {
 	ID           string    `json:"id"`
+	Namespace    string    `json:"namespace"`
 	Content      string    `json:"content"`
 	Title        string    `json:"title"`
 	Type         string    `json:"type"`
@@ -19,48 +31,351 @@ type Seed struct {
 	Protected    bool      `json:"protected"`
 	LastAccessed time.Time `json:"last_accessed"`
 	CreatedAt    time.Time `json:"created_at"`
+
+	// Tags and Metadata carry structured context (source, project, ...)
+	// that search can filter on.
+	Tags     []string        `json:"tags"`
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+
+	// EmbeddingModel is the ModelID of the embedder that produced the stored vector.
+	EmbeddingModel string `json:"embedding_model,omitempty"`
+	// DuplicateOf links a seed inserted under the "link" dedup policy to the
+	// seed it resembles.
+	DuplicateOf string `json:"duplicate_of,omitempty"`
+	// Provenance lists the near-duplicates merged into this seed.
+	Provenance json.RawMessage `json:"provenance,omitempty"`
+	// AccessCount is how often the seed has been returned by search.
+	AccessCount int `json:"access_count"`
+	// DeletedAt is set while the seed is in the trash.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy string     `json:"deleted_by,omitempty"`
+	// SessionID is the conversation session the seed was captured in.
+	SessionID string `json:"session_id,omitempty"`
+}
+
+// seedColumns is the column list read by scanSeed.
+const seedColumns = `id, namespace, content, title, type, confidence, protected, last_accessed, created_at, tags, metadata,
+	COALESCE(embedding_model, ''), COALESCE(duplicate_of::text, ''), provenance, deleted_at, COALESCE(deleted_by, ''), access_count,
+	COALESCE(session_id::text, '')`
+
+func scanSeed(row rowScanner, s *Seed) error {
+	var metadata, provenance []byte
+	var deletedAt sql.NullTime
+	if err := row.Scan(&s.ID, &s.Namespace, &s.Content, &s.Title, &s.Type, &s.Confidence, &s.Protected, &s.LastAccessed, &s.CreatedAt, pq.Array(&s.Tags), &metadata,
+		&s.EmbeddingModel, &s.DuplicateOf, &provenance, &deletedAt, &s.DeletedBy, &s.AccessCount,
+		&s.SessionID); err != nil {
+		return err
+	}
+	s.Metadata = metadata
+	s.Provenance = provenance
+	s.DeletedAt = nil
+	if deletedAt.Valid {
+		s.DeletedAt = &deletedAt.Time
+	}
+	return nil
+}
+
+// SeedFilters narrow listing and search to seeds with matching tags, types
+// or metadata.
+type SeedFilters struct {
+	// TagsAny matches seeds with at least one of the tags, TagsAll seeds
+	// with every one of them.
+	TagsAny []string
+	TagsAll []string
+	// Types matches seeds whose type is in the list.
+	Types []string
+	// Metadata matches seeds whose metadata contains this JSON object.
+	Metadata json.RawMessage
+	// SessionID keeps only seeds of the session, ExcludeSessionID drops
+	// them.
+	SessionID        string
+	ExcludeSessionID string
+}
+
+// appendTo adds the filters to f, numbering parameters after args.
+func (sf SeedFilters) appendTo(f *seedFilter, args []interface{}) []interface{} {
+	add := func(cond string, v interface{}) {
+		args = append(args, v)
+		f.conds = append(f.conds, fmt.Sprintf(cond, len(args)))
+	}
+	if len(sf.TagsAny) > 0 {
+		add("tags && $%d", pq.Array(sf.TagsAny))
+	}
+	if len(sf.TagsAll) > 0 {
+		add("tags @> $%d", pq.Array(sf.TagsAll))
+	}
+	if len(sf.Types) > 0 {
+		add("type = ANY($%d)", pq.Array(sf.Types))
+	}
+	if len(sf.Metadata) > 0 {
+		add("metadata @> $%d::jsonb", string(sf.Metadata))
+	}
+	if sf.SessionID != "" {
+		add("session_id = $%d::uuid", sf.SessionID)
+	}
+	if sf.ExcludeSessionID != "" {
+		add("session_id IS DISTINCT FROM $%d::uuid", sf.ExcludeSessionID)
+	}
+	return args
+}
+
+type SeedListOptions struct {
+	Namespace string
+	SeedFilters
+	// Protected, when set, keeps only protected or unprotected seeds.
+	Protected *bool
+	// MinConfidence and MaxConfidence bound confidence inclusively.
+	MinConfidence *float32
+	MaxConfidence *float32
+	PageOptions
 }
 
-func (db *DB) ListSeeds(ctx context.Context, limit int) ([]Seed, error) {
-	query := `SELECT id, content, title, type, confidence, protected, last_accessed, created_at FROM seeds ORDER BY created_at DESC LIMIT $1`
-	rows, err := db.QueryContext(ctx, query, limit)
+// ListSeeds returns one page of live seeds and the cursor of the next page,
+// or "" on the last page.
+func (db *DB) ListSeeds(ctx context.Context, opts SeedListOptions) ([]Seed, string, error) {
+	pg, err := opts.PageOptions.resolve(seedSortKeys)
+	if err != nil {
+		return nil, "", err
+	}
+
+	filter := seedFilter{conds: []string{"namespace = $1", "deleted_at IS NULL"}}
+	args := opts.SeedFilters.appendTo(&filter, []interface{}{opts.Namespace})
+	if opts.Protected != nil {
+		args = append(args, *opts.Protected)
+		filter.conds = append(filter.conds, fmt.Sprintf("protected = $%d", len(args)))
+	}
+	if opts.MinConfidence != nil {
+		args = append(args, *opts.MinConfidence)
+		filter.conds = append(filter.conds, fmt.Sprintf("confidence >= $%d", len(args)))
+	}
+	if opts.MaxConfidence != nil {
+		args = append(args, *opts.MaxConfidence)
+		filter.conds = append(filter.conds, fmt.Sprintf("confidence <= $%d", len(args)))
+	}
+	args = pg.where(&filter, args)
+	order, args := pg.orderBy(args)
+
+	query := fmt.Sprintf(`SELECT %s FROM seeds WHERE true%s%s`, seedColumns, filter.sql(""), order)
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list seeds: %w", err)
+		return nil, "", fmt.Errorf("failed to list seeds: %w", err)
 	}
 	defer rows.Close()
 
 	var seeds []Seed
 	for rows.Next() {
 		var s Seed
-		if err := rows.Scan(&s.ID, &s.Content, &s.Title, &s.Type, &s.Confidence, &s.Protected, &s.LastAccessed, &s.CreatedAt); err != nil {
-			return nil, err
+		if err := scanSeed(rows, &s); err != nil {
+			return nil, "", err
 		}
 		seeds = append(seeds, s)
 	}
-	return seeds, nil
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if len(seeds) > pg.limit {
+		seeds = seeds[:pg.limit]
+		last := &seeds[pg.limit-1]
+		next = pg.next(pg.seedSortValue(last), last.ID)
+	}
+	return seeds, next, nil
+}
+
+// seedTags returns s.Tags, never nil, for the NOT NULL tags column.
+func seedTags(s *Seed) interface{} {
+	if s.Tags == nil {
+		return pq.Array([]string{})
+	}
+	return pq.Array(s.Tags)
+}
+
+// seedMetadata returns s.Metadata, defaulting to an empty object.
+func seedMetadata(s *Seed) string {
+	if len(s.Metadata) == 0 {
+		return "{}"
+	}
+	return string(s.Metadata)
+}
+
+// InsertSeed stores a seed and, for long content, its embedded chunks. If
+// dedup is enabled and a near-duplicate exists, the policy decides whether
+// the seed is rejected, merged into the existing one or inserted and linked.
+func (db *DB) InsertSeed(ctx context.Context, s *Seed, embedding []float32, chunks []SeedChunk, dedup DedupOptions) (InsertResult, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return InsertResult{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if dedup.enabled() {
+		if err := lockDedupScopes(ctx, tx, []*Seed{s}, dedup); err != nil {
+			return InsertResult{}, err
+		}
+	}
+	res, err := insertSeedTx(ctx, tx, s, embedding, chunks, dedup)
+	if err != nil {
+		return InsertResult{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return InsertResult{}, fmt.Errorf("failed to commit seed: %w", err)
+	}
+	return res, nil
 }
 
-func (db *DB) InsertSeed(ctx context.Context, s *Seed, embedding []float32) error {
+// insertBatchSize keeps multi-row inserts well below Postgres' 65535
+// parameter limit.
+const insertBatchSize = 500
+
+// InsertSeeds inserts many seeds in one transaction. embeddings[i] and
+// chunks[i] belong to seeds[i], as does the returned InsertResult. Every seed
+// is written under a savepoint, so one the database rejects only fails
+// itself: its result carries Err and the others are still stored. Without
+// dedup, seeds go in with multi-row INSERTs, retried one at a time when a
+// batch fails. With dedup each seed is checked against the store, including
+// seeds earlier in the same batch, and inserted one at a time.
+func (db *DB) InsertSeeds(ctx context.Context, seeds []*Seed, embeddings [][]float32, chunks [][]SeedChunk, dedup DedupOptions) ([]InsertResult, error) {
+	if len(seeds) != len(embeddings) || len(seeds) != len(chunks) {
+		return nil, fmt.Errorf("got %d seeds but %d embeddings and %d chunk sets", len(seeds), len(embeddings), len(chunks))
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	results := make([]InsertResult, len(seeds))
+	insertOne := func(i int) {
+		err := withSavepoint(ctx, tx, func() error {
+			var err error
+			results[i], err = insertSeedTx(ctx, tx, seeds[i], embeddings[i], chunks[i], dedup)
+			return err
+		})
+		if err != nil {
+			results[i] = InsertResult{Err: err}
+		}
+	}
+
+	if dedup.enabled() {
+		if err := lockDedupScopes(ctx, tx, seeds, dedup); err != nil {
+			return nil, err
+		}
+		for i := range seeds {
+			insertOne(i)
+		}
+	} else {
+		for start := 0; start < len(seeds); start += insertBatchSize {
+			end := min(start+insertBatchSize, len(seeds))
+			err := withSavepoint(ctx, tx, func() error {
+				return insertSeedRows(ctx, tx, seeds[start:end], embeddings[start:end], chunks[start:end])
+			})
+			for i := start; i < end; i++ {
+				if err != nil {
+					insertOne(i)
+				} else {
+					results[i] = InsertResult{Action: ActionCreated, SeedID: seeds[i].ID}
+				}
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit seeds: %w", err)
+	}
+	return results, nil
+}
+
+// insertSeedRows stores seeds and their chunks with one multi-row INSERT,
+// without dedup.
+func insertSeedRows(ctx context.Context, tx *sql.Tx, seeds []*Seed, embeddings [][]float32, chunks [][]SeedChunk) error {
+	byID := make(map[string]*Seed, len(seeds))
+	var values []string
+	var args []interface{}
+	for i, s := range seeds {
+		if s.Namespace == "" {
+			s.Namespace = DefaultNamespace
+		}
+		if s.Confidence <= 0 {
+			s.Confidence = 1.0
+		}
+		// IDs are generated here so RETURNING rows can be matched to inputs.
+		s.ID = newUUID()
+		byID[s.ID] = s
+
+		n := len(args)
+		values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, NULLIF($%d, ''), $%d, $%d, $%d::jsonb, NULLIF($%d, '')::uuid)",
+			n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, n+9, n+10, n+11, n+12))
+		args = append(args, s.ID, s.Namespace, s.Content, s.Title, s.Type, pgvector.NewVector(embeddings[i]), s.Confidence, s.EmbeddingModel, len(embeddings[i]), seedTags(s), seedMetadata(s), s.SessionID)
+	}
+
 	query := `
-		INSERT INTO seeds (content, title, type, embedding, confidence)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO seeds (id, namespace, content, title, type, embedding, confidence, embedding_model, embedding_dims, tags, metadata, session_id)
+		VALUES ` + strings.Join(values, ", ") + `
 		RETURNING id, created_at, last_accessed
 	`
-	vec := pgvector.NewVector(embedding)
-	if s.Confidence <= 0 {
-		s.Confidence = 1.0
-	}
-	err := db.QueryRowContext(ctx, query, s.Content, s.Title, s.Type, vec, s.Confidence).Scan(&s.ID, &s.CreatedAt, &s.LastAccessed)
+	rows, err := tx.QueryContext(ctx, query, args...)
 	if err != nil {
-		return fmt.Errorf("failed to insert seed: %w", err)
+		return fmt.Errorf("failed to insert seeds: %w", err)
+	}
+	for rows.Next() {
+		var id string
+		var createdAt, lastAccessed time.Time
+		if err := rows.Scan(&id, &createdAt, &lastAccessed); err != nil {
+			rows.Close()
+			return err
+		}
+		if s := byID[id]; s != nil {
+			s.CreatedAt, s.LastAccessed = createdAt, lastAccessed
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to insert seeds: %w", err)
+	}
+
+	for i, s := range seeds {
+		if err := insertChunks(ctx, tx, s, chunks[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withSavepoint runs fn under a savepoint of tx. When fn fails, tx is rolled
+// back to the savepoint and stays usable.
+func withSavepoint(ctx context.Context, tx *sql.Tx, fn func() error) error {
+	if _, err := tx.ExecContext(ctx, `SAVEPOINT seed_insert`); err != nil {
+		return fmt.Errorf("failed to create savepoint: %w", err)
+	}
+	if err := fn(); err != nil {
+		if _, rbErr := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT seed_insert`); rbErr != nil {
+			return fmt.Errorf("%w (rollback to savepoint failed: %v)", err, rbErr)
+		}
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT seed_insert`); err != nil {
+		return fmt.Errorf("failed to release savepoint: %w", err)
 	}
 	return nil
 }
 
-func (db *DB) DeleteSeed(ctx context.Context, id string) error {
+// newUUID returns a random (version 4) UUID.
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// DeleteSeed moves a seed to the trash. It is hidden from listing and search
+// until restored, and purged for good once the trash retention has passed.
+func (db *DB) DeleteSeed(ctx context.Context, namespace, id, actor string) error {
 	// Check if seed is protected
 	var protected bool
-	err := db.QueryRowContext(ctx, `SELECT protected FROM seeds WHERE id = $1`, id).Scan(&protected)
+	err := db.QueryRowContext(ctx, `SELECT protected FROM seeds WHERE id = $1 AND namespace = $2 AND deleted_at IS NULL`, id, namespace).Scan(&protected)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return fmt.Errorf("seed not found")
@@ -71,104 +386,330 @@ func (db *DB) DeleteSeed(ctx context.Context, id string) error {
 		return fmt.Errorf("seed is protected and cannot be deleted")
 	}
 
-	query := `DELETE FROM seeds WHERE id = $1`
-	_, err = db.ExecContext(ctx, query, id)
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE seeds SET deleted_at = NOW(), deleted_by = NULLIF($3, '') WHERE id = $1 AND namespace = $2 AND deleted_at IS NULL AND NOT protected`
+	result, err := tx.ExecContext(ctx, query, id, namespace, actor)
 	if err != nil {
 		return fmt.Errorf("failed to delete seed: %w", err)
 	}
-	return nil
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("seed not found")
+	}
+	// A trashed seed no longer supersedes anything
+	if err := settleSupersededBy(ctx, tx, namespace, id, "", actor); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (db *DB) UpdateSeed(ctx context.Context, s *Seed, embedding []float32) error {
+// UpdateSeed rewrites a seed and replaces its chunks. Nil Tags or Metadata
+// keep the stored values. The previous state is kept in seed_revisions.
+func (db *DB) UpdateSeed(ctx context.Context, s *Seed, embedding []float32, chunks []SeedChunk, actor string) error {
 	query := `
 		UPDATE seeds
-		SET content = $1, title = $2, type = $3, embedding = $4
-		WHERE id = $5
-		RETURNING created_at, confidence, last_accessed
+		SET content = $1, title = $2, type = $3, embedding = $4, embedding_model = NULLIF($7, ''), embedding_dims = $8,
+		    tags = COALESCE($9, tags), metadata = COALESCE($10::jsonb, metadata)
+		WHERE id = $5 AND namespace = $6
+		RETURNING created_at, confidence, protected, last_accessed, tags, metadata
 	`
 	vec := pgvector.NewVector(embedding)
-	err := db.QueryRowContext(ctx, query, s.Content, s.Title, s.Type, vec, s.ID).Scan(&s.CreatedAt, &s.Confidence, &s.LastAccessed)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := snapshotSeed(ctx, tx, s.Namespace, s.ID, ChangeUpdate, actor); err != nil {
+		return err
+	}
+	var metadata sql.NullString
+	if len(s.Metadata) > 0 {
+		metadata = sql.NullString{String: string(s.Metadata), Valid: true}
+	}
+	var tags interface{}
+	if s.Tags != nil {
+		tags = pq.Array(s.Tags)
+	}
+	var newMetadata []byte
+	err = tx.QueryRowContext(ctx, query, s.Content, s.Title, s.Type, vec, s.ID, s.Namespace, s.EmbeddingModel, len(embedding), tags, metadata).
+		Scan(&s.CreatedAt, &s.Confidence, &s.Protected, &s.LastAccessed, pq.Array(&s.Tags), &newMetadata)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return fmt.Errorf("seed not found")
-		}
 		return fmt.Errorf("failed to update seed: %w", err)
 	}
-	return nil
+	s.Metadata = newMetadata
+	if err := replaceChunks(ctx, tx, s, chunks); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+const setConfidenceQuery = `UPDATE seeds SET confidence = $1 WHERE id = $2 AND namespace = $3`
+
+func (db *DB) SetSeedConfidence(ctx context.Context, namespace, id string, confidence float32, actor string) error {
+	return db.setSeedField(ctx, namespace, id, ChangeConfidence, actor, setConfidenceQuery, confidence)
+}
+
+func (db *DB) SetSeedProtected(ctx context.Context, namespace, id string, protected bool, actor string) error {
+	return db.setSeedField(ctx, namespace, id, ChangeProtect, actor, `UPDATE seeds SET protected = $1 WHERE id = $2 AND namespace = $3`, protected)
 }
 
-func (db *DB) SetSeedConfidence(ctx context.Context, id string, confidence float32) error {
-	query := `UPDATE seeds SET confidence = $1 WHERE id = $2`
-	result, err := db.ExecContext(ctx, query, confidence, id)
+// setSeedField runs a single-column update after recording a revision.
+func (db *DB) setSeedField(ctx context.Context, namespace, id, change, actor, query string, value interface{}) error {
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to set confidence: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	rows, _ := result.RowsAffected()
-	if rows == 0 {
-		return fmt.Errorf("seed not found")
+	defer tx.Rollback()
+
+	if err := snapshotSeed(ctx, tx, namespace, id, change, actor); err != nil {
+		return err
 	}
-	return nil
+	if _, err := tx.ExecContext(ctx, query, value, id, namespace); err != nil {
+		return fmt.Errorf("failed to set %s: %w", change, err)
+	}
+	return tx.Commit()
 }
 
-func (db *DB) SetSeedProtected(ctx context.Context, id string, protected bool) error {
-	query := `UPDATE seeds SET protected = $1 WHERE id = $2`
-	result, err := db.ExecContext(ctx, query, protected, id)
-	if err != nil {
-		return fmt.Errorf("failed to set protected: %w", err)
+// GetSeed returns one seed, or nil if it does not exist.
+func (db *DB) GetSeed(ctx context.Context, namespace, id string) (*Seed, error) {
+	var s Seed
+	err := scanSeed(db.QueryRowContext(ctx, `SELECT `+seedColumns+` FROM seeds WHERE id = $1 AND namespace = $2`, id, namespace), &s)
+	if err == sql.ErrNoRows {
+		return nil, nil
 	}
-	rows, _ := result.RowsAffected()
-	if rows == 0 {
-		return fmt.Errorf("seed not found")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get seed: %w", err)
 	}
-	return nil
+	return &s, nil
 }
 
 type SeedSearchResult struct {
 	Seed
-	Similarity float32 `json:"similarity"`
+	Similarity    float32  `json:"similarity"`
+	SemanticScore *float32 `json:"semantic_score,omitempty"`
+	LexicalScore  *float32 `json:"lexical_score,omitempty"`
+	// Passage is the best-matching chunk of a long seed.
+	Passage string `json:"passage,omitempty"`
+	// MMR explains the pick when results are diversified.
+	MMR *MMRExplain `json:"mmr,omitempty"`
+	// Via is set on seeds added by graph expansion.
+	Via *LinkVia `json:"via,omitempty"`
+}
+
+// Search modes supported by SearchSeeds.
+const (
+	SearchModeVector = "vector"
+	SearchModeHybrid = "hybrid"
+)
+
+// rrfK is the rank offset used by reciprocal-rank fusion. 60 is the value
+// from the original RRF paper and works well without tuning.
+const rrfK = 60
+
+// candidateFactor controls how many nearest neighbours each retrieval leg
+// fetches per requested result before filtering and re-ranking.
+const candidateFactor = 4
+
+type SeedSearchOptions struct {
+	Namespace string
+	Limit     int
+	Threshold float32
+	Since     *time.Time
+	Until     *time.Time
+
+	// Mode selects pure vector search (default) or hybrid search.
+	Mode string
+	// QueryText is the raw query, used for the full-text leg in hybrid mode
+	// and kept in the recall history.
+	QueryText string
+	// SemanticWeight and LexicalWeight scale each leg's contribution to the
+	// fused RRF score in hybrid mode.
+	SemanticWeight float32
+	LexicalWeight  float32
+
+	// Reinforcement raises the confidence of returned seeds.
+	Reinforcement ReinforcementOptions
+	// SkipRecall returns the hits without marking them recalled, for callers
+	// that only use some of them and pass those to RecallSeeds.
+	SkipRecall bool
+
+	// EfSearch sets hnsw.ef_search for this search. 0 keeps pgvector's
+	// default unless the search fetches more candidates than that.
+	EfSearch int
+
+	// MMR diversifies the hits by maximal marginal relevance.
+	MMR *MMROptions
+
+	// Expand adds the linked neighbours of the hits to the results.
+	Expand *GraphExpansion
+
+	SeedFilters
+}
+
+// seedFilter collects WHERE conditions on seeds columns. Conditions start
+// with the column name so they can be rendered against a table alias.
+type seedFilter struct {
+	conds []string
+}
+
+func (f seedFilter) sql(alias string) string {
+	out := ""
+	for _, c := range f.conds {
+		out += " AND " + alias + c
+	}
+	return out
 }
 
-func (db *DB) SearchSeeds(ctx context.Context, embedding []float32, limit int, threshold float32, since *time.Time, until *time.Time) ([]SeedSearchResult, error) {
-	if limit <= 0 {
-		limit = 10
+// SearchSeeds returns the seeds closest to embedding. Every hit counts as
+// recalled: last_accessed and access_count are updated, its confidence is
+// reinforced (with a coalesced revision) and the recall is logged in
+// seed_recalls. Seeds added by
+// opts.Expand are not, and neither is any hit with opts.SkipRecall.
+//
+// With opts.MMR the hits are picked from a larger candidate set and
+// returned in pick order, followed by any expanded neighbours.
+func (db *DB) SearchSeeds(ctx context.Context, embedding []float32, opts SeedSearchOptions) ([]SeedSearchResult, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = 10
+	}
+	fetch := opts.Limit
+	if opts.MMR != nil {
+		fetch = opts.MMR.candidates(opts.Limit)
+	}
+	hits, args := seedHitsCTE(embedding, opts, fetch)
+
+	var results []SeedSearchResult
+	err := db.withEfSearch(ctx, efSearch(opts.EfSearch, fetch*candidateFactor), func(q queryer) error {
+		var picked []mmrCandidate
+		if opts.MMR != nil {
+			candidates, err := mmrCandidates(ctx, q, hits, args)
+			if err != nil {
+				return err
+			}
+			picked = selectMMR(candidates, opts.MMR.Lambda, opts.Limit)
+			hits, args = pickedHitsCTE(picked)
+		}
+
+		var err error
+		if results, err = recallHits(ctx, q, hits, args, opts); err != nil {
+			return err
+		}
+		if opts.MMR != nil {
+			orderByMMR(results, picked)
+		}
+
+		neighbours, err := expandNeighbours(ctx, q, results, opts)
+		if err != nil {
+			return err
+		}
+		// Neighbours follow the MMR picks but merge into a plain ranking.
+		results = append(results, neighbours...)
+		if opts.MMR == nil {
+			sortSearchResults(results)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return results, nil
+}
 
-	// Build dynamic WHERE clause for time filtering
-	timeFilter := ""
-	args := []interface{}{pgvector.NewVector(embedding), threshold, limit}
-	paramIdx := 4
+// seedHitsCTE builds the CTEs of a search up to the "hits" relation: at
+// most fetch seeds with their scores, best first. It returns the CTEs and
+// their parameters; $1 is the query embedding.
+func seedHitsCTE(embedding []float32, opts SeedSearchOptions, fetch int) (string, []interface{}) {
+	// Build dynamic WHERE clause for namespace and time filtering
+	filter := seedFilter{conds: []string{"namespace = $4", "deleted_at IS NULL"}}
+	args := []interface{}{pgvector.NewVector(embedding), opts.Threshold, fetch, opts.Namespace}
+	paramIdx := 5
 
-	if since != nil {
-		timeFilter += fmt.Sprintf(" AND created_at >= $%d", paramIdx)
-		args = append(args, *since)
+	if opts.Since != nil {
+		filter.conds = append(filter.conds, fmt.Sprintf("created_at >= $%d", paramIdx))
+		args = append(args, *opts.Since)
 		paramIdx++
 	}
-	if until != nil {
-		timeFilter += fmt.Sprintf(" AND created_at <= $%d", paramIdx)
-		args = append(args, *until)
+	if opts.Until != nil {
+		filter.conds = append(filter.conds, fmt.Sprintf("created_at <= $%d", paramIdx))
+		args = append(args, *opts.Until)
 		paramIdx++
 	}
 
-	// Weighted similarity: raw cosine similarity multiplied by confidence.
-	// This ensures low-confidence (decayed) seeds rank lower even if semantically close.
-	// We also update last_accessed for returned seeds.
-	query := fmt.Sprintf(`
-		WITH matched AS (
-			SELECT id, content, title, type, confidence, last_accessed, created_at,
-			       (1 - (embedding <=> $1)) * confidence AS similarity
-			FROM seeds
-			WHERE (1 - (embedding <=> $1)) * confidence >= $2%s
-			ORDER BY embedding <-> $1
+	args = opts.SeedFilters.appendTo(&filter, args)
+	paramIdx = len(args) + 1
+
+	candIdx := paramIdx
+	args = append(args, fetch*candidateFactor)
+	paramIdx++
+
+	if opts.Mode == SearchModeHybrid {
+		hits, args, _ := hybridHitsCTE(opts, filter, candIdx, args, paramIdx)
+		return hits, args
+	}
+	hits := semanticCTE(filter, candIdx) + `,
+		hits AS (
+			SELECT seed_id AS id, score, NULL::real AS semantic_score, NULL::real AS lexical_score, passage
+			FROM semantic
+			ORDER BY score DESC
 			LIMIT $3
+		)`
+	return hits, args
+}
+
+// recallHits reads the seeds of the "hits" relation built by the CTEs in
+// hits, marking each as recalled unless opts.SkipRecall is set. args holds
+// the CTEs' parameters.
+func recallHits(ctx context.Context, q queryer, hits string, args []interface{}, opts SeedSearchOptions) ([]SeedSearchResult, error) {
+	args = append(args, opts.QueryText)
+	textIdx := len(args)
+	reinforced, reinforceArgs := opts.Reinforcement.sql(textIdx + 1)
+	args = append(args, reinforceArgs...)
+
+	query := fmt.Sprintf(`
+		WITH %[1]s,
+		before AS (
+			SELECT id, confidence FROM seeds WHERE id IN (SELECT id FROM hits)
+		),
+		recalled AS (
+			UPDATE seeds s
+			SET last_accessed = NOW(),
+			    access_count = s.access_count + 1,
+			    confidence = %[2]s
+			FROM hits h
+			WHERE s.id = h.id
+			RETURNING s.id, s.namespace, s.content, s.title, s.type, s.confidence, s.protected, s.last_accessed, s.created_at, s.access_count, s.tags, s.metadata,
+			          COALESCE(s.session_id::text, ''), h.score, h.semantic_score, h.lexical_score, h.passage
+		),
+		logged AS (
+			INSERT INTO seed_recalls (seed_id, namespace, score, confidence_before, confidence_after, query)
+			SELECT r.id, r.namespace, r.score, b.confidence, r.confidence, NULLIF($%[3]d, '')
+			FROM recalled r JOIN before b ON b.id = r.id
+		),
+		reinforced AS (
+			SELECT r.id FROM recalled r JOIN before b ON b.id = r.id WHERE r.confidence <> b.confidence
+		),
+		revised AS (%[4]s
 		)
-		UPDATE seeds s
-		SET last_accessed = NOW()
-		FROM matched m
-		WHERE s.id = m.id
-		RETURNING m.id, m.content, m.title, m.type, m.confidence, m.last_accessed, m.created_at, m.similarity
-	`, timeFilter)
+		SELECT * FROM recalled
+	`, hits, reinforced, textIdx, coalescedRevisionsQuery("reinforced", ChangeReinforce))
+	if opts.SkipRecall {
+		query = fmt.Sprintf(`
+			WITH %s
+			SELECT s.id, s.namespace, s.content, s.title, s.type, s.confidence, s.protected, s.last_accessed, s.created_at, s.access_count, s.tags, s.metadata,
+			       COALESCE(s.session_id::text, ''), h.score, h.semantic_score, h.lexical_score, h.passage
+			FROM hits h
+			JOIN seeds s ON s.id = h.id
+		`, hits)
+		args = args[:textIdx-1]
+	}
 
-	rows, err := db.QueryContext(ctx, query, args...)
+	rows, err := q.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query seeds: %w", err)
 	}
@@ -177,96 +718,372 @@ func (db *DB) SearchSeeds(ctx context.Context, embedding []float32, limit int, t
 	var results []SeedSearchResult
 	for rows.Next() {
 		var res SeedSearchResult
-		if err := rows.Scan(&res.ID, &res.Content, &res.Title, &res.Type, &res.Confidence, &res.LastAccessed, &res.CreatedAt, &res.Similarity); err != nil {
+		var semantic, lexical sql.NullFloat64
+		var passage sql.NullString
+		var metadata []byte
+		if err := rows.Scan(&res.ID, &res.Namespace, &res.Content, &res.Title, &res.Type, &res.Confidence, &res.Protected, &res.LastAccessed, &res.CreatedAt, &res.AccessCount, pq.Array(&res.Tags), &metadata,
+			&res.SessionID, &res.Similarity, &semantic, &lexical, &passage); err != nil {
 			return nil, err
 		}
+		res.Metadata = metadata
+		if opts.Mode == SearchModeHybrid {
+			semScore, lexScore := float32(semantic.Float64), float32(lexical.Float64)
+			res.SemanticScore = &semScore
+			res.LexicalScore = &lexScore
+		}
+		res.Passage = passage.String
 		results = append(results, res)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query seeds: %w", err)
+	}
 	return results, nil
 }
 
+// semanticCTE returns the CTEs that produce the "semantic" relation: one row
+// per matching seed with its best distance, confidence-weighted score and,
+// when a chunk matched better than the whole seed, the passage.
+//
+// Retrieval and ranking are separate stages. seed_index_hits and chunk_hits
+// only order by cosine distance, the operator of the vector_cosine_ops HNSW
+// indexes, so each is a plain index scan returning its candidates. The
+// threshold and confidence weighting are then applied to those candidates,
+// after chunks are folded back onto their parent seed.
+//
+// The index applies the namespace, tag, metadata and other filters after
+// retrieval, so a selective filter can leave it with fewer candidates than
+// the scope holds. seed_hits then switches to an exact scan of the scope;
+// the "+ 0" keeps that scan off the index, and the one-time condition keeps
+// it from running otherwise.
+func semanticCTE(filter seedFilter, candIdx int) string {
+	return fmt.Sprintf(`
+		seed_index_hits AS (
+			SELECT id AS seed_id, embedding <=> $1 AS distance, NULL::text AS passage
+			FROM seeds
+			WHERE embedding IS NOT NULL%[1]s
+			ORDER BY embedding <=> $1
+			LIMIT $%[3]d
+		),
+		seed_underfilled AS (
+			SELECT (SELECT count(*) FROM seed_index_hits) <
+			       (SELECT count(*) FROM (SELECT 1 FROM seeds WHERE embedding IS NOT NULL%[1]s LIMIT $%[3]d) s) AS exact
+		),
+		seed_hits AS (
+			SELECT * FROM seed_index_hits
+			WHERE NOT (SELECT exact FROM seed_underfilled)
+			UNION ALL
+			(SELECT id, embedding <=> $1, NULL::text
+			 FROM seeds
+			 WHERE embedding IS NOT NULL%[1]s
+			   AND (SELECT exact FROM seed_underfilled)
+			 ORDER BY (embedding <=> $1) + 0
+			 LIMIT $%[3]d)
+		),
+		chunk_hits AS (
+			SELECT seed_id, embedding <=> $1 AS distance, content AS passage
+			FROM seed_chunks
+			WHERE namespace = $4
+			ORDER BY embedding <=> $1
+			LIMIT $%[3]d
+		),
+		semantic AS (
+			SELECT DISTINCT ON (h.seed_id) h.seed_id, h.distance, h.passage,
+			       (1 - h.distance) * sd.confidence AS score
+			FROM (SELECT * FROM seed_hits UNION ALL SELECT * FROM chunk_hits) h
+			JOIN seeds sd ON sd.id = h.seed_id
+			WHERE (1 - h.distance) * sd.confidence >= $2%[2]s
+			ORDER BY h.seed_id, h.distance
+		)`, filter.sql(""), filter.sql("sd."), candIdx)
+}
+
+// hybridHitsCTE builds a "hits" relation from a vector query and a full-text
+// query run side by side, fused with reciprocal-rank fusion. The threshold
+// only applies to the semantic leg so exact keyword hits are never filtered
+// out by a poor embedding.
+func hybridHitsCTE(opts SeedSearchOptions, filter seedFilter, candIdx int, args []interface{}, paramIdx int) (string, []interface{}, int) {
+	args = append(args, opts.QueryText, opts.SemanticWeight, opts.LexicalWeight)
+	textIdx, semIdx, lexIdx := paramIdx, paramIdx+1, paramIdx+2
+
+	cte := fmt.Sprintf(`%[1]s,
+		semantic_ranked AS (
+			SELECT seed_id AS id, score, passage, ROW_NUMBER() OVER (ORDER BY distance) AS rank
+			FROM semantic
+		),
+		lexical AS (
+			SELECT id, score, ROW_NUMBER() OVER (ORDER BY score DESC) AS rank
+			FROM (
+				SELECT id, ts_rank_cd(search_vector, q) * confidence AS score
+				FROM seeds, websearch_to_tsquery('simple', $%[3]d) q
+				WHERE search_vector @@ q%[2]s
+				ORDER BY score DESC
+				LIMIT $%[6]d
+			) c
+		),
+		hits AS (
+			SELECT COALESCE(sm.id, lx.id) AS id,
+			       COALESCE($%[4]d::real / (%[7]d + sm.rank), 0) + COALESCE($%[5]d::real / (%[7]d + lx.rank), 0) AS score,
+			       sm.score AS semantic_score,
+			       lx.score AS lexical_score,
+			       sm.passage
+			FROM semantic_ranked sm
+			FULL OUTER JOIN lexical lx ON sm.id = lx.id
+			ORDER BY score DESC
+			LIMIT $3
+		)`, semanticCTE(filter, candIdx), filter.sql(""), textIdx, semIdx, lexIdx, candIdx, rrfK)
+	return cte, args, paramIdx + 3
+}
+
+// sortSearchResults orders results by descending score. UPDATE ... RETURNING
+// does not preserve the ordering of the CTE it reads from.
+func sortSearchResults(results []SeedSearchResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Similarity > results[j].Similarity
+	})
+}
+
 type AgentContext struct {
 	ID        string          `json:"id"`
+	Namespace string          `json:"namespace"`
 	AgentID   string          `json:"agentId"`
 	Type      string          `json:"type"`
 	Metadata  json.RawMessage `json:"metadata"`
 	Summary   string          `json:"summary"`
+	Protected bool            `json:"protected"`
 	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt *time.Time      `json:"updated_at,omitempty"`
+	// SessionID is the conversation session the context belongs to.
+	SessionID string `json:"session_id,omitempty"`
+
+	EmbeddingModel string `json:"embedding_model,omitempty"`
+}
+
+// agentContextColumns is the column list read by scanAgentContext.
+const agentContextColumns = `id, namespace, agent_id, type, metadata, summary, protected, created_at, updated_at, COALESCE(embedding_model, ''),
+	COALESCE(session_id::text, '')`
+
+func scanAgentContext(row rowScanner, ac *AgentContext) error {
+	var meta []byte
+	var sum sql.NullString
+	var updatedAt sql.NullTime
+	if err := row.Scan(&ac.ID, &ac.Namespace, &ac.AgentID, &ac.Type, &meta, &sum, &ac.Protected, &ac.CreatedAt, &updatedAt, &ac.EmbeddingModel, &ac.SessionID); err != nil {
+		return err
+	}
+	if meta != nil {
+		ac.Metadata = meta
+	}
+	if sum.Valid {
+		ac.Summary = sum.String
+	}
+	if updatedAt.Valid {
+		ac.UpdatedAt = &updatedAt.Time
+	}
+	return nil
 }
 
 func (db *DB) InsertAgentContext(ctx context.Context, ac *AgentContext, embedding []float32) error {
+	return insertAgentContext(ctx, db, ac, embedding)
+}
+
+func insertAgentContext(ctx context.Context, q queryer, ac *AgentContext, embedding []float32) error {
 	query := `
-		INSERT INTO agent_contexts (agent_id, type, metadata, summary, embedding)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO agent_contexts (namespace, agent_id, type, metadata, summary, embedding, embedding_model, embedding_dims, protected, session_id)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, NULLIF($10, '')::uuid)
 		RETURNING id, created_at
 	`
 	vec := pgvector.NewVector(embedding)
+	if ac.Namespace == "" {
+		ac.Namespace = DefaultNamespace
+	}
 
 	var meta interface{} = ac.Metadata
 	if len(ac.Metadata) == 0 {
 		meta = nil
 	}
 
-	err := db.QueryRowContext(ctx, query, ac.AgentID, ac.Type, meta, ac.Summary, vec).Scan(&ac.ID, &ac.CreatedAt)
+	err := q.QueryRowContext(ctx, query, ac.Namespace, ac.AgentID, ac.Type, meta, ac.Summary, vec, ac.EmbeddingModel, len(embedding), ac.Protected, ac.SessionID).Scan(&ac.ID, &ac.CreatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to insert agent context: %w", err)
 	}
 	return nil
 }
 
-func (db *DB) GetAgentContexts(ctx context.Context, agentID string) ([]AgentContext, error) {
-	query := `SELECT id, agent_id, type, metadata, summary, created_at FROM agent_contexts`
-	var args []interface{}
+type AgentContextListOptions struct {
+	Namespace string
+	AgentID   string
+	Type      string
+	SessionID string
+	PageOptions
+}
 
-	if agentID != "" {
-		query += ` WHERE agent_id = $1`
-		args = append(args, agentID)
+// GetAgentContexts returns one page of agent contexts and the cursor of the
+// next page, or "" on the last page.
+func (db *DB) GetAgentContexts(ctx context.Context, opts AgentContextListOptions) ([]AgentContext, string, error) {
+	pg, err := opts.PageOptions.resolve(agentContextSortKeys)
+	if err != nil {
+		return nil, "", err
 	}
 
-	query += ` ORDER BY created_at DESC`
+	filter := seedFilter{conds: []string{"namespace = $1"}}
+	args := []interface{}{opts.Namespace}
+	if opts.AgentID != "" {
+		args = append(args, opts.AgentID)
+		filter.conds = append(filter.conds, fmt.Sprintf("agent_id = $%d", len(args)))
+	}
+	if opts.Type != "" {
+		args = append(args, opts.Type)
+		filter.conds = append(filter.conds, fmt.Sprintf("type = $%d", len(args)))
+	}
+	if opts.SessionID != "" {
+		args = append(args, opts.SessionID)
+		filter.conds = append(filter.conds, fmt.Sprintf("session_id = $%d::uuid", len(args)))
+	}
+	args = pg.where(&filter, args)
+	order, args := pg.orderBy(args)
 
+	query := `SELECT ` + agentContextColumns + ` FROM agent_contexts WHERE true` + filter.sql("") + order
 	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer rows.Close()
 
 	var results []AgentContext
 	for rows.Next() {
 		var ac AgentContext
-		var meta []byte
-		var sum sql.NullString
-		if err := rows.Scan(&ac.ID, &ac.AgentID, &ac.Type, &meta, &sum, &ac.CreatedAt); err != nil {
-			return nil, err
-		}
-		if meta != nil {
-			ac.Metadata = meta
-		}
-		if sum.Valid {
-			ac.Summary = sum.String
+		if err := scanAgentContext(rows, &ac); err != nil {
+			return nil, "", err
 		}
 		results = append(results, ac)
 	}
-	return results, nil
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if len(results) > pg.limit {
+		results = results[:pg.limit]
+		last := &results[pg.limit-1]
+		next = pg.next(last.CreatedAt.Format(time.RFC3339Nano), last.ID)
+	}
+	return results, next, nil
 }
 
-func (db *DB) GetAgentContextByID(ctx context.Context, id string) (*AgentContext, error) {
-	query := `SELECT id, agent_id, type, metadata, summary, created_at FROM agent_contexts WHERE id = $1`
+func (db *DB) GetAgentContextByID(ctx context.Context, namespace, id string) (*AgentContext, error) {
+	query := `SELECT ` + agentContextColumns + ` FROM agent_contexts WHERE id = $1 AND namespace = $2`
 	var ac AgentContext
-	var meta []byte
-	var sum sql.NullString
-	err := db.QueryRowContext(ctx, query, id).Scan(&ac.ID, &ac.AgentID, &ac.Type, &meta, &sum, &ac.CreatedAt)
-	if err != nil {
+	if err := scanAgentContext(db.QueryRowContext(ctx, query, id, namespace), &ac); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, err
 	}
-	if meta != nil {
-		ac.Metadata = meta
+	return &ac, nil
+}
+
+type AgentContextSearchResult struct {
+	AgentContext
+	Similarity float32 `json:"similarity"`
+}
+
+type AgentContextSearchOptions struct {
+	Namespace string
+	AgentID   string
+	Type      string
+	Limit     int
+	Threshold float32
+	Since     *time.Time
+	Until     *time.Time
+	// SessionID keeps only contexts of the session, ExcludeSessionID drops
+	// them.
+	SessionID        string
+	ExcludeSessionID string
+}
+
+func (db *DB) SearchAgentContexts(ctx context.Context, embedding []float32, opts AgentContextSearchOptions) ([]AgentContextSearchResult, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = 10
 	}
-	if sum.Valid {
-		ac.Summary = sum.String
+
+	filter := " AND namespace = $4"
+	args := []interface{}{pgvector.NewVector(embedding), opts.Threshold, opts.Limit, opts.Namespace}
+	paramIdx := 5
+
+	if opts.AgentID != "" {
+		filter += fmt.Sprintf(" AND agent_id = $%d", paramIdx)
+		args = append(args, opts.AgentID)
+		paramIdx++
 	}
-	return &ac, nil
+	if opts.Type != "" {
+		filter += fmt.Sprintf(" AND type = $%d", paramIdx)
+		args = append(args, opts.Type)
+		paramIdx++
+	}
+	if opts.Since != nil {
+		filter += fmt.Sprintf(" AND created_at >= $%d", paramIdx)
+		args = append(args, *opts.Since)
+		paramIdx++
+	}
+	if opts.Until != nil {
+		filter += fmt.Sprintf(" AND created_at <= $%d", paramIdx)
+		args = append(args, *opts.Until)
+		paramIdx++
+	}
+	if opts.SessionID != "" {
+		filter += fmt.Sprintf(" AND session_id = $%d::uuid", paramIdx)
+		args = append(args, opts.SessionID)
+		paramIdx++
+	}
+	if opts.ExcludeSessionID != "" {
+		filter += fmt.Sprintf(" AND session_id IS DISTINCT FROM $%d::uuid", paramIdx)
+		args = append(args, opts.ExcludeSessionID)
+		paramIdx++
+	}
+
+	// The nearest candidates are fetched first so the HNSW index serves the
+	// ORDER BY ... LIMIT; the threshold only filters what it returns.
+	args = append(args, opts.Limit*candidateFactor)
+	query := fmt.Sprintf(`
+		SELECT id, namespace, agent_id, type, metadata, summary, protected, created_at, session_id, similarity
+		FROM (
+			SELECT id, namespace, agent_id, type, metadata, summary, protected, created_at,
+			       COALESCE(session_id::text, '') AS session_id, 1 - (embedding <=> $1) AS similarity
+			FROM agent_contexts
+			WHERE embedding IS NOT NULL%s
+			ORDER BY embedding <=> $1
+			LIMIT $%d
+		) candidates
+		WHERE similarity >= $2
+		ORDER BY similarity DESC
+		LIMIT $3
+	`, filter, paramIdx)
+
+	var results []AgentContextSearchResult
+	err := db.withEfSearch(ctx, efSearch(0, opts.Limit*candidateFactor), func(q queryer) error {
+		rows, err := q.QueryContext(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to query agent contexts: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var res AgentContextSearchResult
+			var meta []byte
+			var sum sql.NullString
+			if err := rows.Scan(&res.ID, &res.Namespace, &res.AgentID, &res.Type, &meta, &sum, &res.Protected, &res.CreatedAt, &res.SessionID, &res.Similarity); err != nil {
+				return err
+			}
+			if meta != nil {
+				res.Metadata = meta
+			}
+			if sum.Valid {
+				res.Summary = sum.String
+			}
+			results = append(results, res)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
 }
@@ -0,0 +1,134 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ListTrash returns soft-deleted seeds, most recently deleted first.
+func (db *DB) ListTrash(ctx context.Context, namespace string, limit int) ([]Seed, error) {
+	query := `SELECT ` + seedColumns + ` FROM seeds WHERE namespace = $1 AND deleted_at IS NOT NULL ORDER BY deleted_at DESC LIMIT $2`
+	rows, err := db.QueryContext(ctx, query, namespace, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trash: %w", err)
+	}
+	defer rows.Close()
+
+	var seeds []Seed
+	for rows.Next() {
+		var s Seed
+		if err := scanSeed(rows, &s); err != nil {
+			return nil, err
+		}
+		seeds = append(seeds, s)
+	}
+	return seeds, rows.Err()
+}
+
+// RestoreSeed takes a seed out of the trash. Seeds it supersedes, and the
+// seed itself if it is superseded, are demoted again.
+func (db *DB) RestoreSeed(ctx context.Context, namespace, id, actor string) (*Seed, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE seeds SET deleted_at = NULL, deleted_by = NULL WHERE id = $1 AND namespace = $2 AND deleted_at IS NOT NULL RETURNING id`
+	var restored string
+	err = tx.QueryRowContext(ctx, query, id, namespace).Scan(&restored)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("seed not found in trash")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore seed: %w", err)
+	}
+	if err := settleSupersededBy(ctx, tx, namespace, id, "", actor); err != nil {
+		return nil, err
+	}
+	if err := settleSuperseded(ctx, tx, namespace, id, "", actor); err != nil {
+		return nil, err
+	}
+
+	var s Seed
+	err = scanSeed(tx.QueryRowContext(ctx, `SELECT `+seedColumns+` FROM seeds WHERE id = $1`, id), &s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore seed: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	return &s, nil
+}
+
+// HardDeleteSeed removes a seed, trashed or not and protected or not,
+// together with its chunks and revisions. Seeds it superseded get their
+// confidence back as if the link had been deleted.
+func (db *DB) HardDeleteSeed(ctx context.Context, namespace, id, actor string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := settleSupersededBy(ctx, tx, namespace, id, id, actor); err != nil {
+		return err
+	}
+	result, err := tx.ExecContext(ctx, `DELETE FROM seeds WHERE id = $1 AND namespace = $2`, id, namespace)
+	if err != nil {
+		return fmt.Errorf("failed to delete seed: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("seed not found")
+	}
+	return tx.Commit()
+}
+
+// PurgeTrash permanently deletes seeds that have been in the trash for longer
+// than retention, across all namespaces, and returns how many were removed.
+// Demotions still held by their supersedes links are settled first.
+func (db *DB) PurgeTrash(ctx context.Context, retention time.Duration) (int64, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT l.namespace, l.to_id FROM seed_links l JOIN seeds s ON s.id = l.from_id
+		WHERE l.type = $1 AND l.demoted_from IS NOT NULL AND s.deleted_at < NOW() - $2 * INTERVAL '1 second'`,
+		LinkSupersedes, retention.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to read supersedes links: %w", err)
+	}
+	var held [][2]string
+	for rows.Next() {
+		var ns, id string
+		if err := rows.Scan(&ns, &id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		held = append(held, [2]string{ns, id})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read supersedes links: %w", err)
+	}
+	for _, h := range held {
+		if err := settleSuperseded(ctx, tx, h[0], h[1], "", ""); err != nil {
+			return 0, err
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM seeds WHERE deleted_at < NOW() - $1 * INTERVAL '1 second'`, retention.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge trash: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
@@ -0,0 +1,141 @@
+package decay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"jarvis-memory/internal/db"
+)
+
+const day = 24 * time.Hour
+
+// DefaultPolicies roughly match the old startup decay: seeds older than 90
+// days with a confidence below 0.3 fade towards 0.01, now at a rate that
+// does not depend on how often the server restarts.
+var DefaultPolicies = map[string]db.DecayPolicy{
+	db.DecayDefaultType: {Curve: db.DecayExponential, HalfLife: 30 * day, MinAge: 90 * day, Below: 0.3, Floor: 0.01},
+}
+
+// policyConfig is the JSON form of a db.DecayPolicy.
+type policyConfig struct {
+	Curve    string   `json:"curve"`
+	HalfLife string   `json:"halfLife"`
+	PerDay   float32  `json:"perDay"`
+	MinAge   string   `json:"minAge"`
+	Below    float32  `json:"below"`
+	Floor    *float32 `json:"floor"`
+}
+
+// ParsePolicies reads per-type policies from a JSON object keyed by seed type,
+// e.g. {"episodic": {"curve": "exponential", "halfLife": "14d"},
+// "semantic": {"curve": "none"}}. The "*" key covers every other type and
+// falls back to DefaultPolicies when omitted.
+func ParsePolicies(s string) (map[string]db.DecayPolicy, error) {
+	var raw map[string]policyConfig
+	if err := json.Unmarshal([]byte(s), &raw); err != nil {
+		return nil, fmt.Errorf("invalid decay policies: %w", err)
+	}
+
+	policies := make(map[string]db.DecayPolicy, len(raw)+1)
+	for typ, pc := range raw {
+		p := db.DecayPolicy{Curve: pc.Curve, PerDay: pc.PerDay, Below: pc.Below, Floor: 0.01}
+		if pc.Floor != nil {
+			p.Floor = *pc.Floor
+		}
+		var err error
+		if p.HalfLife, err = parseDuration(pc.HalfLife); err != nil {
+			return nil, fmt.Errorf("policy %q: invalid halfLife: %w", typ, err)
+		}
+		if p.MinAge, err = parseDuration(pc.MinAge); err != nil {
+			return nil, fmt.Errorf("policy %q: invalid minAge: %w", typ, err)
+		}
+		if err := p.Validate(); err != nil {
+			return nil, fmt.Errorf("policy %q: %w", typ, err)
+		}
+		policies[typ] = p
+	}
+	if _, ok := policies[db.DecayDefaultType]; !ok {
+		policies[db.DecayDefaultType] = DefaultPolicies[db.DecayDefaultType]
+	}
+	return policies, nil
+}
+
+// parseDuration accepts Go durations plus whole days ("30d"); "" is zero.
+func parseDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * day, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// Scheduler runs decay every interval. Because each run only covers the time
+// since the previous one, a restart neither skips nor repeats decay.
+type Scheduler struct {
+	db       *db.DB
+	policies map[string]db.DecayPolicy
+	interval time.Duration
+}
+
+func NewScheduler(d *db.DB, policies map[string]db.DecayPolicy, interval time.Duration) *Scheduler {
+	return &Scheduler{db: d, policies: policies, interval: interval}
+}
+
+// Run waits until the next run is due, runs decay, and repeats until ctx is
+// cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	for {
+		wait, err := s.untilDue(ctx)
+		if err != nil {
+			log.Printf("Warning: %v", err)
+			wait = s.interval
+		}
+		if wait > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+		if _, err := s.RunOnce(ctx); err != nil {
+			log.Printf("Warning: %v", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(s.interval):
+			}
+		}
+	}
+}
+
+// RunOnce applies decay now and records the run.
+func (s *Scheduler) RunOnce(ctx context.Context) (*db.DecayRun, error) {
+	run, err := s.db.RunDecay(ctx, s.policies)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Decay applied to %d seeds (%.1f hours since last run).", run.RowsAffected, run.ElapsedSeconds/3600)
+	return run, nil
+}
+
+func (s *Scheduler) untilDue(ctx context.Context) (time.Duration, error) {
+	last, err := s.db.LastDecayRun(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if last == nil {
+		return 0, nil
+	}
+	return time.Until(last.RanAt.Add(s.interval)), nil
+}
@@ -0,0 +1,90 @@
+package decay
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"jarvis-memory/internal/db"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"30d", 30 * day, false},
+		{"0d", 0, false},
+		{"36h", 36 * time.Hour, false},
+		{"1h30m", 90 * time.Minute, false},
+		{"1.5d", 0, true},
+		{"d", 0, true},
+		{"soon", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseDuration(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseDuration(%q) = %v, %v; want %v, err=%v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParsePolicies(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    map[string]db.DecayPolicy
+		wantErr string
+	}{
+		{
+			name: "empty keeps the default",
+			in:   `{}`,
+			want: DefaultPolicies,
+		},
+		{
+			name: "per type with default floor",
+			in:   `{"episodic": {"curve": "exponential", "halfLife": "14d", "minAge": "1d"}, "semantic": {"curve": "none"}}`,
+			want: map[string]db.DecayPolicy{
+				"episodic":          {Curve: db.DecayExponential, HalfLife: 14 * day, MinAge: day, Floor: 0.01},
+				"semantic":          {Curve: db.DecayNone, Floor: 0.01},
+				db.DecayDefaultType: DefaultPolicies[db.DecayDefaultType],
+			},
+		},
+		{
+			name: "explicit default and zero floor",
+			in:   `{"*": {"curve": "linear", "perDay": 0.05, "below": 0.5, "floor": 0}}`,
+			want: map[string]db.DecayPolicy{
+				db.DecayDefaultType: {Curve: db.DecayLinear, PerDay: 0.05, Below: 0.5},
+			},
+		},
+		{name: "bad half-life", in: `{"episodic": {"curve": "exponential", "halfLife": "two weeks"}}`, wantErr: "invalid halfLife"},
+		{name: "bad min age", in: `{"episodic": {"curve": "none", "minAge": "x"}}`, wantErr: "invalid minAge"},
+		{name: "missing rate", in: `{"episodic": {"curve": "linear"}}`, wantErr: `policy "episodic"`},
+		{name: "unknown curve", in: `{"episodic": {"curve": "step"}}`, wantErr: "unknown decay curve"},
+		{name: "not an object", in: `[]`, wantErr: "invalid decay policies"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePolicies(tt.in)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want one mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d policies, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for typ, want := range tt.want {
+				if got[typ] != want {
+					t.Errorf("policy %q = %+v, want %+v", typ, got[typ], want)
+				}
+			}
+		})
+	}
+}
@@ -2,35 +2,87 @@ package embeddings
 
 import (
 	"fmt"
-	"github.com/rcarmo/gte-go/gte"
 )
 
-type Service struct {
-	model *gte.Model
+// Embedder turns text into fixed-size vectors. Implementations must be safe
+// for concurrent use.
+type Embedder interface {
+	Embed(text string) ([]float32, error)
+	EmbedBatch(texts []string) ([][]float32, error)
+	Dimensions() int
+	// ModelID identifies the model that produced a vector, e.g. "gte-small".
+	ModelID() string
 }
 
-func NewService(modelPath string) (*Service, error) {
-	model, err := gte.Load(modelPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load gte model from %s: %w", modelPath, err)
+// Backends selectable through Config.Backend.
+const (
+	BackendGTE  = "gte"
+	BackendHash = "hash"
+	BackendHTTP = "http"
+)
+
+type Config struct {
+	Backend string `json:"backend"`
+
+	// GTE backend
+	ModelPath string `json:"modelPath"`
+
+	// HTTP backend
+	URL    string `json:"url"`
+	Model  string `json:"model"`
+	APIKey string `json:"apiKey"`
+	Format string `json:"format"`
+
+	// Dimensions is required by the hash backend and optional for HTTP,
+	// which probes the endpoint when it is zero.
+	Dimensions int `json:"dimensions"`
+}
+
+// New builds the embedder selected by cfg.Backend.
+func New(cfg Config) (Embedder, error) {
+	switch cfg.Backend {
+	case "", BackendGTE:
+		return NewGTE(cfg.ModelPath)
+	case BackendHash:
+		return NewHashing(cfg.Dimensions), nil
+	case BackendHTTP:
+		return NewHTTP(cfg.URL, cfg.Model, cfg.APIKey, cfg.Format, cfg.Dimensions)
+	default:
+		return nil, fmt.Errorf("unknown embedder backend %q", cfg.Backend)
 	}
+}
 
-	return &Service{
-		model: model,
-	}, nil
+// pinner is implemented by embedders that forward to a replaceable one.
+type pinner interface {
+	Pin(fn func(Embedder) error) error
 }
 
-func (s *Service) Embed(text string) ([]float32, error) {
-	emb, err := s.model.Embed(text)
-	if err != nil {
-		return nil, fmt.Errorf("failed to embed text: %w", err)
+// Pin runs fn with the embedder e forwards to right now, or with e itself.
+// A Swappable is not swapped while fn runs, so everything fn embeds comes
+// from one model and the ModelID it reads is that model's.
+func Pin(e Embedder, fn func(Embedder) error) error {
+	if p, ok := e.(pinner); ok {
+		return p.Pin(fn)
 	}
-	return emb, nil
+	return fn(e)
+}
+
+// EmbedWithModel embeds text and returns the ID of the model that did.
+func EmbedWithModel(e Embedder, text string) ([]float32, string, error) {
+	var vec []float32
+	var model string
+	err := Pin(e, func(cur Embedder) error {
+		var err error
+		model = cur.ModelID()
+		vec, err = cur.Embed(text)
+		return err
+	})
+	return vec, model, err
 }
 
-func (s *Service) Close() {
-	if s.model != nil {
-		// gte.Model doesn't have a Close method in the struct or wait, the README says model.Close().
-		s.model.Close()
+// Close releases e's resources, such as a loaded model, if it holds any.
+func Close(e Embedder) {
+	if c, ok := e.(interface{ Close() }); ok {
+		c.Close()
 	}
 }
@@ -0,0 +1,55 @@
+package embeddings
+
+import (
+	"fmt"
+
+	"github.com/rcarmo/gte-go/gte"
+)
+
+const gteDimensions = 384
+
+// GTE runs the GTE-Small model in-process.
+type GTE struct {
+	model *gte.Model
+}
+
+func NewGTE(modelPath string) (*GTE, error) {
+	model, err := gte.Load(modelPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load gte model from %s: %w", modelPath, err)
+	}
+
+	return &GTE{
+		model: model,
+	}, nil
+}
+
+func (g *GTE) Embed(text string) ([]float32, error) {
+	emb, err := g.model.Embed(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed text: %w", err)
+	}
+	return emb, nil
+}
+
+func (g *GTE) EmbedBatch(texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		emb, err := g.Embed(text)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = emb
+	}
+	return out, nil
+}
+
+func (g *GTE) Dimensions() int { return gteDimensions }
+
+func (g *GTE) ModelID() string { return "gte-small" }
+
+func (g *GTE) Close() {
+	if g.model != nil {
+		g.model.Close()
+	}
+}
@@ -0,0 +1,70 @@
+package embeddings
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+const defaultHashDimensions = 384
+
+// Hashing is a deterministic feature-hashing embedder. It needs no model
+// weights, so it is meant for tests and development. Texts sharing words end
+// up close together, but there is no real semantic understanding.
+type Hashing struct {
+	dims int
+}
+
+func NewHashing(dims int) *Hashing {
+	if dims <= 0 {
+		dims = defaultHashDimensions
+	}
+	return &Hashing{dims: dims}
+}
+
+func (h *Hashing) Embed(text string) ([]float32, error) {
+	vec := make([]float32, h.dims)
+	tokens := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for _, tok := range tokens {
+		f := fnv.New64a()
+		f.Write([]byte(tok))
+		sum := f.Sum64()
+		// The low bits pick the bucket, one high bit picks the sign so
+		// collisions tend to cancel out instead of piling up.
+		idx := int(sum % uint64(h.dims))
+		if sum>>63 == 1 {
+			vec[idx]--
+		} else {
+			vec[idx]++
+		}
+	}
+
+	var norm float64
+	for _, v := range vec {
+		norm += float64(v) * float64(v)
+	}
+	if norm == 0 {
+		return vec, nil
+	}
+	scale := float32(1 / math.Sqrt(norm))
+	for i := range vec {
+		vec[i] *= scale
+	}
+	return vec, nil
+}
+
+func (h *Hashing) EmbedBatch(texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		out[i], _ = h.Embed(text)
+	}
+	return out, nil
+}
+
+func (h *Hashing) Dimensions() int { return h.dims }
+
+func (h *Hashing) ModelID() string { return fmt.Sprintf("hash-%d", h.dims) }
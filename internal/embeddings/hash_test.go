@@ -0,0 +1,119 @@
+package embeddings
+
+import (
+	"math"
+	"testing"
+)
+
+func dot(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
+
+func TestHashing(t *testing.T) {
+	tests := []struct {
+		name     string
+		dims     int
+		wantDims int
+		wantID   string
+	}{
+		{"default", 0, defaultHashDimensions, "hash-384"},
+		{"negative", -3, defaultHashDimensions, "hash-384"},
+		{"custom", 64, 64, "hash-64"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHashing(tt.dims)
+			if h.Dimensions() != tt.wantDims || h.ModelID() != tt.wantID {
+				t.Errorf("got %d dims and model %q, want %d and %q", h.Dimensions(), h.ModelID(), tt.wantDims, tt.wantID)
+			}
+			vec, err := h.Embed("The quick brown fox")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(vec) != tt.wantDims {
+				t.Errorf("got a %d-dimensional vector", len(vec))
+			}
+		})
+	}
+}
+
+func TestHashingEmbed(t *testing.T) {
+	h := NewHashing(256)
+	embed := func(s string) []float32 {
+		v, err := h.Embed(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	tests := []struct {
+		name   string
+		a, b   string
+		minSim float64
+		maxSim float64
+	}{
+		{"case and punctuation ignored", "Deploy on Friday!", "deploy, on friday", 1 - 1e-6, 1 + 1e-6},
+		{"shared words are close", "postgres vector search index", "postgres vector search tuning", 0.5, 1},
+		{"unrelated words are far", "postgres vector search index", "bake bread with sourdough", -0.5, 0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := embed(tt.a), embed(tt.b)
+			for _, v := range [][]float32{a, b} {
+				if n := math.Sqrt(dot(v, v)); math.Abs(n-1) > 1e-5 {
+					t.Errorf("norm = %v, want 1", n)
+				}
+			}
+			if sim := dot(a, b); sim < tt.minSim || sim > tt.maxSim {
+				t.Errorf("similarity = %v, want within [%v, %v]", sim, tt.minSim, tt.maxSim)
+			}
+		})
+	}
+
+	if v := embed("  ... "); dot(v, v) != 0 {
+		t.Error("text without words should embed to the zero vector")
+	}
+}
+
+func TestHashingEmbedBatch(t *testing.T) {
+	h := NewHashing(32)
+	texts := []string{"one", "two words", ""}
+	batch, err := h.EmbedBatch(texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batch) != len(texts) {
+		t.Fatalf("got %d vectors for %d texts", len(batch), len(texts))
+	}
+	for i, text := range texts {
+		single, _ := h.Embed(text)
+		for j := range single {
+			if single[j] != batch[i][j] {
+				t.Fatalf("batch vector %d differs from Embed(%q)", i, text)
+			}
+		}
+	}
+}
+
+func TestSwappable(t *testing.T) {
+	old, next := NewHashing(16), NewHashing(32)
+	s := NewSwappable(old)
+	if s.ModelID() != "hash-16" {
+		t.Errorf("model = %q, want hash-16", s.ModelID())
+	}
+	if prev := s.Swap(next); prev != Embedder(old) {
+		t.Errorf("Swap returned %v, want the previous embedder", prev)
+	}
+	vec, err := s.Embed("hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vec) != 32 || s.Dimensions() != 32 || s.Current() != Embedder(next) {
+		t.Errorf("swapped embedder not in use: %d dims, current %v", len(vec), s.Current())
+	}
+}
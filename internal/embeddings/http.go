@@ -0,0 +1,142 @@
+package embeddings
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Wire formats understood by the HTTP embedder.
+const (
+	FormatOpenAI = "openai"
+	FormatOllama = "ollama"
+)
+
+// HTTP calls a local embedding server speaking either the OpenAI
+// /v1/embeddings protocol (llama.cpp, vLLM, LM Studio, ...) or Ollama's
+// /api/embed protocol.
+type HTTP struct {
+	url    string
+	model  string
+	apiKey string
+	format string
+	dims   int
+	client *http.Client
+}
+
+// NewHTTP creates an HTTP embedder. If dims is zero the endpoint is probed
+// once to learn the vector size.
+func NewHTTP(url, model, apiKey, format string, dims int) (*HTTP, error) {
+	if url == "" || model == "" {
+		return nil, fmt.Errorf("http embedder needs a url and a model")
+	}
+	if format == "" {
+		format = FormatOpenAI
+	}
+	if format != FormatOpenAI && format != FormatOllama {
+		return nil, fmt.Errorf("unknown http embedder format %q", format)
+	}
+
+	h := &HTTP{
+		url:    strings.TrimRight(url, "/"),
+		model:  model,
+		apiKey: apiKey,
+		format: format,
+		dims:   dims,
+		client: &http.Client{Timeout: 60 * time.Second},
+	}
+
+	if h.dims == 0 {
+		probe, err := h.Embed("dimension probe")
+		if err != nil {
+			return nil, fmt.Errorf("failed to probe embedding endpoint: %w", err)
+		}
+		h.dims = len(probe)
+	}
+	return h, nil
+}
+
+func (h *HTTP) Embed(text string) ([]float32, error) {
+	out, err := h.EmbedBatch([]string{text})
+	if err != nil {
+		return nil, err
+	}
+	return out[0], nil
+}
+
+func (h *HTTP) EmbedBatch(texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	endpoint := h.url + "/v1/embeddings"
+	if h.format == FormatOllama {
+		endpoint = h.url + "/api/embed"
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"model": h.model, "input": texts})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+h.apiKey)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call embedding endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("embedding endpoint returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var out [][]float32
+	if h.format == FormatOllama {
+		var r struct {
+			Embeddings [][]float32 `json:"embeddings"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+			return nil, fmt.Errorf("failed to decode embedding response: %w", err)
+		}
+		out = r.Embeddings
+	} else {
+		var r struct {
+			Data []struct {
+				Index     int       `json:"index"`
+				Embedding []float32 `json:"embedding"`
+			} `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+			return nil, fmt.Errorf("failed to decode embedding response: %w", err)
+		}
+		out = make([][]float32, len(r.Data))
+		for _, d := range r.Data {
+			if d.Index < 0 || d.Index >= len(out) {
+				return nil, fmt.Errorf("embedding response has out-of-range index %d", d.Index)
+			}
+			out[d.Index] = d.Embedding
+		}
+	}
+
+	if len(out) != len(texts) {
+		return nil, fmt.Errorf("embedding endpoint returned %d vectors for %d inputs", len(out), len(texts))
+	}
+	return out, nil
+}
+
+func (h *HTTP) Dimensions() int { return h.dims }
+
+func (h *HTTP) ModelID() string { return h.model }
@@ -0,0 +1,57 @@
+package embeddings
+
+import "sync"
+
+// Swappable forwards to an underlying Embedder that can be replaced at
+// runtime, which lets a finished re-embed job switch the server to the new
+// model without a restart.
+type Swappable struct {
+	mu  sync.RWMutex
+	cur Embedder
+}
+
+func NewSwappable(e Embedder) *Swappable {
+	return &Swappable{cur: e}
+}
+
+// Current returns the embedder in use.
+func (s *Swappable) Current() Embedder {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cur
+}
+
+// Swap installs e and returns the previous embedder so the caller can
+// release it. It waits for in-flight Embed and EmbedBatch calls, so the
+// previous embedder is idle once Swap returns.
+func (s *Swappable) Swap(e Embedder) Embedder {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev := s.cur
+	s.cur = e
+	return prev
+}
+
+// Pin runs fn with the current embedder, holding off Swap until it returns.
+// fn must not call back into s.
+func (s *Swappable) Pin(fn func(Embedder) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return fn(s.cur)
+}
+
+func (s *Swappable) Embed(text string) ([]float32, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cur.Embed(text)
+}
+
+func (s *Swappable) EmbedBatch(texts []string) ([][]float32, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cur.EmbedBatch(texts)
+}
+
+func (s *Swappable) Dimensions() int { return s.Current().Dimensions() }
+
+func (s *Swappable) ModelID() string { return s.Current().ModelID() }
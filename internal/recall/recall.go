@@ -0,0 +1,247 @@
+package recall
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"jarvis-memory/internal/chunking"
+)
+
+// Hit kinds.
+const (
+	KindSeed    = "seed"
+	KindContext = "context"
+)
+
+// Block formats.
+const (
+	FormatMarkdown = "markdown"
+	FormatText     = "text"
+)
+
+// duplicateOverlap is the word overlap (Jaccard) above which two hits are
+// considered the same memory.
+const duplicateOverlap = 0.8
+
+// minTruncatedTokens is the smallest remainder of the budget worth filling
+// with a truncated hit.
+const minTruncatedTokens = 24
+
+// Hit is one recalled seed or agent context.
+type Hit struct {
+	Kind       string    `json:"kind"`
+	ID         string    `json:"id"`
+	Title      string    `json:"title,omitempty"`
+	Type       string    `json:"type"`
+	AgentID    string    `json:"agent_id,omitempty"`
+	SessionID  string    `json:"session_id,omitempty"`
+	Text       string    `json:"text"`
+	Score      float32   `json:"score"`
+	CreatedAt  time.Time `json:"created_at"`
+	Tokens     int       `json:"tokens"`
+	Truncated  bool      `json:"truncated,omitempty"`
+	words      map[string]struct{}
+	normalized string
+}
+
+// Options control how hits are assembled into a block.
+type Options struct {
+	// Budget is the maximum estimated token count of the block.
+	Budget int
+	// Diversity is the MMR lambda: 1 ranks purely by score, lower values
+	// increasingly penalise hits that overlap with ones already chosen.
+	Diversity float32
+	// Format is FormatMarkdown or FormatText.
+	Format string
+}
+
+// Result is the assembled block and the hits it contains, in block order.
+type Result struct {
+	Block      string `json:"block"`
+	Format     string `json:"format"`
+	Tokens     int    `json:"tokens"`
+	Hits       []Hit  `json:"hits"`
+	Duplicates int    `json:"duplicates"`
+	Dropped    int    `json:"dropped"`
+}
+
+// Assemble removes duplicate hits, orders the rest by maximal marginal
+// relevance and renders as many as fit into opts.Budget. The last hit that
+// does not fit whole is truncated when enough budget is left for it.
+func Assemble(hits []Hit, opts Options) Result {
+	if opts.Format != FormatText {
+		opts.Format = FormatMarkdown
+	}
+	res := Result{Format: opts.Format, Hits: []Hit{}}
+
+	unique, dups := dedupe(hits)
+	res.Duplicates = dups
+
+	var lines []string
+	used := 0
+	sep := separator(opts.Format)
+	for _, h := range diversify(unique, opts.Diversity) {
+		cost := 0
+		if len(lines) > 0 {
+			cost = chunking.EstimateTokens(sep)
+		}
+		line := render(h, opts.Format)
+		tokens := chunking.EstimateTokens(line)
+		if used+cost+tokens > opts.Budget {
+			left := opts.Budget - used - cost - (tokens - chunking.EstimateTokens(h.Text))
+			if left < minTruncatedTokens {
+				res.Dropped++
+				continue
+			}
+			h.Text, h.Truncated = truncate(h.Text, left-1), true
+			line = render(h, opts.Format)
+			tokens = chunking.EstimateTokens(line)
+			if used+cost+tokens > opts.Budget {
+				res.Dropped++
+				continue
+			}
+		}
+		h.Tokens = tokens
+		used += cost + tokens
+		lines = append(lines, line)
+		res.Hits = append(res.Hits, h)
+	}
+
+	res.Block = strings.Join(lines, sep)
+	res.Tokens = chunking.EstimateTokens(res.Block)
+	return res
+}
+
+// dedupe drops hits whose text repeats or nearly repeats a higher-scoring
+// one, e.g. a seed and the agent context written from the same turn.
+func dedupe(hits []Hit) ([]Hit, int) {
+	sorted := make([]Hit, len(hits))
+	copy(sorted, hits)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Score > sorted[j].Score })
+
+	var kept []Hit
+	seen := make(map[string]bool)
+	dups := 0
+	for _, h := range sorted {
+		h.normalized = strings.Join(strings.Fields(strings.ToLower(h.Text)), " ")
+		h.words = wordSet(h.normalized)
+		if h.normalized == "" || seen[h.Kind+h.ID] || seen[h.normalized] {
+			dups++
+			continue
+		}
+		duplicate := false
+		for _, k := range kept {
+			if overlap(h.words, k.words) >= duplicateOverlap {
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			dups++
+			continue
+		}
+		seen[h.Kind+h.ID], seen[h.normalized] = true, true
+		kept = append(kept, h)
+	}
+	return kept, dups
+}
+
+// diversify reorders hits greedily by lambda*score minus (1-lambda) times
+// the largest word overlap with any hit picked before.
+func diversify(hits []Hit, lambda float32) []Hit {
+	if lambda >= 1 || len(hits) < 3 {
+		return hits
+	}
+	rest := append([]Hit(nil), hits...)
+	out := make([]Hit, 0, len(hits))
+	for len(rest) > 0 {
+		best, bestScore := 0, float32(0)
+		for i, h := range rest {
+			var maxSim float32
+			for _, o := range out {
+				if sim := overlap(h.words, o.words); sim > maxSim {
+					maxSim = sim
+				}
+			}
+			score := lambda*h.Score - (1-lambda)*maxSim
+			if i == 0 || score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		out = append(out, rest[best])
+		rest = append(rest[:best], rest[best+1:]...)
+	}
+	return out
+}
+
+func wordSet(s string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, w := range strings.FieldsFunc(s, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		set[w] = struct{}{}
+	}
+	return set
+}
+
+// overlap is the Jaccard similarity of two word sets.
+func overlap(a, b map[string]struct{}) float32 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for w := range a {
+		if _, ok := b[w]; ok {
+			shared++
+		}
+	}
+	return float32(shared) / float32(len(a)+len(b)-shared)
+}
+
+func separator(format string) string {
+	if format == FormatText {
+		return "\n\n"
+	}
+	return "\n"
+}
+
+// render formats one hit as a markdown list item or a plain paragraph.
+func render(h Hit, format string) string {
+	label := h.Title
+	if h.Kind == KindContext {
+		label = fmt.Sprintf("%s · %s", h.AgentID, h.CreatedAt.UTC().Format("2006-01-02"))
+	}
+	text := strings.TrimSpace(h.Text)
+	if format == FormatText {
+		return fmt.Sprintf("[%s] %s: %s", h.Type, label, text)
+	}
+	return fmt.Sprintf("- **%s** _(%s)_: %s", label, h.Type, strings.ReplaceAll(text, "\n", "\n  "))
+}
+
+// truncate cuts s at a word boundary so that it estimates to at most max
+// tokens including the trailing ellipsis.
+func truncate(s string, max int) string {
+	if chunking.EstimateTokens(s) <= max {
+		return s
+	}
+	var cuts []int
+	for i, r := range s {
+		if unicode.IsSpace(r) {
+			cuts = append(cuts, i)
+		}
+	}
+	lo, hi := 0, len(cuts)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if chunking.EstimateTokens(s[:cuts[mid-1]]) < max {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	if lo == 0 {
+		return "…"
+	}
+	return strings.TrimRightFunc(s[:cuts[lo-1]], unicode.IsSpace) + " …"
+}
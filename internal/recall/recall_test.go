@@ -0,0 +1,171 @@
+package recall
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"jarvis-memory/internal/chunking"
+)
+
+func seedHit(id, title, text string, score float32) Hit {
+	return Hit{Kind: KindSeed, ID: id, Title: title, Type: "semantic", Text: text, Score: score}
+}
+
+func ids(hits []Hit) string {
+	out := make([]string, len(hits))
+	for i, h := range hits {
+		out[i] = h.ID
+	}
+	return strings.Join(out, ",")
+}
+
+func TestAssemble(t *testing.T) {
+	long := strings.Repeat("the deploy pipeline runs tests then ships containers ", 20)
+
+	tests := []struct {
+		name      string
+		hits      []Hit
+		opts      Options
+		wantIDs   string
+		wantDups  int
+		wantDrops int
+		wantTrunc string
+		wantBlock string
+	}{
+		{
+			name:    "no hits",
+			opts:    Options{Budget: 100},
+			wantIDs: "",
+		},
+		{
+			name: "ordered by score",
+			hits: []Hit{
+				seedHit("b", "B", "postgres stores the vectors", 0.6),
+				seedHit("a", "A", "paris is the capital of france", 0.9),
+			},
+			opts:      Options{Budget: 200, Diversity: 1},
+			wantIDs:   "a,b",
+			wantBlock: "- **A** _(semantic)_: paris is the capital of france\n- **B** _(semantic)_: postgres stores the vectors",
+		},
+		{
+			name: "exact and near duplicates",
+			hits: []Hit{
+				seedHit("a", "A", "The deploy runs on Fridays after review", 0.9),
+				seedHit("b", "B", "the deploy  runs on fridays after review", 0.8),
+				seedHit("c", "C", "The deploy runs on Fridays after the review", 0.7),
+				seedHit("a", "A", "The deploy runs on Fridays after review", 0.5),
+				seedHit("d", "D", "", 0.4),
+			},
+			opts:     Options{Budget: 200, Diversity: 1},
+			wantIDs:  "a",
+			wantDups: 4,
+		},
+		{
+			name: "budget drops what does not fit",
+			hits: []Hit{
+				seedHit("a", "A", "short fact one", 0.9),
+				seedHit("b", "B", long, 0.8),
+				seedHit("c", "C", "short fact two", 0.7),
+			},
+			opts:      Options{Budget: 30, Diversity: 1},
+			wantIDs:   "a,c",
+			wantDrops: 1,
+		},
+		{
+			name: "last hit truncated",
+			hits: []Hit{
+				seedHit("a", "A", "short fact one", 0.9),
+				seedHit("b", "B", long, 0.8),
+			},
+			opts:      Options{Budget: 60, Diversity: 1},
+			wantIDs:   "a,b",
+			wantTrunc: "b",
+		},
+		{
+			name: "text format",
+			hits: []Hit{
+				{Kind: KindContext, ID: "x", AgentID: "jarvis", Type: "episodic", Text: "talked about\nthe weather", Score: 0.8,
+					CreatedAt: time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)},
+			},
+			opts:      Options{Budget: 100, Format: FormatText},
+			wantIDs:   "x",
+			wantBlock: "[episodic] jarvis · 2026-03-04: talked about\nthe weather",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := Assemble(tt.hits, tt.opts)
+			if got := ids(res.Hits); got != tt.wantIDs {
+				t.Errorf("hits = %q, want %q", got, tt.wantIDs)
+			}
+			if res.Duplicates != tt.wantDups {
+				t.Errorf("duplicates = %d, want %d", res.Duplicates, tt.wantDups)
+			}
+			if res.Dropped != tt.wantDrops {
+				t.Errorf("dropped = %d, want %d", res.Dropped, tt.wantDrops)
+			}
+			if res.Tokens > tt.opts.Budget {
+				t.Errorf("block has %d tokens, budget %d", res.Tokens, tt.opts.Budget)
+			}
+			if res.Tokens != chunking.EstimateTokens(res.Block) {
+				t.Errorf("tokens = %d, block estimates to %d", res.Tokens, chunking.EstimateTokens(res.Block))
+			}
+			for _, h := range res.Hits {
+				if h.Truncated != (h.ID == tt.wantTrunc) {
+					t.Errorf("hit %s truncated = %v", h.ID, h.Truncated)
+				}
+				if h.Truncated && !strings.HasSuffix(h.Text, "…") {
+					t.Errorf("truncated hit %s lacks an ellipsis: %q", h.ID, h.Text)
+				}
+			}
+			if tt.wantBlock != "" && res.Block != tt.wantBlock {
+				t.Errorf("block = %q, want %q", res.Block, tt.wantBlock)
+			}
+			if tt.opts.Format != FormatText && res.Format != FormatMarkdown {
+				t.Errorf("format = %q, want markdown", res.Format)
+			}
+		})
+	}
+}
+
+func TestDiversify(t *testing.T) {
+	hits := []Hit{
+		{ID: "a", Score: 0.9, words: wordSet("go memory service recall")},
+		{ID: "b", Score: 0.85, words: wordSet("go memory service recall search")},
+		{ID: "c", Score: 0.8, words: wordSet("paris france capital")},
+	}
+	tests := []struct {
+		lambda float32
+		want   string
+	}{
+		{1, "a,b,c"},
+		{0.5, "a,c,b"},
+	}
+	for _, tt := range tests {
+		if got := ids(diversify(hits, tt.lambda)); got != tt.want {
+			t.Errorf("diversify(lambda=%v) = %q, want %q", tt.lambda, got, tt.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		in   string
+		max  int
+		want string
+	}{
+		{"fits as is", 10, "fits as is"},
+		{"one two three four five six", 4, "one two three …"},
+		{"unbreakable", 0, "…"},
+	}
+	for _, tt := range tests {
+		got := truncate(tt.in, tt.max)
+		if got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.in, tt.max, got, tt.want)
+		}
+		if chunking.EstimateTokens(got) > max(tt.max, 1) {
+			t.Errorf("truncate(%q, %d) = %q estimates to %d tokens", tt.in, tt.max, got, chunking.EstimateTokens(got))
+		}
+	}
+}
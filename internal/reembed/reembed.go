@@ -0,0 +1,199 @@
+package reembed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"jarvis-memory/internal/db"
+	"jarvis-memory/internal/embeddings"
+)
+
+const batchSize = 64
+
+var (
+	// ErrRunning is returned by Start while another job is in progress.
+	ErrRunning = errors.New("a re-embed job is already running")
+	// ErrUnknownTarget is returned by Start for a target not configured on
+	// the server.
+	ErrUnknownTarget = errors.New("unknown re-embed target")
+)
+
+// Runner re-embeds seeds and agent contexts with a new model in the
+// background. Vectors go to a shadow column while the server keeps serving
+// from the live one; when every row is done the columns are swapped in a
+// single transaction and the server's embedder is switched over.
+type Runner struct {
+	db      *db.DB
+	current *embeddings.Swappable
+	// targets are the embedders a job may switch to, by name. Only the
+	// server configures them.
+	targets map[string]embeddings.Config
+
+	mu      sync.Mutex
+	running bool
+}
+
+func NewRunner(d *db.DB, current *embeddings.Swappable, targets map[string]embeddings.Config) *Runner {
+	return &Runner{db: d, current: current, targets: targets}
+}
+
+// Targets returns the names of the configured targets, sorted.
+func (r *Runner) Targets() []string {
+	names := make([]string, 0, len(r.targets))
+	for name := range r.targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Start builds the embedder of the named target, registers a job and runs it
+// in the background.
+func (r *Runner) Start(ctx context.Context, name string) (*db.ReembedJob, error) {
+	cfg, ok := r.targets[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q (configured: %s)", ErrUnknownTarget, name, strings.Join(r.Targets(), ", "))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running {
+		return nil, ErrRunning
+	}
+
+	target, err := embeddings.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build re-embed target %q: %w", name, err)
+	}
+	job, err := r.db.CreateReembedJob(ctx, target.ModelID(), target.Dimensions())
+	if err != nil {
+		embeddings.Close(target)
+		return nil, err
+	}
+
+	r.running = true
+	go r.run(job, target)
+	return job, nil
+}
+
+func (r *Runner) run(job *db.ReembedJob, target embeddings.Embedder) {
+	ctx := context.Background()
+	defer func() {
+		r.mu.Lock()
+		r.running = false
+		r.mu.Unlock()
+	}()
+
+	if err := r.reembed(ctx, job, target); err != nil {
+		log.Printf("Re-embed job %s failed: %v", job.ID, err)
+		job.Status = db.ReembedFailed
+		job.Error = err.Error()
+		embeddings.Close(target)
+	} else {
+		log.Printf("Re-embed job %s completed: %d rows now use %s.", job.ID, job.Processed, job.TargetModel)
+		job.Status = db.ReembedCompleted
+	}
+	if err := r.db.UpdateReembedJob(ctx, job); err != nil {
+		log.Printf("Warning: failed to record re-embed job result: %v", err)
+	}
+}
+
+func (r *Runner) reembed(ctx context.Context, job *db.ReembedJob, target embeddings.Embedder) error {
+	for _, t := range db.ReembedTargets {
+		if err := r.db.PrepareShadowColumn(ctx, t, target.Dimensions()); err != nil {
+			return err
+		}
+		n, err := r.db.CountShadowRows(ctx, t)
+		if err != nil {
+			return err
+		}
+		job.Total += n
+	}
+	if err := r.db.UpdateReembedJob(ctx, job); err != nil {
+		return err
+	}
+
+	for _, t := range db.ReembedTargets {
+		for {
+			rows, err := r.db.PendingShadowRows(ctx, t, batchSize)
+			if err != nil {
+				return err
+			}
+			if len(rows) == 0 {
+				break
+			}
+
+			texts := make([]string, len(rows))
+			for i, row := range rows {
+				texts[i] = row.Text
+			}
+			embs, err := target.EmbedBatch(texts)
+			if err != nil {
+				return fmt.Errorf("failed to embed batch: %w", err)
+			}
+			if err := r.db.WriteShadowEmbeddings(ctx, t, rows, embs); err != nil {
+				return err
+			}
+
+			job.Processed += len(rows)
+			if err := r.db.UpdateReembedJob(ctx, job); err != nil {
+				return err
+			}
+		}
+
+		if err := r.db.CreateShadowIndex(ctx, t); err != nil {
+			return err
+		}
+	}
+
+	if err := r.db.SwapShadowColumns(ctx, db.ReembedTargets, target.ModelID(), target.Dimensions(), target.EmbedBatch); err != nil {
+		return err
+	}
+
+	// Writes embedded before this swap still carry the old model, and record
+	// it, so they are picked up once the server embeds with the target. The
+	// target is live from here on, so failures no longer fail the job.
+	embeddings.Close(r.current.Swap(target))
+	if n, err := r.catchUp(ctx, target); err != nil {
+		log.Printf("Warning: re-embed job %s could not catch up on rows written during the swap: %v", job.ID, err)
+	} else if n > 0 {
+		log.Printf("Re-embed job %s caught up on %d rows written during the swap.", job.ID, n)
+	}
+	return nil
+}
+
+// catchUp re-embeds rows whose embedding_model is not the target's and
+// returns how many it found.
+func (r *Runner) catchUp(ctx context.Context, target embeddings.Embedder) (int, error) {
+	model, dims := target.ModelID(), target.Dimensions()
+	total := 0
+	for _, t := range db.ReembedTargets {
+		for {
+			rows, err := r.db.StaleEmbeddingRows(ctx, t, model, batchSize)
+			if err != nil {
+				return total, err
+			}
+			if len(rows) == 0 {
+				break
+			}
+			texts := make([]string, len(rows))
+			for i, row := range rows {
+				texts[i] = row.Text
+			}
+			embs, err := target.EmbedBatch(texts)
+			if err != nil {
+				return total, fmt.Errorf("failed to embed batch: %w", err)
+			}
+			if err := r.db.WriteEmbeddings(ctx, t, rows, embs, model, dims); err != nil {
+				return total, err
+			}
+			total += len(rows)
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,199 @@
+package retention
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"jarvis-memory/internal/db"
+	"jarvis-memory/internal/embeddings"
+)
+
+const day = 24 * time.Hour
+
+// partitionsAhead is how many months of partitions are created in advance.
+const partitionsAhead = 2
+
+// rollupBatch is how many groups are rolled up per query.
+const rollupBatch = 200
+
+// maxRollupSummary caps the length of a rollup's summary in bytes.
+const maxRollupSummary = 8000
+
+// DefaultPolicies keep episodic contexts, which the post-tool-use hook writes
+// on every turn, for 30 days and roll them up afterwards. Everything else is
+// kept.
+var DefaultPolicies = []db.ContextRetentionPolicy{
+	{AgentID: db.RetentionAny, Type: "episodic", Keep: 30 * day, Rollup: true},
+	{AgentID: db.RetentionAny, Type: db.RetentionAny},
+}
+
+// policyConfig is the JSON form of a db.ContextRetentionPolicy.
+type policyConfig struct {
+	AgentID  string `json:"agentId"`
+	Type     string `json:"type"`
+	KeepDays int    `json:"keepDays"`
+	Rollup   bool   `json:"rollup"`
+}
+
+// ParsePolicies reads policies from a JSON array, e.g.
+// [{"type": "episodic", "keepDays": 30, "rollup": true},
+// {"agentId": "ci-bot", "keepDays": 7}, {"type": "decision"}].
+// Omitted agentId and type match everything; keepDays 0 keeps forever.
+func ParsePolicies(s string) ([]db.ContextRetentionPolicy, error) {
+	var raw []policyConfig
+	if err := json.Unmarshal([]byte(s), &raw); err != nil {
+		return nil, fmt.Errorf("invalid retention policies: %w", err)
+	}
+
+	policies := make([]db.ContextRetentionPolicy, 0, len(raw))
+	for i, pc := range raw {
+		p := db.ContextRetentionPolicy{
+			AgentID: pc.AgentID,
+			Type:    pc.Type,
+			Keep:    time.Duration(pc.KeepDays) * day,
+			Rollup:  pc.Rollup,
+		}
+		if p.AgentID == "" {
+			p.AgentID = db.RetentionAny
+		}
+		if p.Type == "" {
+			p.Type = db.RetentionAny
+		}
+		if err := p.Validate(); err != nil {
+			return nil, fmt.Errorf("policy %d: %w", i, err)
+		}
+		policies = append(policies, p)
+	}
+	return policies, nil
+}
+
+// Report describes one maintenance run.
+type Report struct {
+	RanAt             time.Time `json:"ran_at"`
+	PartitionsCreated []string  `json:"partitions_created"`
+	PartitionsDropped []string  `json:"partitions_dropped"`
+	Rollups           int       `json:"rollups"`
+	RolledUp          int64     `json:"rolled_up"`
+	Deleted           int64     `json:"deleted"`
+	Skipped           bool      `json:"skipped,omitempty"`
+}
+
+// Maintainer keeps agent_contexts in shape: it creates upcoming monthly
+// partitions, rolls up and deletes expired contexts, and drops emptied
+// partitions. It runs once at start and then on every tick.
+type Maintainer struct {
+	db       *db.DB
+	emb      embeddings.Embedder
+	policies []db.ContextRetentionPolicy
+	interval time.Duration
+}
+
+func NewMaintainer(d *db.DB, emb embeddings.Embedder, policies []db.ContextRetentionPolicy, interval time.Duration) *Maintainer {
+	return &Maintainer{db: d, emb: emb, policies: policies, interval: interval}
+}
+
+// Run maintains until ctx is cancelled.
+func (m *Maintainer) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := m.RunOnce(ctx); err != nil {
+			log.Printf("Warning: agent context maintenance failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce performs one maintenance pass now. The report is marked skipped
+// when another replica is already running one.
+func (m *Maintainer) RunOnce(ctx context.Context) (*Report, error) {
+	report := &Report{RanAt: time.Now().UTC(), PartitionsCreated: []string{}, PartitionsDropped: []string{}}
+	ok, err := m.db.WithContextMaintenanceLock(ctx, func() error {
+		return m.maintain(ctx, report)
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.Skipped = !ok
+	if report.Rollups > 0 || report.Deleted > 0 || len(report.PartitionsCreated) > 0 || len(report.PartitionsDropped) > 0 {
+		log.Printf("Agent context maintenance: %d contexts rolled up into %d rollups, %d deleted, partitions created %v, dropped %v",
+			report.RolledUp, report.Rollups, report.Deleted, report.PartitionsCreated, report.PartitionsDropped)
+	}
+	return report, nil
+}
+
+func (m *Maintainer) maintain(ctx context.Context, report *Report) error {
+	now := time.Now().UTC()
+	created, err := m.db.EnsureAgentContextPartitions(ctx, now, partitionsAhead)
+	report.PartitionsCreated = append(report.PartitionsCreated, created...)
+	if err != nil {
+		return err
+	}
+
+	for {
+		groups, err := m.db.ExpiredContextGroups(ctx, m.policies, rollupBatch)
+		if err != nil {
+			return err
+		}
+		var batch int64
+		for _, g := range groups {
+			summary := rollupSummary(g)
+			emb, model, err := embeddings.EmbedWithModel(m.emb, summary)
+			if err != nil {
+				return fmt.Errorf("failed to embed rollup: %w", err)
+			}
+			n, err := m.db.RollupAgentContexts(ctx, g, summary, emb, model)
+			if err != nil {
+				return err
+			}
+			report.Rollups++
+			report.RolledUp += n
+			batch += n
+		}
+		// A full batch that deleted nothing would only repeat itself
+		if len(groups) < rollupBatch || batch == 0 {
+			break
+		}
+	}
+
+	deleted, err := m.db.DeleteExpiredAgentContexts(ctx, m.policies)
+	if err != nil {
+		return err
+	}
+	report.Deleted = deleted
+
+	// Only months before the current one can no longer receive rows
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	dropped, err := m.db.DropEmptyAgentContextPartitions(ctx, thisMonth)
+	report.PartitionsDropped = append(report.PartitionsDropped, dropped...)
+	return err
+}
+
+// rollupSummary lists the summaries of a group under a header line,
+// truncated to maxRollupSummary.
+func rollupSummary(g db.ContextRollupGroup) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Rollup of %d %s contexts of %s on %s:", len(g.IDs), g.Type, g.AgentID, g.Day.Format("2006-01-02"))
+	for i, s := range g.Summaries {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		line := "\n- " + strings.ReplaceAll(s, "\n", " ")
+		if b.Len()+len(line) > maxRollupSummary {
+			fmt.Fprintf(&b, "\n… %d more", len(g.Summaries)-i)
+			break
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
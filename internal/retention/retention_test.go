@@ -0,0 +1,80 @@
+package retention
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"jarvis-memory/internal/db"
+)
+
+func TestParsePolicies(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    []db.ContextRetentionPolicy
+		wantErr string
+	}{
+		{name: "empty", in: `[]`, want: []db.ContextRetentionPolicy{}},
+		{
+			name: "defaults to any",
+			in:   `[{"keepDays": 7}]`,
+			want: []db.ContextRetentionPolicy{{AgentID: db.RetentionAny, Type: db.RetentionAny, Keep: 7 * day}},
+		},
+		{
+			name: "full",
+			in:   `[{"type": "episodic", "keepDays": 30, "rollup": true}, {"agentId": "ci-bot", "keepDays": 7}, {"type": "decision"}]`,
+			want: []db.ContextRetentionPolicy{
+				{AgentID: db.RetentionAny, Type: "episodic", Keep: 30 * day, Rollup: true},
+				{AgentID: "ci-bot", Type: db.RetentionAny, Keep: 7 * day},
+				{AgentID: db.RetentionAny, Type: "decision"},
+			},
+		},
+		{name: "negative keep", in: `[{"type": "episodic"}, {"keepDays": -1}]`, wantErr: "policy 1"},
+		{name: "not an array", in: `{"type": "episodic"}`, wantErr: "invalid retention policies"},
+		{name: "wrong field type", in: `[{"keepDays": "30d"}]`, wantErr: "invalid retention policies"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePolicies(tt.in)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want one mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d policies, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("policy %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRollupSummary(t *testing.T) {
+	g := db.ContextRollupGroup{
+		AgentID:   "jarvis",
+		Type:      "episodic",
+		Day:       time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC),
+		IDs:       []string{"1", "2", "3"},
+		Summaries: []string{"fixed the build\nand pushed", "  ", "reviewed a PR"},
+	}
+	want := "Rollup of 3 episodic contexts of jarvis on 2026-03-04:\n- fixed the build and pushed\n- reviewed a PR"
+	if got := rollupSummary(g); got != want {
+		t.Errorf("rollupSummary = %q, want %q", got, want)
+	}
+
+	long := strings.Repeat("x", 3000)
+	g.Summaries = []string{long, long, long, long}
+	got := rollupSummary(g)
+	if len(got) > maxRollupSummary+20 || !strings.HasSuffix(got, "\n… 2 more") {
+		t.Errorf("long rollup is %d bytes ending in %q", len(got), got[len(got)-12:])
+	}
+}
@@ -0,0 +1,47 @@
+package trash
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"jarvis-memory/internal/db"
+)
+
+// Purger permanently deletes seeds that have sat in the trash for longer
+// than the retention period. It runs once at start and then on every tick.
+type Purger struct {
+	db        *db.DB
+	retention time.Duration
+	interval  time.Duration
+}
+
+func NewPurger(d *db.DB, retention, interval time.Duration) *Purger {
+	return &Purger{db: d, retention: retention, interval: interval}
+}
+
+// Run purges until ctx is cancelled.
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.purge(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Purger) purge(ctx context.Context) {
+	n, err := p.db.PurgeTrash(ctx, p.retention)
+	if err != nil {
+		log.Printf("Warning: failed to purge trash: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("Purged %d seeds from the trash", n)
+	}
+}
@@ -5,6 +5,37 @@
 
 API_URL="${JARVIS_API_URL:-http://localhost:8080}"
 
+# Send the API key (if configured) with every request
+function curl {
+  if [ -n "$JARVIS_API_KEY" ]; then
+    command curl -H "Authorization: Bearer $JARVIS_API_KEY" "$@"
+  else
+    command curl "$@"
+  fi
+}
+
+# Fetch every page of a list endpoint by following X-Next-Cursor and print
+# the merged JSON array
+function fetch_all {
+  local url="$1" sep="?" cursor="" dir headers page i=0
+  [[ "$url" == *\?* ]] && sep="&"
+  dir=$(mktemp -d)
+  headers="$dir/headers"
+  while :; do
+    i=$((i + 1))
+    page=$(printf "%s/page%05d.json" "$dir" "$i")
+    if [ -n "$cursor" ]; then
+      curl -s -D "$headers" "${url}${sep}limit=1000&cursor=${cursor}" > "$page"
+    else
+      curl -s -D "$headers" "${url}${sep}limit=1000" > "$page"
+    fi
+    cursor=$(grep -i '^x-next-cursor:' "$headers" | cut -d' ' -f2 | tr -d '\r')
+    [ -z "$cursor" ] && break
+  done
+  jq -s 'add // []' "$dir"/page*.json
+  rm -rf "$dir"
+}
+
 # Colors for output
 RED='\033[0;31m'
 GREEN='\033[0;32m'
@@ -22,16 +53,29 @@ function show_help {
   echo -e "  search <query> [limit] [threshold] [--since X] [--until X]"
   echo -e "                                        🔍 Semantic search (time: today|yesterday|this_week|last_week|YYYY-MM-DD)"
   echo -e "  update <id> <content> <title> [type]  ✏️  Update an existing seed"
-  echo -e "  delete <id>                           🗑️  Delete a seed (protected seeds blocked)"
+  echo -e "  delete <id>                           🗑️  Move a seed to the trash (protected seeds blocked)"
+  echo -e "  trash [limit]                         🗑️  List deleted seeds"
+  echo -e "  restore <id>                          ♻️  Restore a seed from the trash"
   echo -e "  confidence <id> <value>               ⚖️  Set confidence (0.0-1.0)"
   echo -e "  protect <id>                          🛡️  Protect seed from delete/decay"
   echo -e "  unprotect <id>                        🔓 Remove protection"
   echo -e "  classify                              🏷️  Auto-classify all seeds (confidence + protection)"
+  echo -e "  link <from_id> <to_id> <type> [note]  🕸️  Link seeds (supersedes|contradicts|elaborates|caused_by|related)"
+  echo -e "  links <id>                            🕸️  List a seed's links"
   echo -e ""
   echo -e "${GREEN}Agent Contexts:${NC}"
   echo -e "  context-create <agent_id> <type> <metadata> [summary]"
   echo -e "  context-list [agent_id]               📋 List contexts"
   echo -e "  context-get <id>                      🔎 Get specific context"
+  echo -e "  context-update <id> <summary>         ✏️  Replace a context's summary (re-embeds)"
+  echo -e "  context-delete <id>                   🗑️  Delete a context (protected contexts blocked)"
+  echo -e "  context-purge <agent_id> [since] [until]"
+  echo -e "                                        🧹 Delete an agent's contexts (optionally in a time range)"
+  echo -e ""
+  echo -e "${GREEN}Sessions:${NC}"
+  echo -e "  sessions [agent_id] [limit]           💬 List sessions (newest first)"
+  echo -e "  timeline <session_id>                 🕰️  Turns, seeds and contexts of a session"
+  echo -e "  session-end <session_id>              🏁 End a session"
   echo -e ""
   echo -e "${GREEN}Admin:${NC}"
   echo -e "  stats                                 📊 Show database statistics"
@@ -102,6 +146,25 @@ case "$1" in
     curl -s -X DELETE "$API_URL/seeds/$ID" | jq
     ;;
 
+  trash)
+    LIMIT="${2:-20}"
+    echo -e "🗑️  Latest $LIMIT deleted seeds..."
+    curl -s "$API_URL/trash?limit=$LIMIT" | jq '.[] | {id, title, type, deleted_at, deleted_by}'
+    ;;
+
+  restore)
+    ID="$2"
+
+    if [ -z "$ID" ]; then
+      echo -e "${RED}Error: seed ID is required.${NC}"
+      echo "Usage: $0 restore <id>"
+      exit 1
+    fi
+
+    echo -e "♻️  Restoring seed $ID..."
+    curl -s -X POST "$API_URL/seeds/$ID/restore" | jq
+    ;;
+
   confidence)
     ID="$2"
     VALUE="$3"
@@ -118,6 +181,35 @@ case "$1" in
       -d "{\"confidence\": $VALUE}" | jq
     ;;
     
+  link)
+    FROM="$2"
+    TO="$3"
+    TYPE="$4"
+    NOTE="$5"
+
+    if [ -z "$FROM" ] || [ -z "$TO" ] || [ -z "$TYPE" ]; then
+      echo -e "${RED}Error: from, to and type are required.${NC}"
+      echo "Usage: $0 link <from_id> <to_id> <supersedes|contradicts|elaborates|caused_by|related> [note]"
+      exit 1
+    fi
+
+    jq -n --arg to "$TO" --arg type "$TYPE" --arg note "$NOTE" '{targetId: $to, type: $type, note: $note}' \
+      | curl -s -X POST "$API_URL/seeds/$FROM/links" -H "Content-Type: application/json" -d @- | jq
+    ;;
+
+  links)
+    ID="$2"
+
+    if [ -z "$ID" ]; then
+      echo -e "${RED}Error: seed ID is required.${NC}"
+      echo "Usage: $0 links <id>"
+      exit 1
+    fi
+
+    curl -s "$API_URL/seeds/$ID/links" \
+      | jq -r '.[] | "\(if .direction == "out" then "→" else "←" end) \(.type) (\(.weight))  \(.seed.title)  [\(.seed.id)]\(if .note != null then "  — \(.note)" else "" end)"'
+    ;;
+
   search)
     QUERY=""
     LIMIT="10"
@@ -162,13 +254,13 @@ case "$1" in
   list)
     LIMIT="${2:-20}"
     echo -e "📋 Latest $LIMIT seeds..."
-    curl -s "$API_URL/seeds?limit=$LIMIT" | jq '.[] | {id, title, type, confidence, created_at}'
+    curl -s "$API_URL/seeds?limit=$LIMIT" | jq '.[] | {id, title, type, tags, confidence, created_at}'
     ;;
 
   stats)
     echo -e "${CYAN}📊 Jarvis Memory Statistics${NC}"
-    SEEDS=$(curl -s "$API_URL/seeds?limit=1000")
-    CTXS=$(curl -s "$API_URL/agent-contexts")
+    SEEDS=$(fetch_all "$API_URL/seeds")
+    CTXS=$(fetch_all "$API_URL/agent-contexts")
     SEED_COUNT=$(echo "$SEEDS" | jq 'length')
     CTX_COUNT=$(echo "$CTXS" | jq 'length')
     echo -e "  🌱 Seeds:          ${GREEN}$SEED_COUNT${NC}"
@@ -224,6 +316,90 @@ case "$1" in
     curl -s "$API_URL/agent-contexts/$ID" | jq
     ;;
 
+  context-update)
+    ID="$2"
+    SUMMARY="$3"
+
+    if [ -z "$ID" ] || [ -z "$SUMMARY" ]; then
+      echo -e "${RED}Error: context ID and summary are required.${NC}"
+      echo "Usage: $0 context-update <id> <summary>"
+      exit 1
+    fi
+
+    echo -e "✏️  Updating context $ID..."
+    curl -s -X PATCH "$API_URL/agent-contexts/$ID" \
+      -H "Content-Type: application/json" \
+      -d "{\"summary\": $(echo "$SUMMARY" | jq -Rs .)}" | jq
+    ;;
+
+  context-delete)
+    ID="$2"
+
+    if [ -z "$ID" ]; then
+      echo -e "${RED}Error: context ID is required.${NC}"
+      echo "Usage: $0 context-delete <id>"
+      exit 1
+    fi
+
+    echo -e "${YELLOW}🗑️  Deleting context $ID...${NC}"
+    curl -s -X DELETE "$API_URL/agent-contexts/$ID" | jq
+    ;;
+
+  context-purge)
+    AGENT_ID="$2"
+    SINCE="$3"
+    UNTIL="$4"
+
+    if [ -z "$AGENT_ID" ]; then
+      echo -e "${RED}Error: agent_id is required.${NC}"
+      echo "Usage: $0 context-purge <agent_id> [since] [until]"
+      exit 1
+    fi
+
+    echo -e "${YELLOW}🧹 Deleting contexts of $AGENT_ID...${NC}"
+    curl -s -G -X DELETE "$API_URL/agent-contexts" \
+      --data-urlencode "agentId=$AGENT_ID" \
+      ${SINCE:+--data-urlencode "since=$SINCE"} \
+      ${UNTIL:+--data-urlencode "until=$UNTIL"} | jq
+    ;;
+
+  sessions)
+    AGENT_ID="$2"
+    LIMIT="${3:-20}"
+
+    curl -s -G "$API_URL/sessions" \
+      --data-urlencode "limit=$LIMIT" \
+      ${AGENT_ID:+--data-urlencode "agentId=$AGENT_ID"} \
+      | jq -r '.[] | "\(.id)  \(.agentId)  \(.started_at[0:16])  \(.turns) turns\(if .ended_at then " (ended)" else "" end)  \(.title)"'
+    ;;
+
+  timeline)
+    ID="$2"
+
+    if [ -z "$ID" ]; then
+      echo -e "${RED}Error: session ID is required.${NC}"
+      echo "Usage: $0 timeline <session_id>"
+      exit 1
+    fi
+
+    curl -s "$API_URL/sessions/$ID/timeline" \
+      | jq -r '.entries[] | "\(.at[0:19])  [\(.kind)] \(if .kind == "turn" then "User: \(.text)\n                     → \(.response)" else "\(.title // "")\(if .title then ": " else "" end)\(.text)" end)"'
+    ;;
+
+  session-end)
+    ID="$2"
+
+    if [ -z "$ID" ]; then
+      echo -e "${RED}Error: session ID is required.${NC}"
+      echo "Usage: $0 session-end <session_id>"
+      exit 1
+    fi
+
+    curl -s -X PATCH "$API_URL/sessions/$ID" \
+      -H "Content-Type: application/json" \
+      -d '{"ended": true}' | jq
+    ;;
+
   reflect)
     DAY="${2:-today}"
     AGENT_ID="${JARVIS_AGENT_ID:-JARVIS}"
@@ -313,7 +489,7 @@ $(echo "$CONTENTS" | fold -s -w 120 | head -20)
     echo -e "${CYAN}🏷️  Auto-Classifying all seeds...${NC}"
     echo ""
 
-    SEEDS=$(curl -s "$API_URL/seeds?limit=10000")
+    SEEDS=$(fetch_all "$API_URL/seeds")
     TOTAL=$(echo "$SEEDS" | jq 'length')
     PROTECTED=0
     MEDIUM=0
@@ -359,8 +535,8 @@ $(echo "$CONTENTS" | fold -s -w 120 | head -20)
 
     echo -e "📦 Exporting all data..."
 
-    SEEDS=$(curl -s "$API_URL/seeds?limit=10000")
-    CONTEXTS=$(curl -s "$API_URL/agent-contexts")
+    SEEDS=$(fetch_all "$API_URL/seeds?sort=created_at&order=asc")
+    CONTEXTS=$(fetch_all "$API_URL/agent-contexts?order=asc")
 
     SEED_COUNT=$(echo "$SEEDS" | jq 'length')
     CTX_COUNT=$(echo "$CONTEXTS" | jq 'length')
@@ -406,25 +582,17 @@ $(echo "$CONTENTS" | fold -s -w 120 | head -20)
     echo -e "  🤖 Contexts: $CTX_COUNT"
     echo ""
 
-    # Import seeds
+    # Import seeds via /seeds/batch (NDJSON, up to 1000 per request)
     IMPORTED=0
     FAILED=0
     echo -e "🌱 Importing seeds..."
-    for i in $(seq 0 $(($SEED_COUNT - 1))); do
-      CONTENT=$(jq -r ".seeds[$i].content" "$FILE")
-      TITLE=$(jq -r ".seeds[$i].title" "$FILE")
-      TYPE=$(jq -r ".seeds[$i].type" "$FILE")
-
-      RESULT=$(curl -s -o /dev/null -w "%{http_code}" -X POST "$API_URL/seeds" \
-        -F "content=$CONTENT" \
-        -F "title=$TITLE" \
-        -F "type=$TYPE")
-
-      if [ "$RESULT" -eq 201 ]; then
-        IMPORTED=$((IMPORTED + 1))
-      else
-        FAILED=$((FAILED + 1))
-      fi
+    for START in $(seq 0 1000 $(($SEED_COUNT - 1))); do
+      RESULT=$(jq -c ".seeds[$START:$(($START + 1000))][] | {content, title, type, confidence, tags: (.tags // []), metadata: (.metadata // {})}" "$FILE" | \
+        curl -s -X POST "$API_URL/seeds/batch" \
+          -H "Content-Type: application/x-ndjson" \
+          --data-binary @-)
+      IMPORTED=$((IMPORTED + $(echo "$RESULT" | jq '.created // 0')))
+      FAILED=$((FAILED + $(echo "$RESULT" | jq '.failed // 0')))
       printf "\r  Progress: %d/%d (failed: %d)" $((IMPORTED + FAILED)) "$SEED_COUNT" "$FAILED"
     done
     echo ""
@@ -434,7 +602,7 @@ $(echo "$CONTENTS" | fold -s -w 120 | head -20)
     CTX_FAILED=0
     echo -e "🤖 Importing contexts..."
     for i in $(seq 0 $(($CTX_COUNT - 1))); do
-      BODY=$(jq ".agent_contexts[$i] | {agentId: .agentId, type: .type, metadata: .metadata, summary: .summary}" "$FILE")
+      BODY=$(jq ".agent_contexts[$i] | {agentId: .agentId, type: .type, metadata: .metadata, summary: .summary, protected: (.protected // false)}" "$FILE")
 
       RESULT=$(curl -s -o /dev/null -w "%{http_code}" -X POST "$API_URL/agent-contexts" \
         -H "Content-Type: application/json" \